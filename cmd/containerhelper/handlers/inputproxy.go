@@ -8,6 +8,7 @@ import (
 	"errors"
 	"github.com/traas-stack/holoinsight-agent/cmd/containerhelper/model"
 	_ "github.com/traas-stack/holoinsight-agent/pkg/plugin/input/dialcheckw"
+	_ "github.com/traas-stack/holoinsight-agent/pkg/plugin/input/golang"
 	"github.com/traas-stack/holoinsight-agent/pkg/plugin/input/inputproxy"
 	"io"
 	"os"
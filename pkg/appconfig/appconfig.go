@@ -12,6 +12,7 @@ import (
 	"github.com/traas-stack/holoinsight-agent/pkg/core"
 	"gopkg.in/yaml.v3"
 	"os"
+	"strings"
 )
 
 var agentVersion string
@@ -52,6 +53,7 @@ type (
 		Cluster     string            `json:"cluster" yaml:"cluster" toml:"cluster"`
 		Data        DataConfig        `json:"data" yaml:"data" toml:"data"`
 		Daemonagent DaemonagentConfig `json:"daemonagent" yaml:"daemonagent" toml:"daemonagent"`
+		Sys         SysConfig         `json:"sys" yaml:"sys" toml:"sys"`
 	}
 	BasicConfig struct {
 		App       string         `json:"app" yaml:"app" toml:"app"`
@@ -81,7 +83,7 @@ type (
 		Metrics K8sMetricsConfig `json:"metrics" yaml:"metrics" toml:"metrics"`
 	}
 	K8sConfig_Cri struct {
-		// docker pouch
+		// docker pouch containerd cri(for CRI-O)
 		Type  string            `json:"type,omitempty" yaml:"type" toml:"type"`
 		Pouch K8sCriPouchConfig `json:"pouch,omitempty" yaml:"pouch" toml:"pouch"`
 		// If GetPodsFromKubeletAPI is not empty, it means to get pods information from the kubelet http API of the physical machine.
@@ -104,7 +106,31 @@ type (
 		AutoScapeK8s bool `json:"autoScapeK8s,omitempty" yaml:"autoScapeK8s" toml:"autoScapeK8s"`
 	}
 	DataConfig struct {
-		Metric DataConfig_Metric `json:"metric" yaml:"metric" toml:"metric"`
+		Metric  DataConfig_Metric  `json:"metric" yaml:"metric" toml:"metric"`
+		Masking DataConfig_Masking `json:"masking" yaml:"masking" toml:"masking"`
+		// IPLookup is used by ipLookupV1 transform and cidr where
+		IPLookup DataConfig_IPLookup `json:"ipLookup" yaml:"ipLookup" toml:"ipLookup"`
+	}
+	// DataConfig_Masking is the agent-level masking policy, it applies to all log samples before they are sent to server
+	DataConfig_Masking struct {
+		Enabled bool `json:"enabled,omitempty" yaml:"enabled" toml:"enabled"`
+		// Detectors are names of built-in detectors, empty means all built-in detectors
+		Detectors []string                   `json:"detectors,omitempty" yaml:"detectors" toml:"detectors"`
+		Rules     []*DataConfig_Masking_Rule `json:"rules,omitempty" yaml:"rules" toml:"rules"`
+	}
+	DataConfig_Masking_Rule struct {
+		Name       string `json:"name,omitempty" yaml:"name" toml:"name"`
+		Expression string `json:"expression" yaml:"expression" toml:"expression"`
+		Group      int    `json:"group,omitempty" yaml:"group" toml:"group"`
+		KeepPrefix int    `json:"keepPrefix,omitempty" yaml:"keepPrefix" toml:"keepPrefix"`
+		KeepSuffix int    `json:"keepSuffix,omitempty" yaml:"keepSuffix" toml:"keepSuffix"`
+		MaskChar   string `json:"maskChar,omitempty" yaml:"maskChar" toml:"maskChar"`
+	}
+	DataConfig_IPLookup struct {
+		// GeoFiles are paths of MaxMind DB files, e.g. GeoLite2-City.mmdb and GeoLite2-ASN.mmdb
+		GeoFiles []string `json:"geoFiles,omitempty" yaml:"geoFiles" toml:"geoFiles"`
+		// Zones maps CIDRs to zone labels, e.g. '10.0.0.0/8': 'office'
+		Zones map[string]string `json:"zones,omitempty" yaml:"zones" toml:"zones"`
 	}
 	DataConfig_Metric struct {
 		RefLabels DataConfig_Metric_RefLabels `json:"refLabels" yaml:"refLabels" toml:"refLabels"`
@@ -124,11 +150,40 @@ type (
 		DefaultValue string `json:"defaultValue" yaml:"defaultValue" toml:"defaultValue" `
 	}
 	K8sMetricsConfig struct {
-		FiveSecondsEnabled bool `json:"fiveSecondsEnabled,omitempty" yaml:"fiveSecondsEnabled" toml:"fiveSecondsEnabled"`
+		FiveSecondsEnabled bool               `json:"fiveSecondsEnabled,omitempty" yaml:"fiveSecondsEnabled" toml:"fiveSecondsEnabled"`
+		KubeState          K8sKubeStateConfig `json:"kubeState" yaml:"kubeState" toml:"kubeState"`
+	}
+	// K8sKubeStateConfig controls kube-state metrics generated by clusteragent
+	K8sKubeStateConfig struct {
+		Disabled bool `json:"disabled,omitempty" yaml:"disabled" toml:"disabled"`
+		// Namespaces limits objects to these namespaces, empty means all namespaces
+		Namespaces []string `json:"namespaces,omitempty" yaml:"namespaces" toml:"namespaces"`
+		// ExcludeNamespaces excludes objects in these namespaces
+		ExcludeNamespaces []string `json:"excludeNamespaces,omitempty" yaml:"excludeNamespaces" toml:"excludeNamespaces"`
+		// LabelSelector limits namespaced objects(pods/deployments/statefulsets/daemonsets) by labels, such as 'app=demo,env!=test'
+		LabelSelector string `json:"labelSelector,omitempty" yaml:"labelSelector" toml:"labelSelector"`
 	}
 	K8sCriPouchConfig struct {
 		CpWorkaroundEnabled bool `json:"cpWorkaroundEnabled,omitempty" yaml:"cpWorkaroundEnabled"`
 	}
+	// SysConfig controls system inputs of node
+	SysConfig struct {
+		Disk SysConfig_Disk `json:"disk" yaml:"disk" toml:"disk"`
+	}
+	// SysConfig_Disk controls which mountpoints and devices are reported by disk input.
+	// Empty lists mean built-in defaults of disk input.
+	SysConfig_Disk struct {
+		IncludeFsTypes []string `json:"includeFsTypes,omitempty" yaml:"includeFsTypes" toml:"includeFsTypes"`
+		ExcludeFsTypes []string `json:"excludeFsTypes,omitempty" yaml:"excludeFsTypes" toml:"excludeFsTypes"`
+		// IncludeMounts and ExcludeMounts are regexps of mountpoint
+		IncludeMounts []string `json:"includeMounts,omitempty" yaml:"includeMounts" toml:"includeMounts"`
+		ExcludeMounts []string `json:"excludeMounts,omitempty" yaml:"excludeMounts" toml:"excludeMounts"`
+		// IncludeDevices and ExcludeDevices are regexps of block device name such as 'sda' and 'nvme0n1'
+		IncludeDevices []string `json:"includeDevices,omitempty" yaml:"includeDevices" toml:"includeDevices"`
+		ExcludeDevices []string `json:"excludeDevices,omitempty" yaml:"excludeDevices" toml:"excludeDevices"`
+		// Partitions reports io of partitions, by default only whole disks are reported to avoid double counting
+		Partitions bool `json:"partitions,omitempty" yaml:"partitions" toml:"partitions"`
+	}
 	// DaemonagentConfig daemonagent config
 	DaemonagentConfig struct {
 	}
@@ -253,6 +308,52 @@ func SetupAppConfig() error {
 		StdAgentConfig.K8s.Meta.SidecarCheck = s
 	}
 
+	if s := os.Getenv("HI_K8S_KUBE_STATE_DISABLED"); s != "" {
+		StdAgentConfig.K8s.Metrics.KubeState.Disabled = cast.ToBool(s)
+	}
+	if s := os.Getenv("HI_K8S_KUBE_STATE_NAMESPACES"); s != "" {
+		StdAgentConfig.K8s.Metrics.KubeState.Namespaces = strings.Split(s, ",")
+	}
+	if s := os.Getenv("HI_K8S_KUBE_STATE_EXCLUDE_NAMESPACES"); s != "" {
+		StdAgentConfig.K8s.Metrics.KubeState.ExcludeNamespaces = strings.Split(s, ",")
+	}
+	if s := os.Getenv("HI_K8S_KUBE_STATE_LABEL_SELECTOR"); s != "" {
+		StdAgentConfig.K8s.Metrics.KubeState.LabelSelector = s
+	}
+
+	if s := os.Getenv("HI_DATA_MASKING_ENABLED"); s != "" {
+		StdAgentConfig.Data.Masking.Enabled = cast.ToBool(s)
+	}
+	if s := os.Getenv("HI_DATA_MASKING_DETECTORS"); s != "" {
+		StdAgentConfig.Data.Masking.Detectors = strings.Split(s, ",")
+	}
+
+	if s := os.Getenv("HI_DATA_IP_GEO_FILES"); s != "" {
+		StdAgentConfig.Data.IPLookup.GeoFiles = strings.Split(s, ",")
+	}
+
+	if s := os.Getenv("HI_SYS_DISK_INCLUDE_FS_TYPES"); s != "" {
+		StdAgentConfig.Sys.Disk.IncludeFsTypes = strings.Split(s, ",")
+	}
+	if s := os.Getenv("HI_SYS_DISK_EXCLUDE_FS_TYPES"); s != "" {
+		StdAgentConfig.Sys.Disk.ExcludeFsTypes = strings.Split(s, ",")
+	}
+	if s := os.Getenv("HI_SYS_DISK_INCLUDE_MOUNTS"); s != "" {
+		StdAgentConfig.Sys.Disk.IncludeMounts = strings.Split(s, ",")
+	}
+	if s := os.Getenv("HI_SYS_DISK_EXCLUDE_MOUNTS"); s != "" {
+		StdAgentConfig.Sys.Disk.ExcludeMounts = strings.Split(s, ",")
+	}
+	if s := os.Getenv("HI_SYS_DISK_INCLUDE_DEVICES"); s != "" {
+		StdAgentConfig.Sys.Disk.IncludeDevices = strings.Split(s, ",")
+	}
+	if s := os.Getenv("HI_SYS_DISK_EXCLUDE_DEVICES"); s != "" {
+		StdAgentConfig.Sys.Disk.ExcludeDevices = strings.Split(s, ",")
+	}
+	if s := os.Getenv("HI_SYS_DISK_PARTITIONS"); s != "" {
+		StdAgentConfig.Sys.Disk.Partitions = cast.ToBool(s)
+	}
+
 	if s := os.Getenv("HI_WORKSPACE"); s != "" {
 		StdAgentConfig.Workspace = s
 	}
@@ -435,7 +435,7 @@ func (b *AgentBootstrap) setupCRI() error {
 		b.TM.AddStopComponents(i)
 	}
 	App.AddStopComponents(i)
-	maybeInitDockerOOMManager()
+	maybeInitOOMManager()
 	b.callCustomizers("cri-setup-end", nil)
 	return nil
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/traas-stack/holoinsight-agent/pkg/collecttask"
 	"github.com/traas-stack/holoinsight-agent/pkg/cri"
 	"github.com/traas-stack/holoinsight-agent/pkg/cri/containerdutils"
+	"github.com/traas-stack/holoinsight-agent/pkg/cri/criapiutils"
 	"github.com/traas-stack/holoinsight-agent/pkg/cri/dockerutils"
 	"github.com/traas-stack/holoinsight-agent/pkg/cri/impl/engine"
 	"github.com/traas-stack/holoinsight-agent/pkg/ioc"
@@ -33,6 +34,17 @@ func InitContainerdEngine() (cri.ContainerEngine, error) {
 	return &engine.ContainerdContainerEngine{Client: client}, nil
 }
 
+// InitCriEngine creates a container engine using CRI API, it is used for CRI-O
+func InitCriEngine() (cri.ContainerEngine, error) {
+	client, conn, versionResp, err := criapiutils.NewClientFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	logger.Infoz("[bootstrap] [cri] init cri client", zap.String("addr", conn.Target()), zap.Any("version", versionResp))
+	logger.Criz("[bootstrap] cri client", zap.String("addr", conn.Target()), zap.Any("version", versionResp))
+	return &engine.CriContainerEngine{Client: client, RuntimeName: versionResp.RuntimeName}, nil
+}
+
 func InitDockerEngine() (cri.ContainerEngine, error) {
 	docker, pingResp, err := dockerutils.NewClientFromEnv()
 	if err != nil {
@@ -58,13 +70,17 @@ func InitCollectTaskManager(rs *registry.Service, staticTasks []*collecttask.Col
 	return ctm, nil
 }
 
-func maybeInitDockerOOMManager() {
+func maybeInitOOMManager() {
 	i := ioc.Crii
-	e := i.Engine()
-	if x, ok := e.(*engine.DockerContainerEngine); ok {
+	switch x := i.Engine().(type) {
+	case *engine.DockerContainerEngine:
 		oomManager := engine.NewOOMManager(i, x.Client)
 		oomManager.Start()
 		App.AddStopComponents(oomManager)
+	case *engine.ContainerdContainerEngine:
+		oomManager := engine.NewContainerdOOMManager(i, x.Client)
+		oomManager.Start()
+		App.AddStopComponents(oomManager)
 	}
 }
 
@@ -120,13 +136,18 @@ func CreateContainerEngine() (cri.ContainerEngine, error) {
 		return InitContainerdEngine()
 	case "docker":
 		return InitDockerEngine()
+	case "cri", "crio", "cri-o":
+		return InitCriEngine()
 	case "":
 		engine, err := InitDockerEngine()
 		if err != nil {
 			engine, err = InitContainerdEngine()
-			if err != nil {
-				err = errors.New("fail to init container engine automatically")
-			}
+		}
+		if err != nil {
+			engine, err = InitCriEngine()
+		}
+		if err != nil {
+			err = errors.New("fail to init container engine automatically")
 		}
 		return engine, err
 	default:
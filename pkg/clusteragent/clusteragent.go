@@ -5,15 +5,20 @@
 package clusteragent
 
 import (
+	"github.com/traas-stack/holoinsight-agent/pkg/appconfig"
 	"github.com/traas-stack/holoinsight-agent/pkg/ioc"
+	"github.com/traas-stack/holoinsight-agent/pkg/k8s/k8sevents"
 	"github.com/traas-stack/holoinsight-agent/pkg/k8s/k8ssync"
 	"github.com/traas-stack/holoinsight-agent/pkg/logger"
+	"go.uber.org/zap"
 )
 
 type (
-	// MasterComponent starts k8ssync.MetaSyncer
+	// MasterComponent starts k8ssync.MetaSyncer, k8sevents.Watcher and kube-state metrics
 	MasterComponent struct {
-		k8sMetaSyncer k8ssync.MetaSyncer
+		k8sMetaSyncer    k8ssync.MetaSyncer
+		k8sEventsWatcher k8sevents.Watcher
+		kubeState        *kubeStateMetrics
 	}
 )
 
@@ -27,6 +32,19 @@ func (c *MasterComponent) Start() {
 	k8sMetaSyncer := k8ssync.NewMetaSyncer(ioc.RegistryService, ioc.K8sClientset)
 	k8sMetaSyncer.Start()
 	c.k8sMetaSyncer = k8sMetaSyncer
+
+	k8sEventsWatcher := k8sevents.NewWatcher(ioc.RegistryService, ioc.K8sClientset)
+	k8sEventsWatcher.Start()
+	c.k8sEventsWatcher = k8sEventsWatcher
+
+	if config := &appconfig.StdAgentConfig.K8s.Metrics.KubeState; !config.Disabled {
+		if kubeState, err := newKubeStateMetrics(ioc.K8sClientset, config, k8sMetaSyncer.PodLister(), k8sMetaSyncer.PodsSynced); err == nil {
+			kubeState.Start()
+			c.kubeState = kubeState
+		} else {
+			logger.Metaz("[clusteragent] invalid kube-state config", zap.Error(err))
+		}
+	}
 }
 
 func (c *MasterComponent) Stop() {
@@ -37,4 +55,16 @@ func (c *MasterComponent) Stop() {
 	if s != nil {
 		s.Stop()
 	}
+
+	w := c.k8sEventsWatcher
+	c.k8sEventsWatcher = nil
+	if w != nil {
+		w.Stop()
+	}
+
+	k := c.kubeState
+	c.kubeState = nil
+	if k != nil {
+		k.Stop()
+	}
 }
@@ -0,0 +1,201 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package clusteragent
+
+import (
+	"context"
+	"github.com/traas-stack/holoinsight-agent/pkg/appconfig"
+	"github.com/traas-stack/holoinsight-agent/pkg/logger"
+	"github.com/traas-stack/holoinsight-agent/pkg/plugin/output/gateway"
+	"github.com/traas-stack/holoinsight-agent/pkg/util"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"reflect"
+	"time"
+)
+
+const (
+	defaultKubeStateInterval = time.Minute
+)
+
+type (
+	// kubeStateMetrics generates kube-state metrics (workload replicas, pod phases, container restarts, node conditions)
+	// from informers, and writes them to gateway every minute.
+	kubeStateMetrics struct {
+		clientset        kubernetes.Interface
+		config           *appconfig.K8sKubeStateConfig
+		filter           *kubeStateFilter
+		interval         time.Duration
+		stopSig          *util.StopSignal
+		nodeLister       corelisters.NodeLister
+		deploymentLister appslisters.DeploymentLister
+		stsLister        appslisters.StatefulSetLister
+		dsLister         appslisters.DaemonSetLister
+		// podLister and podsSynced are shared with k8ssync.MetaSyncer which already watches all pods
+		podLister  corelisters.PodLister
+		podsSynced cache.InformerSynced
+	}
+)
+
+func newKubeStateMetrics(clientset kubernetes.Interface, config *appconfig.K8sKubeStateConfig, podLister corelisters.PodLister, podsSynced cache.InformerSynced) (*kubeStateMetrics, error) {
+	filter, err := newKubeStateFilter(config)
+	if err != nil {
+		return nil, err
+	}
+	return &kubeStateMetrics{
+		clientset:  clientset,
+		config:     config,
+		filter:     filter,
+		interval:   defaultKubeStateInterval,
+		stopSig:    util.NewStopSignal(),
+		podLister:  podLister,
+		podsSynced: podsSynced,
+	}, nil
+}
+
+func (k *kubeStateMetrics) Start() {
+	var opts []informers.SharedInformerOption
+	// Watches only one namespace when possible. Multiple namespaces are filtered at client side.
+	if len(k.config.Namespaces) == 1 {
+		opts = append(opts, informers.WithNamespace(k.config.Namespaces[0]))
+	}
+	if k.config.LabelSelector != "" {
+		opts = append(opts, informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = k.config.LabelSelector
+		}))
+	}
+	factory := informers.NewSharedInformerFactoryWithOptions(k.clientset, 0, opts...)
+	// Nodes are not namespaced and are not filtered by selectors
+	nodeFactory := informers.NewSharedInformerFactory(k.clientset, 0)
+
+	k.deploymentLister = factory.Apps().V1().Deployments().Lister()
+	k.stsLister = factory.Apps().V1().StatefulSets().Lister()
+	k.dsLister = factory.Apps().V1().DaemonSets().Lister()
+	k.nodeLister = nodeFactory.Core().V1().Nodes().Lister()
+
+	factory.Start(k.stopSig.C)
+	nodeFactory.Start(k.stopSig.C)
+
+	go func() {
+		defer k.stopSig.StopDone()
+
+		begin := time.Now()
+		for _, synced := range []map[reflect.Type]bool{
+			factory.WaitForCacheSync(k.stopSig.C),
+			nodeFactory.WaitForCacheSync(k.stopSig.C),
+		} {
+			for informerType, ok := range synced {
+				// It happens only when stopped
+				if !ok {
+					logger.Metaz("[kubestate] informer not synced", zap.Stringer("type", informerType))
+					return
+				}
+			}
+		}
+		if !cache.WaitForCacheSync(k.stopSig.C, k.podsSynced) {
+			logger.Metaz("[kubestate] pods not synced")
+			return
+		}
+		logger.Metaz("[kubestate] informers synced", zap.Duration("cost", time.Now().Sub(begin)))
+		k.taskLoop()
+	}()
+}
+
+func (k *kubeStateMetrics) Stop() {
+	k.stopSig.StopAndWait()
+}
+
+func (k *kubeStateMetrics) taskLoop() {
+	timer, _ := util.NewAlignedTimer(k.interval, 3*time.Second, true, false)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			metricTime := timer.NextEmitTime().Truncate(k.interval)
+			k.collectOnce(metricTime)
+			timer.Next()
+		case <-k.stopSig.C:
+			return
+		}
+	}
+}
+
+func (k *kubeStateMetrics) collectOnce(metricTime time.Time) {
+	begin := time.Now()
+	objs, err := k.snapshot()
+	if err != nil {
+		logger.Errorz("[kubestate] list objects error", zap.Error(err))
+		return
+	}
+	metrics := buildKubeStateMetrics(metricTime.UnixMilli(), objs)
+	err = gateway.GetWriteService().WriteV1(context.Background(), &gateway.WriteV1Request{
+		Batch: metrics,
+	})
+	logger.Infoz("[kubestate] collect",
+		zap.Time("metricTime", metricTime),
+		zap.Int("pods", len(objs.pods)),
+		zap.Int("nodes", len(objs.nodes)),
+		zap.Int("metrics", len(metrics)),
+		zap.Duration("cost", time.Now().Sub(begin)),
+		zap.Error(err))
+}
+
+// snapshot lists objects from informer caches and filters them
+func (k *kubeStateMetrics) snapshot() (*kubeStateObjects, error) {
+	objs := &kubeStateObjects{}
+
+	pods, err := k.podLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods {
+		if k.filter.accept(pod) {
+			objs.pods = append(objs.pods, pod)
+		}
+	}
+
+	deployments, err := k.deploymentLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range deployments {
+		if k.filter.accept(d) {
+			objs.deployments = append(objs.deployments, d)
+		}
+	}
+
+	statefulSets, err := k.stsLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range statefulSets {
+		if k.filter.accept(s) {
+			objs.statefulSets = append(objs.statefulSets, s)
+		}
+	}
+
+	daemonSets, err := k.dsLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range daemonSets {
+		if k.filter.accept(d) {
+			objs.daemonSets = append(objs.daemonSets, d)
+		}
+	}
+
+	objs.nodes, err = k.nodeLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	return objs, nil
+}
@@ -0,0 +1,264 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package clusteragent
+
+import (
+	"github.com/traas-stack/holoinsight-agent/pkg/appconfig"
+	"github.com/traas-stack/holoinsight-agent/pkg/k8s/k8sutils"
+	"github.com/traas-stack/holoinsight-agent/pkg/meta"
+	"github.com/traas-stack/holoinsight-agent/pkg/model"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+type (
+	// kubeStateObjects is a snapshot of objects used to build kube-state metrics
+	kubeStateObjects struct {
+		pods         []*v1.Pod
+		nodes        []*v1.Node
+		deployments  []*appsv1.Deployment
+		statefulSets []*appsv1.StatefulSet
+		daemonSets   []*appsv1.DaemonSet
+	}
+	// kubeStateFilter bounds cardinality of kube-state metrics by namespaces and labels
+	kubeStateFilter struct {
+		namespaces        map[string]struct{}
+		excludeNamespaces map[string]struct{}
+		selector          labels.Selector
+	}
+	kubeStateBuilder struct {
+		ts      int64
+		metrics []*model.Metric
+	}
+)
+
+var (
+	podPhases = []v1.PodPhase{v1.PodPending, v1.PodRunning, v1.PodSucceeded, v1.PodFailed, v1.PodUnknown}
+)
+
+func newKubeStateFilter(config *appconfig.K8sKubeStateConfig) (*kubeStateFilter, error) {
+	selector := labels.Everything()
+	if config.LabelSelector != "" {
+		s, err := labels.Parse(config.LabelSelector)
+		if err != nil {
+			return nil, err
+		}
+		selector = s
+	}
+	return &kubeStateFilter{
+		namespaces:        toSet(config.Namespaces),
+		excludeNamespaces: toSet(config.ExcludeNamespaces),
+		selector:          selector,
+	}, nil
+}
+
+func toSet(ss []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(ss))
+	for _, s := range ss {
+		if s != "" {
+			set[s] = struct{}{}
+		}
+	}
+	return set
+}
+
+// accept returns whether a namespaced object should be reported
+func (f *kubeStateFilter) accept(obj metav1.Object) bool {
+	if _, ok := f.excludeNamespaces[obj.GetNamespace()]; ok {
+		return false
+	}
+	if len(f.namespaces) > 0 {
+		if _, ok := f.namespaces[obj.GetNamespace()]; !ok {
+			return false
+		}
+	}
+	return f.selector.Matches(labels.Set(obj.GetLabels()))
+}
+
+func (b *kubeStateBuilder) add(name string, tags map[string]string, value float64) {
+	b.metrics = append(b.metrics, &model.Metric{
+		Name:      name,
+		Tags:      tags,
+		Timestamp: b.ts,
+		Value:     value,
+	})
+}
+
+// buildKubeStateMetrics builds kube-state metrics from objects, objects must have been filtered
+func buildKubeStateMetrics(ts int64, objs *kubeStateObjects) []*model.Metric {
+	b := &kubeStateBuilder{ts: ts}
+	b.addPodMetrics(objs.pods)
+	b.addNodeMetrics(objs.nodes)
+	for _, d := range objs.deployments {
+		b.addDeploymentMetrics(d)
+	}
+	for _, s := range objs.statefulSets {
+		b.addStatefulSetMetrics(s)
+	}
+	for _, d := range objs.daemonSets {
+		b.addDaemonSetMetrics(d)
+	}
+	return b.metrics
+}
+
+func namespaceTags(namespace string) map[string]string {
+	tags := map[string]string{"namespace": namespace}
+	meta.AttachSystemCommonTagsTo(tags)
+	return tags
+}
+
+func (b *kubeStateBuilder) addPodMetrics(pods []*v1.Pod) {
+	// namespace -> phase -> count
+	phases := make(map[string]map[v1.PodPhase]int)
+	// namespace -> pending reason -> count
+	pendings := make(map[string]map[string]int)
+
+	for _, pod := range pods {
+		byPhase, ok := phases[pod.Namespace]
+		if !ok {
+			byPhase = make(map[v1.PodPhase]int, len(podPhases))
+			phases[pod.Namespace] = byPhase
+		}
+		phase := pod.Status.Phase
+		if phase == "" {
+			phase = v1.PodUnknown
+		}
+		byPhase[phase]++
+
+		if phase == v1.PodPending {
+			byReason, ok := pendings[pod.Namespace]
+			if !ok {
+				byReason = make(map[string]int)
+				pendings[pod.Namespace] = byReason
+			}
+			byReason[pendingReason(pod)]++
+		}
+
+		b.addContainerMetrics(pod)
+	}
+
+	for namespace, byPhase := range phases {
+		for _, phase := range podPhases {
+			tags := namespaceTags(namespace)
+			tags["phase"] = string(phase)
+			b.add("k8s_pod_phase_count", tags, float64(byPhase[phase]))
+		}
+	}
+	for namespace, byReason := range pendings {
+		for reason, count := range byReason {
+			tags := namespaceTags(namespace)
+			tags["reason"] = reason
+			b.add("k8s_pod_pending_count", tags, float64(count))
+		}
+	}
+}
+
+// pendingReason returns why a pod is pending, such as 'Unschedulable' and 'ImagePullBackOff'
+func pendingReason(pod *v1.Pod) string {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == v1.PodScheduled && c.Status == v1.ConditionFalse && c.Reason != "" {
+			return c.Reason
+		}
+	}
+	for _, statuses := range [][]v1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+		for i := range statuses {
+			if w := statuses[i].State.Waiting; w != nil && w.Reason != "" {
+				return w.Reason
+			}
+		}
+	}
+	return "Unknown"
+}
+
+func (b *kubeStateBuilder) addContainerMetrics(pod *v1.Pod) {
+	for i := range pod.Status.ContainerStatuses {
+		cs := &pod.Status.ContainerStatuses[i]
+
+		tags := meta.ExtractPodCommonTags(pod)
+		tags["container"] = cs.Name
+		b.add("k8s_kube_state_container_restarts", tags, float64(cs.RestartCount))
+		b.add("k8s_container_ready", tags, boolToFloat64(cs.Ready))
+
+		if cs.State.Waiting != nil && cs.State.Waiting.Reason != "" {
+			waitingTags := meta.ExtractPodCommonTags(pod)
+			waitingTags["container"] = cs.Name
+			waitingTags["reason"] = cs.State.Waiting.Reason
+			b.add("k8s_container_waiting", waitingTags, 1)
+		}
+		if t := cs.LastTerminationState.Terminated; t != nil && t.Reason != "" {
+			terminatedTags := meta.ExtractPodCommonTags(pod)
+			terminatedTags["container"] = cs.Name
+			terminatedTags["reason"] = t.Reason
+			b.add("k8s_container_last_terminated", terminatedTags, float64(t.ExitCode))
+		}
+	}
+}
+
+func (b *kubeStateBuilder) addNodeMetrics(nodes []*v1.Node) {
+	for _, node := range nodes {
+		ip := k8sutils.GetNodeIP(node)
+		for _, c := range node.Status.Conditions {
+			tags := meta.ExtractNodeCommonTags(node)
+			tags["ip"] = ip
+			tags["condition"] = string(c.Type)
+			tags["status"] = string(c.Status)
+			meta.AttachSystemCommonTagsTo(tags)
+			b.add("k8s_node_condition", tags, boolToFloat64(c.Status == v1.ConditionTrue))
+		}
+		tags := meta.ExtractNodeCommonTags(node)
+		tags["ip"] = ip
+		meta.AttachSystemCommonTagsTo(tags)
+		b.add("k8s_node_unschedulable", tags, boolToFloat64(node.Spec.Unschedulable))
+	}
+}
+
+func workloadTags(kind string, obj metav1.Object) map[string]string {
+	tags := namespaceTags(obj.GetNamespace())
+	tags[kind] = obj.GetName()
+	return tags
+}
+
+// replicas returns desired replicas, which defaults to 1 when it is not specified
+func replicas(r *int32) float64 {
+	if r == nil {
+		return 1
+	}
+	return float64(*r)
+}
+
+func (b *kubeStateBuilder) addDeploymentMetrics(d *appsv1.Deployment) {
+	tags := workloadTags("deployment", d)
+	b.add("k8s_deployment_replicas_desired", tags, replicas(d.Spec.Replicas))
+	b.add("k8s_deployment_replicas_available", tags, float64(d.Status.AvailableReplicas))
+	b.add("k8s_deployment_replicas_unavailable", tags, float64(d.Status.UnavailableReplicas))
+	b.add("k8s_deployment_replicas_updated", tags, float64(d.Status.UpdatedReplicas))
+	b.add("k8s_deployment_replicas_ready", tags, float64(d.Status.ReadyReplicas))
+}
+
+func (b *kubeStateBuilder) addStatefulSetMetrics(s *appsv1.StatefulSet) {
+	tags := workloadTags("statefulset", s)
+	b.add("k8s_statefulset_replicas_desired", tags, replicas(s.Spec.Replicas))
+	b.add("k8s_statefulset_replicas_ready", tags, float64(s.Status.ReadyReplicas))
+	b.add("k8s_statefulset_replicas_current", tags, float64(s.Status.CurrentReplicas))
+	b.add("k8s_statefulset_replicas_updated", tags, float64(s.Status.UpdatedReplicas))
+}
+
+func (b *kubeStateBuilder) addDaemonSetMetrics(d *appsv1.DaemonSet) {
+	tags := workloadTags("daemonset", d)
+	b.add("k8s_daemonset_desired", tags, float64(d.Status.DesiredNumberScheduled))
+	b.add("k8s_daemonset_current", tags, float64(d.Status.CurrentNumberScheduled))
+	b.add("k8s_daemonset_ready", tags, float64(d.Status.NumberReady))
+	b.add("k8s_daemonset_available", tags, float64(d.Status.NumberAvailable))
+	b.add("k8s_daemonset_misscheduled", tags, float64(d.Status.NumberMisscheduled))
+}
+
+func boolToFloat64(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package clusteragent
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/traas-stack/holoinsight-agent/pkg/appconfig"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestKubeStateFilter(t *testing.T) {
+	f, err := newKubeStateFilter(&appconfig.K8sKubeStateConfig{
+		Namespaces:    []string{"default", "prod"},
+		LabelSelector: "app=demo",
+	})
+	assert.NoError(t, err)
+	assert.True(t, f.accept(&metav1.ObjectMeta{Namespace: "default", Labels: map[string]string{"app": "demo"}}))
+	assert.False(t, f.accept(&metav1.ObjectMeta{Namespace: "default", Labels: map[string]string{"app": "other"}}))
+	assert.False(t, f.accept(&metav1.ObjectMeta{Namespace: "kube-system", Labels: map[string]string{"app": "demo"}}))
+
+	f, err = newKubeStateFilter(&appconfig.K8sKubeStateConfig{ExcludeNamespaces: []string{"kube-system"}})
+	assert.NoError(t, err)
+	assert.True(t, f.accept(&metav1.ObjectMeta{Namespace: "default"}))
+	assert.False(t, f.accept(&metav1.ObjectMeta{Namespace: "kube-system"}))
+
+	_, err = newKubeStateFilter(&appconfig.K8sKubeStateConfig{LabelSelector: "app in ("})
+	assert.Error(t, err)
+}
+
+func TestBuildKubeStateMetrics(t *testing.T) {
+	replicas := int32(3)
+	objs := &kubeStateObjects{
+		pods: []*v1.Pod{{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo-1"},
+			Status: v1.PodStatus{
+				Phase: v1.PodRunning,
+				ContainerStatuses: []v1.ContainerStatus{{
+					Name:         "app",
+					Ready:        true,
+					RestartCount: 4,
+					LastTerminationState: v1.ContainerState{
+						Terminated: &v1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137},
+					},
+				}},
+			},
+		}, {
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo-2"},
+			Status: v1.PodStatus{
+				Phase: v1.PodPending,
+				Conditions: []v1.PodCondition{{
+					Type: v1.PodScheduled, Status: v1.ConditionFalse, Reason: v1.PodReasonUnschedulable,
+				}},
+			},
+		}},
+		nodes: []*v1.Node{{
+			ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+			Status: v1.NodeStatus{Conditions: []v1.NodeCondition{
+				{Type: v1.NodeReady, Status: v1.ConditionTrue},
+				{Type: v1.NodeMemoryPressure, Status: v1.ConditionFalse},
+			}},
+		}},
+		deployments: []*appsv1.Deployment{{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo"},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+			Status:     appsv1.DeploymentStatus{AvailableReplicas: 1, UnavailableReplicas: 2},
+		}},
+	}
+
+	values := make(map[string]float64)
+	for _, m := range buildKubeStateMetrics(1690000000000, objs) {
+		assert.Equal(t, int64(1690000000000), m.Timestamp)
+		key := m.Name
+		for _, tag := range []string{"pod", "phase", "reason", "condition", "deployment"} {
+			if v, ok := m.Tags[tag]; ok {
+				key += "/" + v
+			}
+		}
+		values[key] = m.Value
+	}
+
+	assert.Equal(t, float64(1), values["k8s_pod_phase_count/Running"])
+	assert.Equal(t, float64(1), values["k8s_pod_phase_count/Pending"])
+	assert.Equal(t, float64(0), values["k8s_pod_phase_count/Failed"])
+	assert.Equal(t, float64(1), values["k8s_pod_pending_count/Unschedulable"])
+	assert.Equal(t, float64(4), values["k8s_kube_state_container_restarts/demo-1"])
+	assert.Equal(t, float64(1), values["k8s_container_ready/demo-1"])
+	assert.Equal(t, float64(137), values["k8s_container_last_terminated/demo-1/OOMKilled"])
+	assert.Equal(t, float64(1), values["k8s_node_condition/Ready"])
+	assert.Equal(t, float64(0), values["k8s_node_condition/MemoryPressure"])
+	assert.Equal(t, float64(0), values["k8s_node_unschedulable"])
+	assert.Equal(t, float64(3), values["k8s_deployment_replicas_desired/demo"])
+	assert.Equal(t, float64(1), values["k8s_deployment_replicas_available/demo"])
+	assert.Equal(t, float64(2), values["k8s_deployment_replicas_unavailable/demo"])
+}
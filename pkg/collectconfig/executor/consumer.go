@@ -15,6 +15,7 @@ import (
 	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig/executor/agg"
 	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig/executor/dryrun/event"
 	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig/executor/logstream"
+	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig/executor/masking"
 	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig/executor/storage"
 	"github.com/traas-stack/holoinsight-agent/pkg/collecttask"
 	"github.com/traas-stack/holoinsight-agent/pkg/cri"
@@ -73,6 +74,8 @@ type (
 
 		debugEvent *event.Event
 		runInLock  func(f func())
+		// masker masks samples before they leave the consumer, nil means masking is disabled
+		masker *masking.Masker
 
 		consumerState
 	}
@@ -115,11 +118,15 @@ type (
 		Emit                   int32
 		EmitSuccess            int32
 		EmitError              int32
+		// rows dropped because details byte budget is exceeded
+		FilterDetailBudget int32
 		// error count when agg
 		AggWhereError int32
 		// error count when select
 		SelectError int32
-		ZeroBytes   int
+		// error count when evaluating expressions
+		ExpressionError int32
+		ZeroBytes       int
 	}
 
 	ParsedConf struct {
@@ -151,6 +158,8 @@ type (
 		Watermark          int64
 		Shards             []*shardSateObj
 		ConsumerStat       ConsumerStat
+		// SubState is the state of StatefulSubConsumer
+		SubState interface{}
 	}
 	// shardSateObj is storage.Shard state obj for gob.
 	shardSateObj struct {
@@ -189,12 +198,14 @@ func (c *Consumer) reportUpEvent(expectedTs int64, ps *PeriodStatus) {
 			"in_groups":    int64(ps.Stat.Groups),
 			"in_processed": int64(ps.Stat.Processed),
 
-			"f_where": int64(ps.Stat.FilterWhere),
-			"f_group": int64(ps.Stat.FilterGroup),
-			"f_gkeys": int64(ps.Stat.FilterGroupMaxKeys),
-			"f_delay": int64(ps.Stat.FilterDelay),
+			"f_where":   int64(ps.Stat.FilterWhere),
+			"f_group":   int64(ps.Stat.FilterGroup),
+			"f_gkeys":   int64(ps.Stat.FilterGroupMaxKeys),
+			"f_delay":   int64(ps.Stat.FilterDelay),
+			"f_dbudget": int64(ps.Stat.FilterDetailBudget),
 
 			"p_select": int64(ps.Stat.SelectError),
+			"p_expr":   int64(ps.Stat.ExpressionError),
 		},
 		Strings: nil,
 	}
@@ -582,7 +593,10 @@ func convertStringMapToInterfaceMap(m map[string]string) map[string]interface{}
 }
 
 func (c *Consumer) processMultiline(iw *inputWrapper, resp *logstream.ReadResponse, consumer func(*LogContext)) {
-	ctx := &LogContext{}
+	ctx := &LogContext{stat: &c.stat}
+	if c.ct != nil && c.ct.Target != nil {
+		ctx.target = c.ct.Target.Meta
+	}
 	oneLine := &LogGroup{Lines: []string{""}}
 
 	tz := c.getTargetTimezone()
@@ -617,7 +631,7 @@ func (c *Consumer) processMultiline(iw *inputWrapper, resp *logstream.ReadRespon
 				}
 			}
 		} else {
-			for _, line := range lines {
+			for i, line := range lines {
 				oneLine.SetOneLine(line)
 				if line == "" || strings.HasPrefix(line, "\tat") || (strings.HasPrefix(line, "\t... ") && strings.HasSuffix(line, " more")) {
 					c.stat.FilterIgnore++
@@ -626,6 +640,7 @@ func (c *Consumer) processMultiline(iw *inputWrapper, resp *logstream.ReadRespon
 				ctx.log = oneLine
 				ctx.path = iw.FatPath.Path
 				ctx.pathTags = iw.FatPath.Tags
+				ctx.logTags = getLineTags(resp, lines, i)
 				c.stat.Groups++
 				ctx.tz = tz
 				consumer(ctx)
@@ -646,22 +661,20 @@ func (c *Consumer) processMultiline(iw *inputWrapper, resp *logstream.ReadRespon
 			// 但此时无法知道该组是否已经完整了, 因为组的完成性是靠遇到下一个组的 start 才能确定的, 如果下一行首日志迟迟不打印呢? 或者由于各种原因, 暂时还没有读到下一行首日志呢
 
 			// 如果还有更多日志, 那么我们跳过本次操作让下次的读取来驱动 pendingLog 的 finish 即可
-			pending := c.multilineAccumulator.getAndClearPending()
-			// pending == nil means no more pending logs
-			if pending == nil || resp.HasMore {
+			// 否则可以断定当前就是读到日志尾了, 且tempLog里有日志残留, 它们是否自成一组呢?
+			// 我们现在认为, 读到文件尾后(hasMore==false), 如果在 timeout 时间内没有新的行加入, 那么最后一组自成一组
+			pending := c.multilineAccumulator.flush(resp.HasMore, time.Now())
+			// pending == nil means no pending logs or the pending logs are not complete
+			if pending == nil {
 				continue
 			}
 
-			// Here, means we have pending logs
-
-			// 否则可以断定当前就是读到日志尾了, 且tempLog里有日志残留, 它们是否自成一组呢?
-			// 我们现在认为, 一旦读到文件尾了(hasMore==false), 那么最后一组一定自成一组, 虽然这个假设在极端情况下不成立
-
 			fullGroup = pending
 		} else {
 			line := lines[i]
 			oneLine.SetOneLine(line)
 			ctx.log = oneLine
+			ctx.logTags = getLineTags(resp, lines, i)
 
 			fullGroup, err = c.multilineAccumulator.add(ctx)
 			if err != nil {
@@ -674,6 +687,7 @@ func (c *Consumer) processMultiline(iw *inputWrapper, resp *logstream.ReadRespon
 			}
 		}
 		ctx.log = fullGroup
+		ctx.logTags = fullGroup.Tags
 		ctx.path = iw.FatPath.Path
 		ctx.pathTags = iw.FatPath.Tags
 		c.stat.Groups++
@@ -683,6 +697,29 @@ func (c *Consumer) processMultiline(iw *inputWrapper, resp *logstream.ReadRespon
 	}
 }
 
+var (
+	// Line tags of container stdout logs, they are shared by all lines and must not be modified
+	stdoutLineTags = map[string]interface{}{"stream": logstream.StreamStdout}
+	stderrLineTags = map[string]interface{}{"stream": logstream.StreamStderr}
+)
+
+// getLineTags returns tags of lines[i], it returns nil if there is no line level tags
+func getLineTags(resp *logstream.ReadResponse, lines []string, i int) map[string]interface{} {
+	if len(resp.Streams) != len(lines) {
+		return nil
+	}
+	switch stream := resp.Streams[i]; stream {
+	case "":
+		return nil
+	case logstream.StreamStdout:
+		return stdoutLineTags
+	case logstream.StreamStderr:
+		return stderrLineTags
+	default:
+		return map[string]interface{}{"stream": stream}
+	}
+}
+
 func (c *Consumer) createPoint(alignTs int64, groupKeyValues []string) *storage.Point {
 	xs := c.Select.(*xSelect)
 	values := make([]interface{}, len(xs.values))
@@ -783,12 +820,14 @@ func (c *Consumer) createStatEvent(stat ConsumerStat) *pb2.ReportEventRequest_Ev
 			"f_delay":     int64(stat.FilterDelay),
 			"f_multiline": int64(stat.FilterMultiline),
 			"f_zerobytes": int64(stat.ZeroBytes),
+			"f_dbudget":   int64(stat.FilterDetailBudget),
 
 			"out_emit":  int64(stat.Emit),
 			"out_error": int64(stat.EmitError),
 
 			"p_agg":    int64(stat.AggWhereError),
 			"p_select": int64(stat.SelectError),
+			"p_expr":   int64(stat.ExpressionError),
 		},
 		Strings: map[string]string{},
 	}
@@ -1090,7 +1129,7 @@ func (c *Consumer) executeSelectAgg(processGroupEvent *event.Event, ctx *LogCont
 	}
 
 	// handle log samples
-	if xs.logSamples != nil && len(point.LogSamples) < xs.logSamples.MaxCount {
+	if xs.logSamples != nil && (len(point.LogSamples) < xs.logSamples.MaxCount || xs.logSamples.Strategy == logSamplesStrategyReservoir || xs.logSamples.Dedup) {
 		whereOk := false
 		if xs.logSamples.Where == nil {
 			whereOk = true
@@ -1098,11 +1137,14 @@ func (c *Consumer) executeSelectAgg(processGroupEvent *event.Event, ctx *LogCont
 			whereOk = true
 		}
 		if whereOk {
-			truncatedLines := make([]string, len(ctx.log.Lines))
-			for i, line := range ctx.log.Lines {
-				truncatedLines[i] = util.SubstringMax(line, xs.logSamples.MaxLength)
-			}
-			point.LogSamples = append(point.LogSamples, truncatedLines)
+			xs.logSamples.add(point, ctx.log.Lines, func() []string {
+				truncatedLines := make([]string, len(ctx.log.Lines))
+				for i, line := range ctx.log.Lines {
+					// masks before truncating, otherwise a truncated sensitive value may not be detected
+					truncatedLines[i] = util.SubstringMax(c.maskSample(line), xs.logSamples.MaxLength)
+				}
+				return truncatedLines
+			})
 		}
 	}
 
@@ -1134,6 +1176,7 @@ func (c *Consumer) getOrCreateStoragePoint(alignTs int64, ctx *LogContext, shard
 }
 
 func (c *Consumer) init() {
+	c.masker = getSampleMasker()
 	if c.sub != nil {
 		c.sub.init()
 	}
@@ -1176,6 +1219,10 @@ func (c *Consumer) SaveState() (*consumerStateObj, error) {
 		state.Shards = append(state.Shards, s)
 	}
 
+	if s, ok := c.sub.(StatefulSubConsumer); ok {
+		state.SubState = s.saveState()
+	}
+
 	return state, nil
 }
 
@@ -1194,6 +1241,10 @@ func (c *Consumer) LoadState(state *consumerStateObj) error {
 		}
 	}
 
+	if s, ok := c.sub.(StatefulSubConsumer); ok && state.SubState != nil {
+		s.loadState(state.SubState)
+	}
+
 	return nil
 }
 
@@ -4,10 +4,14 @@
 
 package executor
 
+import "time"
+
 type (
 	multilineAccumulator struct {
 		pendingLog *LogGroup
 		multiline  *xMultiline
+		// pendingTime is the time when the last line is added to pendingLog
+		pendingTime time.Time
 	}
 )
 
@@ -22,6 +26,7 @@ func (a *multilineAccumulator) add(ctx *LogContext) (ret *LogGroup, err error) {
 	if err != nil {
 		return nil, err
 	}
+	a.pendingTime = time.Now()
 
 	if b {
 		if a.multiline.what == multilineWhatPrevious {
@@ -42,7 +47,7 @@ func (a *multilineAccumulator) add(ctx *LogContext) (ret *LogGroup, err error) {
 		} else {
 			// 该行匹配 where, 纳入当前组
 			if a.pendingLog == nil {
-				a.pendingLog = &LogGroup{Line: ctx.GetLine(), Lines: []string{ctx.GetLine()}}
+				a.pendingLog = &LogGroup{Line: ctx.GetLine(), Lines: []string{ctx.GetLine()}, Tags: ctx.logTags}
 			} else {
 				a.pendingLog.Add(ctx.GetLine())
 				if len(a.pendingLog.Lines) >= a.multiline.maxLines {
@@ -56,17 +61,35 @@ func (a *multilineAccumulator) add(ctx *LogContext) (ret *LogGroup, err error) {
 		if a.multiline.what == multilineWhatPrevious {
 			// 该行不匹配 where, 因此它中断 pendingLog, 并且自立一个新分组, 它成为行首
 			ret = a.pendingLog
-			a.pendingLog = &LogGroup{Line: ctx.GetLine(), Lines: []string{ctx.GetLine()}}
+			a.pendingLog = &LogGroup{Line: ctx.GetLine(), Lines: []string{ctx.GetLine()}, Tags: ctx.logTags}
 		} else {
 			// 该行不匹配 where, 因此它中断 pendingLog, 它是pending的最后一行
-			a.pendingLog.Add(ctx.GetLine())
-			ret = a.pendingLog
-			a.pendingLog = nil
+			if a.pendingLog == nil {
+				// 没有 pending 时该行自成一组
+				ret = &LogGroup{Line: ctx.GetLine(), Lines: []string{ctx.GetLine()}, Tags: ctx.logTags}
+			} else {
+				a.pendingLog.Add(ctx.GetLine())
+				ret = a.pendingLog
+				a.pendingLog = nil
+			}
 		}
 	}
 	return
 }
 
+// flush returns the pending log group when it is considered complete at the end of a read.
+// When there are more logs to read, the next read will drive the pending log group to finish.
+// Otherwise the pending log group is flushed after no more lines are added for a while.
+func (a *multilineAccumulator) flush(hasMore bool, now time.Time) *LogGroup {
+	if a.pendingLog == nil || hasMore {
+		return nil
+	}
+	if now.Sub(a.pendingTime) < a.multiline.timeout {
+		return nil
+	}
+	return a.getAndClearPending()
+}
+
 func (a *multilineAccumulator) getAndClearPending() *LogGroup {
 	pending := a.pendingLog
 	a.pendingLog = nil
@@ -16,4 +16,10 @@ type (
 		init()
 		MaybeFlush()
 	}
+	// StatefulSubConsumer is a SubConsumer which holds state across time windows.
+	// Its state is saved and loaded along with Consumer state, so it must be encodable by gob.
+	StatefulSubConsumer interface {
+		saveState() interface{}
+		loadState(state interface{})
+	}
 )
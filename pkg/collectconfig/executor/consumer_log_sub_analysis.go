@@ -8,21 +8,59 @@ import (
 	"encoding/gob"
 	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig"
 	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig/executor/storage"
+	"github.com/traas-stack/holoinsight-agent/pkg/ioc"
 	"github.com/traas-stack/holoinsight-agent/pkg/loganalysis"
 	"github.com/traas-stack/holoinsight-agent/pkg/logger"
 	"github.com/traas-stack/holoinsight-agent/pkg/model"
+	pb2 "github.com/traas-stack/holoinsight-agent/pkg/server/registry/pb"
 	"github.com/traas-stack/holoinsight-agent/pkg/util"
 	"go.uber.org/zap"
+	"sync"
+)
+
+const (
+	defaultNewPatternsMaxEvents = 10
 )
 
 type (
 	logAnalysisSubConsumer struct {
 		parent *Consumer
 		conf   *ParsedConf
+		// mutex protects drain and novelty
+		mutex sync.Mutex
+		// drain is not nil when algorithm is 'drain'. Its parse tree lives across time windows.
+		drain *loganalysis.DrainMiner
+		// novelty is not nil when new patterns detection is enabled
+		novelty              *loganalysis.NoveltyTracker
+		newPatternsMaxEvents int
 	}
 	logAnalysisSubConsumerState struct {
 		LogAnalyzer   *loganalysis.Analyzer
 		KnownPatterns map[string]*loganalysis.AnalyzedLog
+		// drain cluster id -> count in this time window
+		DrainCounts map[int64]*drainTemplateCount
+	}
+	// logAnalysisPersistentState is the state lives across time windows
+	logAnalysisPersistentState struct {
+		Drain   *loganalysis.DrainMiner
+		Novelty *loganalysis.NoveltyTracker
+	}
+	drainTemplateCount struct {
+		// Key is the stable id of the cluster in this agent
+		Key string
+		// TemplateKey and Template are the template when the cluster is first seen in this time window.
+		// They are used when the cluster has been evicted before emitting.
+		TemplateKey string
+		Template    string
+		Sample      string
+		Count       int
+	}
+	// newPattern is a pattern seen for the first time
+	newPattern struct {
+		key    string
+		name   string
+		sample string
+		count  int
 	}
 )
 
@@ -31,12 +69,67 @@ func (c *logAnalysisSubConsumer) MaybeFlush() {
 
 func init() {
 	gob.Register(&logAnalysisSubConsumerState{})
+	gob.Register(&logAnalysisPersistentState{})
 }
 
 func newLogAnalysisSubConsumer(conf *collectconfig.LogAnalysisConf) (*logAnalysisSubConsumer, error) {
-	return &logAnalysisSubConsumer{
+	c := &logAnalysisSubConsumer{
 		conf: parseLogAnalysisConf(conf),
-	}, nil
+	}
+	if conf.Algorithm == collectconfig.LogAnalysisAlgorithmDrain {
+		drainConf := conf.Drain
+		if drainConf == nil {
+			drainConf = &collectconfig.LogAnalysisDrainConf{}
+		}
+		c.drain = loganalysis.NewDrainMiner(drainConf.Depth, drainConf.SimilarityThreshold, drainConf.MaxChildren, drainConf.MaxTemplates, conf.MaxLogLength)
+	}
+	if np := conf.NewPatterns; np != nil && np.Enabled {
+		horizon := util.ParseDurationDefault(np.Horizon, loganalysis.DefaultNoveltyHorizon)
+		warmup := util.ParseDurationDefault(np.Warmup, loganalysis.DefaultNoveltyWarmup)
+		c.novelty = loganalysis.NewNoveltyTracker(horizon, warmup, 0)
+		c.newPatternsMaxEvents = np.MaxEvents
+		if c.newPatternsMaxEvents <= 0 {
+			c.newPatternsMaxEvents = defaultNewPatternsMaxEvents
+		}
+	}
+	return c, nil
+}
+
+func (c *logAnalysisSubConsumer) saveState() interface{} {
+	if c.drain == nil && c.novelty == nil {
+		return nil
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return &logAnalysisPersistentState{
+		Drain:   c.drain,
+		Novelty: c.novelty,
+	}
+}
+
+func (c *logAnalysisSubConsumer) loadState(state interface{}) {
+	ps, ok := state.(*logAnalysisPersistentState)
+	if !ok {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Options of current config take precedence
+	if miner := ps.Drain; miner != nil && c.drain != nil {
+		miner.Depth = c.drain.Depth
+		miner.SimThreshold = c.drain.SimThreshold
+		miner.MaxChildren = c.drain.MaxChildren
+		miner.MaxClusters = c.drain.MaxClusters
+		miner.MaxLogLength = c.drain.MaxLogLength
+		c.drain = miner
+	}
+	if tracker := ps.Novelty; tracker != nil && c.novelty != nil {
+		tracker.Horizon = c.novelty.Horizon
+		tracker.Warmup = c.novelty.Warmup
+		tracker.MaxKeys = c.novelty.MaxKeys
+		c.novelty = tracker
+	}
 }
 
 func newLogAnalysisSubConsumerState(conf *ParsedConf) *logAnalysisSubConsumerState {
@@ -127,14 +220,77 @@ func (c *logAnalysisSubConsumer) ProcessGroup(iw *inputWrapper, ctx *LogContext,
 			if t, ok := state.KnownPatterns[pattern.Name]; ok {
 				t.Count++
 			} else {
-				t = &loganalysis.AnalyzedLog{Sample: ctx.GetLine(), Count: 1}
+				t = &loganalysis.AnalyzedLog{Sample: c.parent.maskSample(ctx.GetLine()), Count: 1}
 				state.KnownPatterns[pattern.Name] = t
 			}
 			return
 		}
 	}
 
-	state.LogAnalyzer.Analyze(ctx.GetLine())
+	// Unknown logs are masked before being analyzed, because their parts and templates are sent to server too
+	line := c.parent.maskSample(ctx.GetLine())
+	if c.drain != nil {
+		c.addToDrain(state, line, ts)
+		return
+	}
+
+	state.LogAnalyzer.Analyze(line)
+}
+
+func (c *logAnalysisSubConsumer) addToDrain(state *logAnalysisSubConsumerState, line string, ts int64) {
+	c.mutex.Lock()
+	cluster := c.drain.Add(line, ts)
+	if cluster == nil {
+		c.mutex.Unlock()
+		return
+	}
+	id := cluster.ID
+	if state.DrainCounts == nil {
+		state.DrainCounts = make(map[int64]*drainTemplateCount)
+	}
+	t, ok := state.DrainCounts[id]
+	if !ok {
+		t = &drainTemplateCount{Key: cluster.Key, TemplateKey: cluster.TemplateKey(), Template: cluster.Template(), Sample: line}
+		state.DrainCounts[id] = t
+	}
+	c.mutex.Unlock()
+	t.Count++
+}
+
+// mergeDrainCounts merges counts of clusters by their template keys, and fills current templates.
+// Template key only depends on the converged template, so the same template has the same id across agents.
+// Clusters with the same template key (e.g. re-created after eviction) are summed, the smallest cluster key is kept for novelty.
+func (c *logAnalysisSubConsumer) mergeDrainCounts(counts map[int64]*drainTemplateCount) (map[string]*drainTemplateCount, int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	byTemplateKey := make(map[string]*drainTemplateCount, len(counts))
+	total := 0
+	for id, t := range counts {
+		template := t.Template
+		templateKey := t.TemplateKey
+		if cluster := c.drain.Get(id); cluster != nil {
+			template = cluster.Template()
+			templateKey = cluster.TemplateKey()
+		}
+		total += t.Count
+		if merged, ok := byTemplateKey[templateKey]; ok {
+			merged.Count += t.Count
+			if t.Key < merged.Key {
+				merged.Key = t.Key
+			}
+		} else {
+			byTemplateKey[templateKey] = &drainTemplateCount{
+				Key:         t.Key,
+				TemplateKey: templateKey,
+				Template:    template,
+				Sample:      t.Sample,
+				Count:       t.Count,
+			}
+		}
+	}
+
+	return byTemplateKey, total
 }
 
 func (c *logAnalysisSubConsumer) Emit(expectedTs int64) bool {
@@ -163,26 +319,66 @@ func (c *logAnalysisSubConsumer) Emit(expectedTs int64) bool {
 	state.LogAnalyzer.Clear()
 
 	var metrics []*model.DetailData
+	var newPatterns []*newPattern
 	unknownPatternLogsCount := 0
 	for _, log := range analyzedLogs {
 		unknownPatternLogsCount += log.Count
+		if key := "similarity/" + log.Key(); c.observe(key, expectedTs) {
+			log.New = true
+			newPatterns = append(newPatterns, &newPattern{key: key, name: "__analysis", sample: log.Sample, count: log.Count})
+		}
 	}
 
 	totalCount := unknownPatternLogsCount
 
+	if len(state.DrainCounts) > 0 {
+		byTemplateKey, drainCount := c.mergeDrainCounts(state.DrainCounts)
+		state.DrainCounts = nil
+		totalCount += drainCount
+		for templateKey, t := range byTemplateKey {
+			// templates change when logs are merged, so novelty is keyed on the stable cluster key
+			values := map[string]interface{}{
+				"value":    t.Count,
+				"template": t.Template,
+				"sample":   t.Sample,
+			}
+			if key := "drain/" + t.Key; c.observe(key, expectedTs) {
+				values["new"] = true
+				newPatterns = append(newPatterns, &newPattern{key: key, name: t.Template, sample: t.Sample, count: t.Count})
+			}
+			metrics = append(metrics, &model.DetailData{
+				Timestamp: expectedTs,
+				Tags: map[string]string{
+					"eventName":  "__template",
+					"templateId": templateKey,
+				},
+				Values: values,
+			})
+		}
+	}
+
 	knownPatterns := state.KnownPatterns
 	state.KnownPatterns = make(map[string]*loganalysis.AnalyzedLog)
 	for pattern, t := range knownPatterns {
 		totalCount += t.Count
 
+		if key := "known/" + pattern; c.observe(key, expectedTs) {
+			t.New = true
+			newPatterns = append(newPatterns, &newPattern{key: key, name: pattern, sample: t.Sample, count: t.Count})
+		}
+
 		r := &loganalysis.Unknown{AnalyzedLogs: []*loganalysis.AnalyzedLog{t}}
+		values := map[string]interface{}{
+			"value":    t.Count,
+			"analysis": util.ToJsonString(r),
+		}
+		if t.New {
+			values["new"] = true
+		}
 		metrics = append(metrics, &model.DetailData{
 			Timestamp: expectedTs,
 			Tags:      map[string]string{"eventName": pattern},
-			Values: map[string]interface{}{
-				"value":    t.Count,
-				"analysis": util.ToJsonString(r),
-			},
+			Values:    values,
 		})
 	}
 
@@ -203,8 +399,56 @@ func (c *logAnalysisSubConsumer) Emit(expectedTs int64) bool {
 		})
 	}
 
+	if c.novelty != nil && len(metrics) > 0 {
+		metrics = append(metrics, &model.DetailData{
+			Timestamp: expectedTs,
+			Tags:      map[string]string{"eventName": "__new_patterns"},
+			Values: map[string]interface{}{
+				"value": len(newPatterns),
+			},
+		})
+		c.reportNewPatterns(expectedTs, newPatterns)
+	}
+
 	c.parent.stat.Emit += int32(len(metrics))
 	c.parent.AddBatchDetailDatus(expectedTs, metrics)
 
 	return len(metrics) > 0
 }
+
+// observe returns true if the pattern is new, it always returns false when new patterns detection is disabled
+func (c *logAnalysisSubConsumer) observe(key string, ts int64) bool {
+	if c.novelty == nil {
+		return false
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.novelty.Observe(key, ts)
+}
+
+// reportNewPatterns reports new patterns as DIGEST events, at most newPatternsMaxEvents events are reported per time window
+func (c *logAnalysisSubConsumer) reportNewPatterns(expectedTs int64, newPatterns []*newPattern) {
+	if len(newPatterns) > c.newPatternsMaxEvents {
+		logger.Infoz("[consumer] [loganalysis] too many new patterns",
+			zap.String("key", c.parent.key),
+			zap.Int("count", len(newPatterns)))
+		newPatterns = newPatterns[:c.newPatternsMaxEvents]
+	}
+	for _, p := range newPatterns {
+		tags := c.parent.getCommonEventTags()
+		tags["pattern_key"] = p.key
+		ioc.RegistryService.ReportEventAsync(&pb2.ReportEventRequest_Event{
+			EventTimestamp: expectedTs,
+			EventType:      "DIGEST",
+			PayloadType:    "log_new_pattern",
+			Tags:           tags,
+			Numbers: map[string]int64{
+				"count": int64(p.count),
+			},
+			Strings: map[string]string{
+				"pattern": p.name,
+				"sample":  p.sample,
+			},
+		})
+	}
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package executor
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/traas-stack/holoinsight-agent/pkg/loganalysis"
+	"testing"
+	"time"
+)
+
+func TestLogAnalysisSubConsumer_drainNovelty(t *testing.T) {
+	c := &logAnalysisSubConsumer{
+		drain:   loganalysis.NewDrainMiner(0, 0, 0, 0, 0),
+		novelty: loganalysis.NewNoveltyTracker(time.Hour, 0, 0),
+	}
+
+	state := &logAnalysisSubConsumerState{}
+	c.addToDrain(state, "login user alice from web", 1)
+	c.addToDrain(state, "login user alice from web", 2)
+	byTemplateKey, total := c.mergeDrainCounts(state.DrainCounts)
+	assert.Equal(t, 2, total)
+	assert.Len(t, byTemplateKey, 1)
+	var first *drainTemplateCount
+	for _, t := range byTemplateKey {
+		first = t
+	}
+	assert.Equal(t, "login user alice from web", first.Template)
+	assert.True(t, c.observe("drain/"+first.Key, 60000))
+
+	// the template changes in the next window, but it is the same pattern
+	state = &logAnalysisSubConsumerState{}
+	c.addToDrain(state, "login user bob from app", 60001)
+	byTemplateKey, total = c.mergeDrainCounts(state.DrainCounts)
+	assert.Equal(t, 1, total)
+	assert.Len(t, byTemplateKey, 1)
+	var second *drainTemplateCount
+	for _, t := range byTemplateKey {
+		second = t
+	}
+	assert.Equal(t, "login user <*> from <*>", second.Template)
+	assert.Equal(t, first.Key, second.Key)
+	assert.False(t, c.observe("drain/"+second.Key, 120000))
+
+	// another agent whose first log differs emits the same template id for the converged template
+	other := &logAnalysisSubConsumer{drain: loganalysis.NewDrainMiner(0, 0, 0, 0, 0)}
+	otherState := &logAnalysisSubConsumerState{}
+	other.addToDrain(otherState, "login user carol from app", 1)
+	other.addToDrain(otherState, "login user dave from web", 2)
+	otherByTemplateKey, _ := other.mergeDrainCounts(otherState.DrainCounts)
+	assert.Contains(t, otherByTemplateKey, second.TemplateKey)
+	assert.Contains(t, byTemplateKey, second.TemplateKey)
+}
@@ -18,10 +18,19 @@ import (
 	"time"
 )
 
+const (
+	defaultDetailsMaxBytesPerMinute = 8 * 1024 * 1024
+)
+
 type (
 	detailConsumer struct {
 		parent *Consumer
 		table  *model.Table
+		// maxBytesPerMinute is the byte budget of details output per minute
+		maxBytesPerMinute int
+		// budgetMinute is the minute that usedBytes belongs to
+		budgetMinute int64
+		usedBytes    int
 	}
 )
 
@@ -190,7 +199,7 @@ func (c *detailConsumer) ProcessGroup(iw *inputWrapper, ctx *LogContext, maxTs *
 	}
 
 	for i := 0; i < len(groupValues); i++ {
-		row.TagValues[i] = groupValues[i]
+		row.TagValues[i] = c.parent.maskSample(groupValues[i])
 	}
 
 	for i, item := range xs.values {
@@ -205,13 +214,55 @@ func (c *detailConsumer) ProcessGroup(iw *inputWrapper, ctx *LogContext, maxTs *
 		row.FieldValues[i] = f64
 	}
 
+	if !c.consumeBudget(estimateRowBytes(row), time.Now()) {
+		c.parent.stat.FilterDetailBudget++
+		periodStatus.Stat.FilterDetailBudget++
+		if processGroupEvent != nil {
+			processGroupEvent.Info("details budget exceeded, break")
+		}
+		return
+	}
+
 	c.table.Rows = append(c.table.Rows, row)
 
 }
 
+// consumeBudget returns true if there is enough byte budget in current minute for a row of given size
+func (c *detailConsumer) consumeBudget(size int, now time.Time) bool {
+	minute := now.Unix() / 60
+	if c.budgetMinute != minute {
+		c.budgetMinute = minute
+		c.usedBytes = 0
+	}
+	if c.usedBytes+size > c.maxBytesPerMinute {
+		if c.usedBytes <= c.maxBytesPerMinute {
+			// log only once per minute
+			logger.Warnz("[consumer] [log] details budget exceeded", zap.String("key", c.parent.key), zap.Int("maxBytesPerMinute", c.maxBytesPerMinute))
+			// mark as logged
+			c.usedBytes = c.maxBytesPerMinute + 1
+		}
+		return false
+	}
+	c.usedBytes += size
+	return true
+}
+
+// estimateRowBytes estimates the output size of a row
+func estimateRowBytes(row *model.Row) int {
+	size := 8 + 8*len(row.FieldValues)
+	for _, tagValue := range row.TagValues {
+		size += len(tagValue)
+	}
+	return size
+}
+
 func (c *detailConsumer) Emit(expectedTs int64) bool {
 	return true
 }
 
 func (c *detailConsumer) init() {
+	c.maxBytesPerMinute = defaultDetailsMaxBytesPerMinute
+	if details := c.parent.task.GroupBy.Details; details != nil && details.MaxBytesPerMinute > 0 {
+		c.maxBytesPerMinute = details.MaxBytesPerMinute
+	}
 }
@@ -195,14 +195,19 @@ func (c *logStatSubConsumer) Emit(expectedTs int64) bool {
 			if len(v.LogSamples) > 0 {
 				logSamplesConf := c.parent.task.Select.LogSamples
 				if logSamplesConf != nil && logSamplesConf.Enabled {
+					sample := map[string]interface{}{
+						"hostname": c.parent.getTargetHostname(),
+						"logs":     v.LogSamples,
+					}
+					if len(v.LogSampleCounts) == len(v.LogSamples) {
+						sample["counts"] = v.LogSampleCounts
+					}
+					if logSamplesConf.Strategy == logSamplesStrategyReservoir {
+						sample["total"] = v.LogSamplesSeen
+					}
 					dd.Values["logsamples"] = util.ToJsonString(map[string]interface{}{
 						"maxCount": c.parent.task.Select.LogSamples.MaxCount,
-						"samples": []interface{}{
-							map[string]interface{}{
-								"hostname": c.parent.getTargetHostname(),
-								"logs":     v.LogSamples,
-							},
-						},
+						"samples":  []interface{}{sample},
 					})
 				}
 			}
@@ -0,0 +1,76 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package executor
+
+import (
+	"github.com/traas-stack/holoinsight-agent/pkg/appconfig"
+	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig/executor/masking"
+	"github.com/traas-stack/holoinsight-agent/pkg/logger"
+	"go.uber.org/zap"
+	"sync"
+)
+
+var (
+	sampleMaskerOnce sync.Once
+	sampleMasker     *masking.Masker
+)
+
+// getSampleMasker returns the masker of agent-level masking policy, it returns nil if the policy is disabled.
+func getSampleMasker() *masking.Masker {
+	sampleMaskerOnce.Do(func() {
+		conf := appconfig.StdAgentConfig.Data.Masking
+		if !conf.Enabled {
+			return
+		}
+		sampleMasker = newSampleMasker(conf)
+	})
+	return sampleMasker
+}
+
+// newSampleMasker creates a masker of the policy. It fails closed: invalid detectors and rules are skipped,
+// and built-in detectors are used if no valid detector is left, so that samples are never sent without masking.
+func newSampleMasker(conf appconfig.DataConfig_Masking) *masking.Masker {
+	var detectors []string
+	for _, detector := range conf.Detectors {
+		if _, ok := masking.BuiltinRule(detector); ok {
+			detectors = append(detectors, detector)
+		} else {
+			logger.Errorz("[consumer] unsupported masking detector, skip it", zap.String("detector", detector))
+		}
+	}
+	if len(detectors) == 0 {
+		detectors = masking.DefaultDetectors
+	}
+
+	rules := make([]*masking.Rule, 0, len(conf.Rules))
+	for _, r := range conf.Rules {
+		rule := &masking.Rule{
+			Name:       r.Name,
+			Expression: r.Expression,
+			Group:      r.Group,
+			KeepPrefix: r.KeepPrefix,
+			KeepSuffix: r.KeepSuffix,
+			MaskChar:   r.MaskChar,
+		}
+		if _, err := masking.NewMasker(nil, []*masking.Rule{rule}); err != nil {
+			logger.Errorz("[consumer] invalid masking rule, skip it", zap.String("name", r.Name), zap.Error(err))
+			continue
+		}
+		rules = append(rules, rule)
+	}
+
+	m, err := masking.NewMasker(detectors, rules)
+	if err != nil {
+		// It should not happen since all detectors and rules are valid
+		logger.Errorz("[consumer] invalid masking policy, use built-in detectors", zap.Error(err))
+		m, _ = masking.NewMasker(masking.DefaultDetectors, nil)
+	}
+	return m
+}
+
+// maskSample masks sensitive data of a sample before it leaves the consumer
+func (c *Consumer) maskSample(s string) string {
+	return c.masker.Mask(s)
+}
@@ -0,0 +1,32 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package executor
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/traas-stack/holoinsight-agent/pkg/appconfig"
+	"testing"
+)
+
+func TestNewSampleMasker_invalidPolicy(t *testing.T) {
+	// unknown detectors fall back to built-in detectors, bad rules are skipped
+	m := newSampleMasker(appconfig.DataConfig_Masking{
+		Enabled:   true,
+		Detectors: []string{"unknown"},
+		Rules: []*appconfig.DataConfig_Masking_Rule{
+			{Name: "bad", Expression: "(unclosed"},
+			{Name: "account", Expression: `account=(\w+)`, Group: 1},
+		},
+	})
+	assert.NotNil(t, m)
+	assert.Equal(t, "mail b********@example.com account=********", m.Mask("mail bob.smith@example.com account=abcd1234"))
+
+	// valid detectors are kept
+	m = newSampleMasker(appconfig.DataConfig_Masking{
+		Enabled:   true,
+		Detectors: []string{"phone", "unknown"},
+	})
+	assert.Equal(t, "phone *******5678 bob@example.com", m.Mask("phone 13812345678 bob@example.com"))
+}
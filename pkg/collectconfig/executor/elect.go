@@ -118,6 +118,15 @@ func parseElect0(e *collectconfig.Elect) (XElect, error) {
 		return xElectContextInstance, nil
 	case collectconfig.EElectRefVar:
 		return &xRefVar{name: e.RefVar.Name}, nil
+	case collectconfig.EElectExpression:
+		if e.Expression == nil {
+			return nil, errors.New("expression is nil")
+		}
+		x, err := compileExpression(e.Expression.Expression, e.Expression.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		return &xElectExpression{x: x}, nil
 	}
 	return nil, errors.New("unsupported elect type " + e.Type)
 }
@@ -0,0 +1,37 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package executor
+
+import "github.com/spf13/cast"
+
+type (
+	// xElectExpression elects the result of an expression
+	xElectExpression struct {
+		x *xExpression
+	}
+)
+
+func (x *xElectExpression) Init() {
+}
+
+func (x *xElectExpression) Elect(ctx *LogContext) (interface{}, error) {
+	return x.x.Elect(ctx)
+}
+
+func (x *xElectExpression) ElectString(ctx *LogContext) (string, error) {
+	v, err := x.x.Elect(ctx)
+	if err != nil || v == nil {
+		return "", err
+	}
+	return cast.ToStringE(v)
+}
+
+func (x *xElectExpression) ElectNumber(ctx *LogContext) (float64, error) {
+	v, err := x.x.Elect(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return cast.ToFloat64E(v)
+}
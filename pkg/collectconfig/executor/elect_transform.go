@@ -24,7 +24,7 @@ func (x *xElectWrap) Elect(ctx *LogContext) (interface{}, error) {
 	}
 
 	if x.transform != nil {
-		ctx2 := &LogContext{contextValue: v}
+		ctx2 := ctx.fork(v)
 		_, err = x.transform.Filter(ctx2)
 		return ctx2.contextValue, err
 	}
@@ -39,7 +39,7 @@ func (x *xElectWrap) ElectString(ctx *LogContext) (string, error) {
 	}
 
 	if x.transform != nil {
-		ctx2 := &LogContext{contextValue: v}
+		ctx2 := ctx.fork(v)
 		_, err = x.transform.Filter(ctx2)
 		return cast.ToString(ctx2.contextValue), err
 	}
@@ -54,7 +54,7 @@ func (x *xElectWrap) ElectNumber(ctx *LogContext) (float64, error) {
 	}
 
 	if x.transform != nil {
-		ctx2 := &LogContext{contextValue: v}
+		ctx2 := ctx.fork(v)
 		_, err = x.transform.Filter(ctx2)
 		if err != nil {
 			return cast.ToFloat64(ctx2.contextValue), err
@@ -123,6 +123,9 @@ func (e *DryRunExecutor) processLines(evt *event.Event, consumer *Consumer, fatp
 		Lines:       lines,
 		Path:        fatpath.Path,
 	}
+	if format := fatpath.Attrs[logstream.AttrContainerLogFormat]; logstream.IsValidContainerLogFormat(format) {
+		mockResp.Lines, mockResp.Streams = logstream.DecodeContainerLogLines(format, lines)
+	}
 	mockIw := &inputWrapper{
 		inputStateObj: inputStateObj{
 			FatPath: fatpath,
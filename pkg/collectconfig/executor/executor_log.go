@@ -17,6 +17,8 @@ type (
 		Line string
 		// 多行case, 99%的case都只有一行
 		Lines []string
+		// Tags of the first line, such as 'stream' of container stdout logs
+		Tags map[string]interface{}
 	}
 	// 日志上下文
 	LogContext struct {
@@ -34,6 +36,10 @@ type (
 		whereEvent   *event.WhereEvent
 		periodStatus *PeriodStatus
 		vars         map[string]interface{}
+		// target is meta of collect target, e.g. pod labels
+		target map[string]string
+		// stat is stat of the Consumer that this context belongs to
+		stat *ConsumerStat
 
 		// Value is a value related to this context.
 		// It is used when doing transform.
@@ -68,6 +74,24 @@ func (c *LogContext) GetColumnByName(name string) (interface{}, error) {
 	return nil, nil
 }
 
+// fork creates a child context sharing log data with c, it is used by transforms of elects
+func (c *LogContext) fork(contextValue interface{}) *LogContext {
+	return &LogContext{
+		log:          c.log,
+		path:         c.path,
+		pathTags:     c.pathTags,
+		columns:      c.columns,
+		columnMap:    c.columnMap,
+		logTags:      c.logTags,
+		tz:           c.tz,
+		periodStatus: c.periodStatus,
+		vars:         c.vars,
+		target:       c.target,
+		stat:         c.stat,
+		contextValue: contextValue,
+	}
+}
+
 func (c *LogContext) clearData() {
 	c.log = nil
 	c.columns = nil
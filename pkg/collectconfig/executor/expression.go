@@ -0,0 +1,311 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package executor
+
+import (
+	"errors"
+	"github.com/d5/tengo/v2"
+	"github.com/d5/tengo/v2/parser"
+	"github.com/d5/tengo/v2/stdlib"
+	"github.com/traas-stack/holoinsight-agent/pkg/util"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	defaultExpressionTimeout = 10 * time.Millisecond
+	// expressionMaxAllocs limits objects allocated by one evaluation
+	expressionMaxAllocs = 10000
+	expressionResultVar = "__result"
+)
+
+type (
+	// xExpression is a compiled tengo expression. It is compiled once when the Consumer is parsed.
+	// Like other parts of Consumer, it is not safe for concurrent use.
+	xExpression struct {
+		expression string
+		timeout    time.Duration
+		bytecode   *tengo.Bytecode
+		globals    []tengo.Object
+		// resultIndex is the global index of expressionResultVar
+		resultIndex int
+		// vars are variables loaded by the compiled expression, only they are converted to tengo objects
+		vars []expressionVar
+		// vm is reused by evaluations, it is recreated after an error or a timeout
+		vm *tengo.VM
+		// timer aborts vm when an evaluation exceeds timeout, it is reset for every evaluation
+		timer *time.Timer
+		// target and tags rarely change, so their objects are cached until the source maps change
+		target    map[string]string
+		targetObj tengo.Object
+		tags      map[string]interface{}
+		tagsObj   tengo.Object
+	}
+	expressionVar struct {
+		name  string
+		index int
+	}
+)
+
+var (
+	// expressionVars are variables available in expressions
+	expressionVars = []string{"line", "lines", "columns", "vars", "tags", "path", "target", "value"}
+	// expressionModules are tengo stdlib modules available in expressions, modules which access os or stdout (os, fmt) are excluded
+	expressionModules    = []string{"math", "text", "times", "json", "base64", "hex", "enum"}
+	errExpressionTimeout = errors.New("expression timeout")
+)
+
+func compileExpression(expression string, timeout string) (*xExpression, error) {
+	if strings.TrimSpace(expression) == "" {
+		return nil, errors.New("empty expression")
+	}
+
+	body := expressionResultVar + " := (" + expression + ")"
+	file, err := parseExpression([]byte(body))
+	if err != nil {
+		return nil, err
+	}
+
+	// stdlib modules referenced by the expression are imported implicitly, e.g. 'text.contains(line, "x")'
+	if modules := implicitModules(file.Stmts); len(modules) > 0 {
+		sb := strings.Builder{}
+		for _, module := range modules {
+			sb.WriteString(module + " := import(\"" + module + "\")\n")
+		}
+		sb.WriteString(body)
+		if file, err = parseExpression([]byte(sb.String())); err != nil {
+			return nil, err
+		}
+	}
+
+	// Compiles like tengo.Script, but keeps bytecode and globals so that the vm can be reused
+	symbolTable := tengo.NewSymbolTable()
+	for idx, fn := range tengo.GetAllBuiltinFunctions() {
+		symbolTable.DefineBuiltin(idx, fn.Name)
+	}
+	for _, name := range expressionVars {
+		symbolTable.Define(name)
+	}
+
+	c := tengo.NewCompiler(file.InputFile, symbolTable, nil, stdlib.GetModuleMap(expressionModules...), nil)
+	if err := c.Compile(file); err != nil {
+		return nil, err
+	}
+	bytecode := c.Bytecode()
+	bytecode.RemoveDuplicates()
+
+	result, _, ok := symbolTable.Resolve(expressionResultVar, false)
+	if !ok {
+		return nil, errors.New("no expression result")
+	}
+	globals := make([]tengo.Object, symbolTable.MaxSymbols()+1)
+	for i := range globals {
+		globals[i] = tengo.UndefinedValue
+	}
+
+	x := &xExpression{
+		expression:  expression,
+		timeout:     util.ParseDurationDefault(timeout, defaultExpressionTimeout),
+		bytecode:    bytecode,
+		globals:     globals,
+		resultIndex: result.Index,
+	}
+	loaded := loadedGlobals(bytecode)
+	for _, name := range expressionVars {
+		if symbol, _, _ := symbolTable.Resolve(name, false); loaded[symbol.Index] {
+			x.vars = append(x.vars, expressionVar{name: name, index: symbol.Index})
+		}
+	}
+	return x, nil
+}
+
+func parseExpression(input []byte) (*parser.File, error) {
+	fileSet := parser.NewFileSet()
+	srcFile := fileSet.AddFile("(expression)", -1, len(input))
+	return parser.NewParser(srcFile, input, nil).ParseFile()
+}
+
+// implicitModules returns expressionModules used as bases of selectors, e.g. 'text' of 'text.contains(line, "x")'
+func implicitModules(stmts []parser.Stmt) []string {
+	bases := make(map[string]bool)
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		switch v.Kind() {
+		case reflect.Interface, reflect.Ptr:
+			if v.IsNil() {
+				return
+			}
+			if s, ok := v.Interface().(*parser.SelectorExpr); ok {
+				if ident, ok := s.Expr.(*parser.Ident); ok {
+					bases[ident.Name] = true
+				}
+			}
+			walk(v.Elem())
+		case reflect.Struct:
+			for i := 0; i < v.NumField(); i++ {
+				if v.Type().Field(i).IsExported() {
+					walk(v.Field(i))
+				}
+			}
+		case reflect.Slice:
+			for i := 0; i < v.Len(); i++ {
+				walk(v.Index(i))
+			}
+		}
+	}
+	walk(reflect.ValueOf(stmts))
+
+	var modules []string
+	for _, module := range expressionModules {
+		if bases[module] {
+			modules = append(modules, module)
+		}
+	}
+	return modules
+}
+
+// loadedGlobals returns indexes of globals loaded by the bytecode, including those loaded by function literals
+func loadedGlobals(bytecode *tengo.Bytecode) map[int]bool {
+	loaded := make(map[int]bool)
+	scan := func(insts []byte) {
+		for i := 0; i < len(insts); {
+			op := insts[i]
+			operands, read := parser.ReadOperands(parser.OpcodeOperands[op], insts[i+1:])
+			if op == parser.OpGetGlobal {
+				loaded[operands[0]] = true
+			}
+			i += 1 + read
+		}
+	}
+	scan(bytecode.MainFunction.Instructions)
+	for _, c := range bytecode.Constants {
+		if fn, ok := c.(*tengo.CompiledFunction); ok {
+			scan(fn.Instructions)
+		}
+	}
+	return loaded
+}
+
+// eval evaluates the expression, and returns the result as a tengo object
+func (x *xExpression) eval(ctx *LogContext) (tengo.Object, error) {
+	obj, err := x.eval0(ctx)
+	if err != nil {
+		if ctx.stat != nil {
+			ctx.stat.ExpressionError++
+		}
+		if ctx.periodStatus != nil {
+			ctx.periodStatus.Stat.ExpressionError++
+		}
+	}
+	return obj, err
+}
+
+func (x *xExpression) eval0(ctx *LogContext) (tengo.Object, error) {
+	for _, v := range x.vars {
+		obj, err := x.getVar(ctx, v.name)
+		if err != nil {
+			return nil, err
+		}
+		x.globals[v.index] = obj
+	}
+
+	if x.vm == nil {
+		vm := tengo.NewVM(x.bytecode, x.globals, expressionMaxAllocs)
+		x.vm = vm
+		x.timer = time.AfterFunc(x.timeout, vm.Abort)
+	} else {
+		x.timer.Reset(x.timeout)
+	}
+	err := x.vm.Run()
+	if !x.timer.Stop() {
+		// The timer has fired, vm aborts silently. The vm is dropped, because the timer may abort it later.
+		x.vm = nil
+		x.timer = nil
+		return nil, errExpressionTimeout
+	}
+	if err != nil {
+		// vm keeps the error, so it can't be reused
+		x.vm = nil
+		x.timer = nil
+		return nil, err
+	}
+	return x.globals[x.resultIndex], nil
+}
+
+func (x *xExpression) getVar(ctx *LogContext, name string) (tengo.Object, error) {
+	switch name {
+	case "line":
+		if ctx.log != nil {
+			return &tengo.String{Value: ctx.log.Line}, nil
+		}
+	case "lines":
+		if ctx.log != nil {
+			lines := make([]tengo.Object, len(ctx.log.Lines))
+			for i, line := range ctx.log.Lines {
+				lines[i] = &tengo.String{Value: line}
+			}
+			return &tengo.ImmutableArray{Value: lines}, nil
+		}
+	case "columns":
+		if len(ctx.columnMap) > 0 {
+			return tengo.FromInterface(ctx.columnMap)
+		}
+		if len(ctx.columns) > 0 {
+			columns := make([]tengo.Object, len(ctx.columns))
+			for i, column := range ctx.columns {
+				columns[i] = &tengo.String{Value: column}
+			}
+			return &tengo.ImmutableArray{Value: columns}, nil
+		}
+	case "vars":
+		if ctx.vars != nil {
+			return tengo.FromInterface(ctx.vars)
+		}
+	case "tags":
+		if ctx.logTags == nil {
+			break
+		}
+		if x.tagsObj == nil || !sameMap(x.tags, ctx.logTags) {
+			obj, err := tengo.FromInterface(ctx.logTags)
+			if err != nil {
+				return nil, err
+			}
+			x.tags = ctx.logTags
+			x.tagsObj = &tengo.ImmutableMap{Value: obj.(*tengo.Map).Value}
+		}
+		return x.tagsObj, nil
+	case "path":
+		return &tengo.String{Value: ctx.path}, nil
+	case "target":
+		if x.targetObj == nil || !sameMap(x.target, ctx.target) {
+			target := make(map[string]tengo.Object, len(ctx.target))
+			for k, v := range ctx.target {
+				target[k] = &tengo.String{Value: v}
+			}
+			x.target = ctx.target
+			x.targetObj = &tengo.ImmutableMap{Value: target}
+		}
+		return x.targetObj, nil
+	case "value":
+		return tengo.FromInterface(ctx.contextValue)
+	}
+	return tengo.UndefinedValue, nil
+}
+
+// sameMap returns true if a and b are the same map instance. The cached map is referenced, so its address is not reused.
+func sameMap(a, b interface{}) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	return va.IsNil() == vb.IsNil() && va.Pointer() == vb.Pointer()
+}
+
+// Elect returns the result as a go value
+func (x *xExpression) Elect(ctx *LogContext) (interface{}, error) {
+	obj, err := x.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return tengo.ToInterface(obj), nil
+}
@@ -0,0 +1,165 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package executor
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig"
+	"testing"
+)
+
+func newExpressionTestContext() *LogContext {
+	return &LogContext{
+		log:  &LogGroup{Line: "GET /api 200", Lines: []string{"GET /api 200"}},
+		path: "/home/admin/logs/app.log",
+		columnMap: map[string]interface{}{
+			"method": "GET",
+			"cost":   12.5,
+			"items":  []interface{}{1, 2, 3},
+		},
+		vars:   map[string]interface{}{"app": "demo"},
+		target: map[string]string{"namespace": "default"},
+		stat:   &ConsumerStat{},
+	}
+}
+
+func TestExpression_elect(t *testing.T) {
+	e := &collectconfig.Elect{}
+	require.NoError(t, json.Unmarshal([]byte(`{"type":"expression","expression":{"expression":"text.to_upper(vars.app) + \"/\" + target.namespace + \"/\" + columns.method","timeout":"50ms"}}`), e))
+	x, err := parseElect(e)
+	require.NoError(t, err)
+
+	ctx := newExpressionTestContext()
+	s, err := x.ElectString(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "DEMO/default/GET", s)
+
+	e = &collectconfig.Elect{Type: collectconfig.EElectExpression, Expression: &collectconfig.ElectExpression{Expression: "columns.cost * 2"}}
+	x, err = parseElect(e)
+	require.NoError(t, err)
+	f, err := x.ElectNumber(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 25.0, f)
+
+	e = &collectconfig.Elect{Type: collectconfig.EElectExpression, Expression: &collectconfig.ElectExpression{Expression: "("}}
+	_, err = parseElect(e)
+	assert.Error(t, err)
+}
+
+func TestExpression_where(t *testing.T) {
+	ctx := newExpressionTestContext()
+	assert.True(t, testWhere(t, `{"expression":{"expression":"text.contains(line, \"/api\") && columns.cost > 10"}}`, ctx))
+	assert.False(t, testWhere(t, `{"expression":{"expression":"columns.method == \"POST\""}}`, ctx))
+	assert.True(t, testWhere(t, `{"expression":{"expression":"text.has_suffix(path, \".log\") && len(lines) == 1"}}`, ctx))
+	assert.Equal(t, int32(0), ctx.stat.ExpressionError)
+
+	// runtime errors are counted and treated as false
+	assert.False(t, testWhere(t, `{"expression":{"expression":"columns.method - 1"}}`, ctx))
+	assert.Equal(t, int32(1), ctx.stat.ExpressionError)
+}
+
+func TestExpression_timeout(t *testing.T) {
+	ctx := newExpressionTestContext()
+	ctx.periodStatus = &PeriodStatus{}
+	x, err := compileExpression("func() { for true {} }()", "5ms")
+	require.NoError(t, err)
+
+	_, err = x.Elect(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), ctx.stat.ExpressionError)
+	assert.Equal(t, int32(1), ctx.periodStatus.Stat.ExpressionError)
+
+	// a new vm is used after timeout
+	_, err = x.Elect(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, int32(2), ctx.stat.ExpressionError)
+}
+
+func TestExpression_vars(t *testing.T) {
+	x, err := compileExpression(`len(lines) > 0 && func() { return target.namespace }() == "default"`, "")
+	require.NoError(t, err)
+	var names []string
+	for _, v := range x.vars {
+		names = append(names, v.name)
+	}
+	// 'line' is not referenced although 'lines' contains it
+	assert.Equal(t, []string{"lines", "target"}, names)
+
+	ctx := newExpressionTestContext()
+	for i := 0; i < 3; i++ {
+		ret, err := x.Elect(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, true, ret)
+	}
+	// cached target is rebuilt when target changes
+	ctx.target = map[string]string{"namespace": "prod"}
+	ret, err := x.Elect(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, false, ret)
+
+	// cached objects can't be modified by expressions
+	x, err = compileExpression(`func() { target.namespace = "x"; return 1 }()`, "")
+	require.NoError(t, err)
+	_, err = x.Elect(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, "prod", ctx.target["namespace"])
+}
+
+func TestExpression_implicitModules(t *testing.T) {
+	for expression, expected := range map[string][]string{
+		`text.to_upper(line)`:                       {"text"},
+		`math.abs(-1) + len(text.split(line, " "))`: {"math", "text"},
+		`"see text." + line`:                        nil,
+		`mymath.x`:                                  nil,
+		`func() { return times.now() }()`:           {"times"},
+		`columns.method`:                            nil,
+	} {
+		file, err := parseExpression([]byte(expression))
+		require.NoError(t, err, expression)
+		assert.Equal(t, expected, implicitModules(file.Stmts), expression)
+	}
+
+	x, err := compileExpression(`"see text." + line`, "")
+	require.NoError(t, err)
+	ret, err := x.Elect(newExpressionTestContext())
+	assert.NoError(t, err)
+	assert.Equal(t, "see text.GET /api 200", ret)
+
+	// fmt writes to stdout, it is not available
+	_, err = compileExpression(`fmt.println(line)`, "")
+	assert.Error(t, err)
+}
+
+func TestExpression_reuseAfterError(t *testing.T) {
+	ctx := newExpressionTestContext()
+	x, err := compileExpression("columns.cost > 10 ? columns.cost : columns.method - 1", "")
+	require.NoError(t, err)
+
+	ret, err := x.Elect(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 12.5, ret)
+
+	ctx.columnMap["cost"] = 1
+	_, err = x.Elect(ctx)
+	assert.Error(t, err)
+
+	ctx.columnMap["cost"] = 20
+	ret, err = x.Elect(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(20), ret)
+}
+
+func TestTransform_expression(t *testing.T) {
+	c, err := loadTransformFilter("transforms/expression.yaml")
+	require.NoError(t, err)
+
+	ctx := newExpressionTestContext()
+	ctx.contextValue = 5
+	ret, err := c.Filter(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(13), ret)
+}
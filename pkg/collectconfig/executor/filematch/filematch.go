@@ -13,6 +13,10 @@ const (
 	TypeFormat    = "format"
 	TypeSls       = "sls"
 	TypeContainer = "container"
+	// TypeStdout matches stdout/stderr log file of a container of a pod
+	TypeStdout = "stdout"
+	// TypePodLogs matches log files declared by a pod, see PodLogsFileMatcher
+	TypePodLogs = "podlogs"
 )
 
 type (
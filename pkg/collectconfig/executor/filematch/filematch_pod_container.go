@@ -6,9 +6,9 @@ package filematch
 
 import (
 	"errors"
+	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig/executor/logstream"
 	"github.com/traas-stack/holoinsight-agent/pkg/collecttask"
 	"github.com/traas-stack/holoinsight-agent/pkg/cri/criutils"
-	"github.com/traas-stack/holoinsight-agent/pkg/cri/dockerutils"
 	"github.com/traas-stack/holoinsight-agent/pkg/ioc"
 )
 
@@ -29,7 +29,8 @@ func (m *ContainerFileMatcher) Find() ([]FatPath, int, error) {
 	return []FatPath{{
 		Path: c.LogPath,
 		Attrs: map[string]string{
-			dockerutils.AttrIsDockerJsonLog: "true",
+			// Log file of main biz container may be a docker json-file or a cri log file
+			logstream.AttrContainerLogFormat: logstream.ContainerLogFormatAuto,
 		},
 	}}, 0, nil
 }
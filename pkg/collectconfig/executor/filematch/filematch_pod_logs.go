@@ -0,0 +1,279 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package filematch
+
+import (
+	"github.com/traas-stack/holoinsight-agent/pkg/collecttask"
+	"github.com/traas-stack/holoinsight-agent/pkg/cri"
+	"github.com/traas-stack/holoinsight-agent/pkg/ioc"
+	"io/fs"
+	v1 "k8s.io/api/core/v1"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// AnnotationLogPaths is an opt-in pod annotation which declares log files of the pod.
+	// Its value is a list of globs of in-container paths separated by ',' or '\n'.
+	// A glob can be prefixed with '<k8s container name>:' to limit it to a container, otherwise it applies to all biz containers.
+	// Example: "/home/admin/logs/*.log,sidecar:/var/log/nginx/access.log"
+	AnnotationLogPaths = "holoinsight.io/log-paths"
+
+	defaultPodLogsPattern    = "*.log"
+	defaultPodLogsMaxMatched = 20
+	defaultPodLogsMaxVisited = 1000
+)
+
+type (
+	// PodLogsFileMatcher discovers log files declared by a pod.
+	// Log files come from two sources:
+	// 1. globs declared in pod annotation AnnotationLogPaths
+	// 2. files whose base names match pattern under emptyDir/hostPath volumes mounted by biz and sidecar containers
+	// Every matched file is tagged with namespace/pod/container.
+	PodLogsFileMatcher struct {
+		Target     *collecttask.CollectTarget
+		pattern    string
+		maxMatched int
+		maxVisited int
+	}
+	podLogPathDecl struct {
+		container string
+		glob      string
+	}
+	podLogsCollector struct {
+		pod        *cri.Pod
+		maxMatched int
+		visited    map[string]struct{}
+		// stats are stats of added files.
+		// The same file may be reached by different host paths, e.g. '/hostfs/proc/<pid>/root/...' from annotation and volume source from mounts.
+		stats []os.FileInfo
+		ret   []FatPath
+	}
+)
+
+func NewPodLogsFileMatcher(target *collecttask.CollectTarget, pattern string, maxMatched int) (*PodLogsFileMatcher, error) {
+	if pattern == "" {
+		pattern = defaultPodLogsPattern
+	}
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	if maxMatched <= 0 {
+		maxMatched = defaultPodLogsMaxMatched
+	}
+	return &PodLogsFileMatcher{
+		Target:     target,
+		pattern:    pattern,
+		maxMatched: maxMatched,
+		maxVisited: defaultPodLogsMaxVisited,
+	}, nil
+}
+
+func (m *PodLogsFileMatcher) IsDynamicMultiFiles() bool {
+	return true
+}
+
+func (m *PodLogsFileMatcher) Find() ([]FatPath, int, error) {
+	pod, err := ioc.Crii.GetPod(m.Target.GetNamespace(), m.Target.GetPodName())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	c := &podLogsCollector{
+		pod:        pod,
+		maxMatched: m.maxMatched,
+		visited:    make(map[string]struct{}),
+	}
+
+	var lastErr error
+	for _, decl := range parsePodLogPaths(pod.Annotations[AnnotationLogPaths]) {
+		if err := c.collectDecl(decl); err != nil {
+			lastErr = err
+		}
+	}
+	for _, ctr := range podLogContainers(pod) {
+		c.collectMounts(ctr, m.pattern, m.maxVisited)
+	}
+
+	// Errors of declared globs are ignored when some files are found
+	if len(c.ret) == 0 && lastErr != nil {
+		return nil, 0, lastErr
+	}
+	return c.ret, 0, nil
+}
+
+// podLogContainers returns containers may write logs, sandbox is excluded
+func podLogContainers(pod *cri.Pod) []*cri.Container {
+	containers := make([]*cri.Container, 0, len(pod.Biz)+len(pod.Sidecar))
+	containers = append(containers, pod.Biz...)
+	containers = append(containers, pod.Sidecar...)
+	return containers
+}
+
+func (c *podLogsCollector) full() bool {
+	return len(c.ret) >= c.maxMatched
+}
+
+func (c *podLogsCollector) add(ctr *cri.Container, hostPath string) {
+	if _, ok := c.visited[hostPath]; ok {
+		return
+	}
+	c.visited[hostPath] = struct{}{}
+	if stat, err := os.Stat(hostPath); err == nil {
+		for _, added := range c.stats {
+			if os.SameFile(added, stat) {
+				return
+			}
+		}
+		c.stats = append(c.stats, stat)
+	}
+	c.ret = append(c.ret, FatPath{
+		Path: hostPath,
+		Tags: map[string]string{
+			"namespace": c.pod.Namespace,
+			"pod":       c.pod.Name,
+			"container": ctr.K8sContainerName,
+		},
+	})
+}
+
+func (c *podLogsCollector) collectDecl(decl podLogPathDecl) error {
+	containers := c.pod.Biz
+	if decl.container != "" {
+		ctr, err := c.pod.GetContainer(decl.container)
+		if err != nil {
+			return err
+		}
+		containers = []*cri.Container{ctr}
+	}
+
+	for _, ctr := range containers {
+		hostPattern, err := transferGlobToHostPath(ctr, decl.glob)
+		if err != nil {
+			return err
+		}
+		files, err := filepath.Glob(hostPattern)
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			if c.full() {
+				return nil
+			}
+			c.add(ctr, file)
+		}
+	}
+	return nil
+}
+
+// collectMounts walks emptyDir/hostPath volumes mounted by ctr, and collects files whose base names match pattern
+func (c *podLogsCollector) collectMounts(ctr *cri.Container, pattern string, maxVisited int) {
+	for _, hostDir := range podLogVolumeDirs(c.pod, ctr) {
+		visited := 0
+		filepath.WalkDir(hostDir, func(path string, d fs.DirEntry, err error) error {
+			if c.full() {
+				return filepath.SkipAll
+			}
+			visited++
+			if visited >= maxVisited {
+				return filepath.SkipAll
+			}
+			if err != nil {
+				return nil
+			}
+			base := d.Name()
+			// skip hide files/dirs
+			if strings.HasPrefix(base, ".") && path != hostDir {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			if ok, _ := filepath.Match(pattern, base); ok {
+				c.add(ctr, path)
+			}
+			return nil
+		})
+	}
+}
+
+// podLogVolumeDirs returns host dirs of writable emptyDir/hostPath volumes mounted by ctr
+func podLogVolumeDirs(pod *cri.Pod, ctr *cri.Container) []string {
+	volumes := make(map[string]struct{}, len(pod.Spec.Volumes))
+	for i := range pod.Spec.Volumes {
+		v := &pod.Spec.Volumes[i]
+		if v.EmptyDir != nil || v.HostPath != nil {
+			volumes[v.Name] = struct{}{}
+		}
+	}
+	if len(volumes) == 0 {
+		return nil
+	}
+
+	var spec *v1.Container
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == ctr.K8sContainerName {
+			spec = &pod.Spec.Containers[i]
+			break
+		}
+	}
+	if spec == nil {
+		return nil
+	}
+
+	var dirs []string
+	for _, vm := range spec.VolumeMounts {
+		if _, ok := volumes[vm.Name]; !ok || vm.ReadOnly || vm.SubPath != "" {
+			continue
+		}
+		for _, mount := range ctr.Mounts {
+			if mount.Destination == vm.MountPath {
+				dirs = append(dirs, mount.Source)
+				break
+			}
+		}
+	}
+	return dirs
+}
+
+// parsePodLogPaths parses value of AnnotationLogPaths
+func parsePodLogPaths(value string) []podLogPathDecl {
+	var decls []podLogPathDecl
+	for _, item := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '\n' }) {
+		item = strings.TrimSpace(item)
+		decl := podLogPathDecl{glob: item}
+		if !strings.HasPrefix(item, "/") {
+			index := strings.IndexByte(item, ':')
+			if index <= 0 {
+				continue
+			}
+			decl.container = strings.TrimSpace(item[:index])
+			decl.glob = strings.TrimSpace(item[index+1:])
+		}
+		if !filepath.IsAbs(decl.glob) {
+			continue
+		}
+		decls = append(decls, decl)
+	}
+	return decls
+}
+
+// transferGlobToHostPath transfers a glob of in-container path to a glob of host path.
+// The static dir part of the glob is transferred, so the glob must not cross mount points.
+func transferGlobToHostPath(ctr *cri.Container, glob string) (string, error) {
+	dir := filepath.Dir(glob)
+	if index := strings.IndexAny(glob, "*?["); index >= 0 {
+		dir = filepath.Dir(glob[:index])
+	}
+	hostDir, err := cri.TransferToHostPathForContainer(ctr, dir, true)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(hostDir, strings.TrimPrefix(glob, dir)), nil
+}
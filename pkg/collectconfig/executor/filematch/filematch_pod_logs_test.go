@@ -0,0 +1,141 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package filematch
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traas-stack/holoinsight-agent/pkg/collecttask"
+	"github.com/traas-stack/holoinsight-agent/pkg/cri"
+	"github.com/traas-stack/holoinsight-agent/pkg/ioc"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+type (
+	fakePodCri struct {
+		cri.Interface
+		pod *cri.Pod
+	}
+)
+
+func (f *fakePodCri) GetPod(namespace, podName string) (*cri.Pod, error) {
+	return f.pod, nil
+}
+
+func TestParsePodLogPaths(t *testing.T) {
+	decls := parsePodLogPaths(" /home/admin/logs/*.log ,sidecar:/var/log/nginx/access.log\nbad, /tmp/a.log")
+	assert.Equal(t, []podLogPathDecl{
+		{glob: "/home/admin/logs/*.log"},
+		{container: "sidecar", glob: "/var/log/nginx/access.log"},
+		{glob: "/tmp/a.log"},
+	}, decls)
+}
+
+func TestPodLogsFileMatcher(t *testing.T) {
+	emptyDir := t.TempDir()
+	for _, name := range []string{"a.log", "d.txt", "sub/b.txt", ".hidden/c.log"} {
+		path := filepath.Join(emptyDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte("hello\n"), 0644))
+	}
+
+	pod := &cri.Pod{Pod: &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "foo-0",
+			Annotations: map[string]string{AnnotationLogPaths: "/home/admin/logs/sub/*.txt"},
+		},
+		Spec: v1.PodSpec{
+			Volumes: []v1.Volume{
+				{Name: "logs", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}},
+			},
+			Containers: []v1.Container{{
+				Name:         "app",
+				VolumeMounts: []v1.VolumeMount{{Name: "logs", MountPath: "/home/admin/logs"}},
+			}},
+		},
+	}}
+	app := &cri.Container{
+		Pod:              pod,
+		K8sContainerName: "app",
+		Mounts:           []*cri.MountPoint{{Source: emptyDir, Destination: "/home/admin/logs", RW: true}},
+	}
+	pod.All = []*cri.Container{app}
+	pod.Biz = []*cri.Container{app}
+
+	old := ioc.Crii
+	ioc.Crii = &fakePodCri{pod: pod}
+	defer func() { ioc.Crii = old }()
+
+	m, err := NewPodLogsFileMatcher(&collecttask.CollectTarget{
+		Type: collecttask.TargetPod,
+		Meta: map[string]string{"namespace": "default", "pod": "foo-0"},
+	}, "", 0)
+	require.NoError(t, err)
+
+	paths, _, err := m.Find()
+	require.NoError(t, err)
+
+	files := GetPaths(paths)
+	sort.Strings(files)
+	assert.Equal(t, []string{filepath.Join(emptyDir, "a.log"), filepath.Join(emptyDir, "sub/b.txt")}, files)
+	for _, p := range paths {
+		assert.Equal(t, map[string]string{"namespace": "default", "pod": "foo-0", "container": "app"}, p.Tags)
+	}
+}
+
+func TestPodLogsFileMatcher_overlap(t *testing.T) {
+	emptyDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(emptyDir, "a.log"), []byte("hello\n"), 0644))
+	// the same file is reached through another host path, like '/hostfs/proc/<pid>/root/...' of runc containers
+	rootfs := t.TempDir()
+	require.NoError(t, os.Link(filepath.Join(emptyDir, "a.log"), filepath.Join(rootfs, "a.log")))
+
+	pod := &cri.Pod{Pod: &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "foo-0",
+			Annotations: map[string]string{AnnotationLogPaths: "/data/*.log"},
+		},
+		Spec: v1.PodSpec{
+			Volumes: []v1.Volume{
+				{Name: "logs", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}},
+			},
+			Containers: []v1.Container{{
+				Name:         "app",
+				VolumeMounts: []v1.VolumeMount{{Name: "logs", MountPath: "/home/admin/logs"}},
+			}},
+		},
+	}}
+	app := &cri.Container{
+		Pod:              pod,
+		K8sContainerName: "app",
+		Mounts: []*cri.MountPoint{
+			{Source: emptyDir, Destination: "/home/admin/logs", RW: true},
+			{Source: rootfs, Destination: "/data", RW: true},
+		},
+	}
+	pod.All = []*cri.Container{app}
+	pod.Biz = []*cri.Container{app}
+
+	old := ioc.Crii
+	ioc.Crii = &fakePodCri{pod: pod}
+	defer func() { ioc.Crii = old }()
+
+	m, err := NewPodLogsFileMatcher(&collecttask.CollectTarget{
+		Type: collecttask.TargetPod,
+		Meta: map[string]string{"namespace": "default", "pod": "foo-0"},
+	}, "", 0)
+	require.NoError(t, err)
+
+	paths, _, err := m.Find()
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(rootfs, "a.log")}, GetPaths(paths))
+}
@@ -0,0 +1,62 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package filematch
+
+import (
+	"errors"
+	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig/executor/logstream"
+	"github.com/traas-stack/holoinsight-agent/pkg/collecttask"
+	"github.com/traas-stack/holoinsight-agent/pkg/cri"
+	"github.com/traas-stack/holoinsight-agent/pkg/cri/criutils"
+	"github.com/traas-stack/holoinsight-agent/pkg/ioc"
+)
+
+type (
+	// PodStdoutFileMatcher matches the stdout/stderr log file of a container of a pod.
+	// The log file may be a docker json-file or a cri log file (containerd/cri-o), its format is detected line by line.
+	PodStdoutFileMatcher struct {
+		Target *collecttask.CollectTarget
+		// K8s container name, empty means the main biz container
+		Container string
+	}
+)
+
+func NewPodStdoutFileMatcher(target *collecttask.CollectTarget, container string) *PodStdoutFileMatcher {
+	return &PodStdoutFileMatcher{
+		Target:    target,
+		Container: container,
+	}
+}
+
+func (m *PodStdoutFileMatcher) Find() ([]FatPath, int, error) {
+	var c *cri.Container
+	var err error
+	if m.Container == "" {
+		c, err = criutils.GetMainBizContainerE(ioc.Crii, m.Target.GetNamespace(), m.Target.GetPodName())
+	} else {
+		c, err = criutils.GetContainerE(ioc.Crii, m.Target.GetNamespace(), m.Target.GetPodName(), m.Container)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if c.LogPath == "" {
+		return nil, 0, errors.New("empty logPath")
+	}
+	return []FatPath{{
+		Path: c.LogPath,
+		Tags: map[string]string{
+			"namespace": c.Pod.Namespace,
+			"pod":       c.Pod.Name,
+			"container": c.K8sContainerName,
+		},
+		Attrs: map[string]string{
+			logstream.AttrContainerLogFormat: logstream.ContainerLogFormatAuto,
+		},
+	}}, 0, nil
+}
+
+func (m *PodStdoutFileMatcher) IsDynamicMultiFiles() bool {
+	return false
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package iplookup
+
+import (
+	"github.com/golang/groupcache/lru"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultCacheSize = 4096
+)
+
+type (
+	// GeoInfo is the geo info of an ip, fields are empty if not found
+	GeoInfo struct {
+		// Country is ISO 3166-1 country code, e.g. 'CN'
+		Country string
+		// CountryName is the English name of country
+		CountryName string
+		// City is the English name of city
+		City   string
+		ASN    uint64
+		ASNOrg string
+	}
+	// Lookup looks up ips against MaxMind DB files and a CIDR table. It is safe for concurrent use.
+	Lookup struct {
+		readers []*MmdbReader
+		zones   []*zone
+
+		mutex sync.Mutex
+		// cache caches GeoInfo by ip, because decoding mmdb records is expensive
+		cache *lru.Cache
+	}
+	zone struct {
+		ipNet *net.IPNet
+		label string
+	}
+)
+
+// NewLookup creates a Lookup. Records of readers are merged, so a City database and an ASN database can be used together.
+// zones maps CIDRs (or plain ips) to zone labels, the longest prefix wins.
+func NewLookup(readers []*MmdbReader, zones map[string]string) (*Lookup, error) {
+	l := &Lookup{
+		readers: readers,
+		cache:   lru.New(defaultCacheSize),
+	}
+	for cidr, label := range zones {
+		ipNet, err := ParseCidr(cidr)
+		if err != nil {
+			return nil, err
+		}
+		l.zones = append(l.zones, &zone{ipNet: ipNet, label: label})
+	}
+	sort.Slice(l.zones, func(i, j int) bool {
+		oi, _ := l.zones[i].ipNet.Mask.Size()
+		oj, _ := l.zones[j].ipNet.Mask.Size()
+		if oi != oj {
+			return oi > oj
+		}
+		return l.zones[i].ipNet.String() < l.zones[j].ipNet.String()
+	})
+	return l, nil
+}
+
+// ParseCidr parses a CIDR, a plain ip is treated as a single ip network
+func ParseCidr(s string) (*net.IPNet, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, "/") {
+		if ip := net.ParseIP(s); ip != nil {
+			if ip4 := ip.To4(); ip4 != nil {
+				return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+			}
+			return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+		}
+	}
+	_, ipNet, err := net.ParseCIDR(s)
+	return ipNet, err
+}
+
+// Zone returns the zone label of ip, it returns "" if not found
+func (l *Lookup) Zone(ip net.IP) string {
+	for _, z := range l.zones {
+		if z.ipNet.Contains(ip) {
+			return z.label
+		}
+	}
+	return ""
+}
+
+// Geo returns the geo info of ip. The returned GeoInfo is shared by callers, it must not be modified.
+func (l *Lookup) Geo(ip net.IP) *GeoInfo {
+	key := string(ip.To16())
+	l.mutex.Lock()
+	v, ok := l.cache.Get(key)
+	l.mutex.Unlock()
+	if ok {
+		return v.(*GeoInfo)
+	}
+
+	info := &GeoInfo{}
+	for _, r := range l.readers {
+		record, err := r.Lookup(ip)
+		if err != nil || record == nil {
+			continue
+		}
+		m, _ := record.(map[string]interface{})
+		if s := getString(m, "country", "iso_code"); s != "" && info.Country == "" {
+			info.Country = s
+		}
+		if s := getString(m, "country", "names", "en"); s != "" && info.CountryName == "" {
+			info.CountryName = s
+		}
+		if s := getString(m, "city", "names", "en"); s != "" && info.City == "" {
+			info.City = s
+		}
+		if n := toUint64(m["autonomous_system_number"]); n != 0 && info.ASN == 0 {
+			info.ASN = n
+		}
+		if s := getString(m, "autonomous_system_organization"); s != "" && info.ASNOrg == "" {
+			info.ASNOrg = s
+		}
+	}
+
+	l.mutex.Lock()
+	l.cache.Add(key, info)
+	l.mutex.Unlock()
+	return info
+}
+
+// getString gets a string from nested maps by path
+func getString(m map[string]interface{}, path ...string) string {
+	for i, key := range path {
+		v := m[key]
+		if i == len(path)-1 {
+			s, _ := v.(string)
+			return s
+		}
+		if m, _ = v.(map[string]interface{}); m == nil {
+			return ""
+		}
+	}
+	return ""
+}
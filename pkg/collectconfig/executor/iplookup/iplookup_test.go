@@ -0,0 +1,158 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package iplookup
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"sort"
+	"testing"
+)
+
+// encodeMmdbValue encodes a value into MaxMind DB data section format, only types used by tests are supported
+func encodeMmdbValue(buf *bytes.Buffer, v interface{}) {
+	writeCtrl := func(typ int, size int) {
+		sizeBits, extra := size, -1
+		if size >= 29 {
+			sizeBits, extra = 29, size-29
+		}
+		if typ <= 7 {
+			buf.WriteByte(byte(typ<<5 | sizeBits))
+		} else {
+			buf.WriteByte(byte(sizeBits))
+			buf.WriteByte(byte(typ - 7))
+		}
+		if extra >= 0 {
+			buf.WriteByte(byte(extra))
+		}
+	}
+	switch x := v.(type) {
+	case string:
+		writeCtrl(mmdbTypeString, len(x))
+		buf.WriteString(x)
+	case uint32:
+		writeCtrl(mmdbTypeUint32, 4)
+		buf.Write([]byte{byte(x >> 24), byte(x >> 16), byte(x >> 8), byte(x)})
+	case uint16:
+		writeCtrl(mmdbTypeUint16, 2)
+		buf.Write([]byte{byte(x >> 8), byte(x)})
+	case map[string]interface{}:
+		writeCtrl(mmdbTypeMap, len(x))
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			encodeMmdbValue(buf, k)
+			encodeMmdbValue(buf, x[k])
+		}
+	}
+}
+
+// buildMmdb builds an IPv4 database with 24 bits records, which maps network to record
+func buildMmdb(network *net.IPNet, record map[string]interface{}) []byte {
+	ones, _ := network.Mask.Size()
+	nodeCount := ones
+	dataPointer := nodeCount + mmdbDataSectionSeparatorSize
+
+	var buf bytes.Buffer
+	writeRecord := func(v int) {
+		buf.Write([]byte{byte(v >> 16), byte(v >> 8), byte(v)})
+	}
+	ip := network.IP.To4()
+	for i := 0; i < ones; i++ {
+		next := i + 1
+		if i == ones-1 {
+			next = dataPointer
+		}
+		if (ip[i>>3]>>(7-uint(i&7)))&1 == 0 {
+			writeRecord(next)
+			writeRecord(nodeCount)
+		} else {
+			writeRecord(nodeCount)
+			writeRecord(next)
+		}
+	}
+	buf.Write(make([]byte, mmdbDataSectionSeparatorSize))
+	encodeMmdbValue(&buf, record)
+	buf.Write(mmdbMetadataMarker)
+	encodeMmdbValue(&buf, map[string]interface{}{
+		"node_count":    uint32(nodeCount),
+		"record_size":   uint16(24),
+		"ip_version":    uint16(4),
+		"database_type": "Test",
+	})
+	return buf.Bytes()
+}
+
+func TestLookup(t *testing.T) {
+	_, network, _ := net.ParseCIDR("1.2.3.0/24")
+	city, err := NewMmdbReader(buildMmdb(network, map[string]interface{}{
+		"country": map[string]interface{}{
+			"iso_code": "CN",
+			"names":    map[string]interface{}{"en": "China"},
+		},
+		"city": map[string]interface{}{
+			"names": map[string]interface{}{"en": "Hangzhou"},
+		},
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "Test", city.Metadata["database_type"])
+
+	_, network, _ = net.ParseCIDR("1.2.0.0/16")
+	asn, err := NewMmdbReader(buildMmdb(network, map[string]interface{}{
+		"autonomous_system_number":       uint32(37963),
+		"autonomous_system_organization": "Hangzhou Alibaba Advertising Co.,Ltd.",
+	}))
+	require.NoError(t, err)
+
+	l, err := NewLookup([]*MmdbReader{city, asn}, map[string]string{
+		"10.0.0.0/8":  "office",
+		"10.1.0.0/16": "idc",
+		"192.168.0.1": "gateway",
+	})
+	require.NoError(t, err)
+
+	info := l.Geo(net.ParseIP("1.2.3.4"))
+	assert.Equal(t, &GeoInfo{
+		Country:     "CN",
+		CountryName: "China",
+		City:        "Hangzhou",
+		ASN:         37963,
+		ASNOrg:      "Hangzhou Alibaba Advertising Co.,Ltd.",
+	}, info)
+	// cached
+	assert.Same(t, info, l.Geo(net.ParseIP("1.2.3.4")))
+
+	info = l.Geo(net.ParseIP("1.2.4.4"))
+	assert.Equal(t, "", info.Country)
+	assert.Equal(t, uint64(37963), info.ASN)
+
+	assert.Equal(t, &GeoInfo{}, l.Geo(net.ParseIP("8.8.8.8")))
+	assert.Equal(t, &GeoInfo{}, l.Geo(net.ParseIP("::1")))
+
+	assert.Equal(t, "office", l.Zone(net.ParseIP("10.2.0.1")))
+	assert.Equal(t, "idc", l.Zone(net.ParseIP("10.1.0.1")))
+	assert.Equal(t, "gateway", l.Zone(net.ParseIP("192.168.0.1")))
+	assert.Equal(t, "", l.Zone(net.ParseIP("192.168.0.2")))
+}
+
+func TestNewMmdbReader_invalid(t *testing.T) {
+	_, err := NewMmdbReader([]byte("not a mmdb"))
+	assert.Error(t, err)
+
+	var buf bytes.Buffer
+	buf.Write(mmdbMetadataMarker)
+	encodeMmdbValue(&buf, map[string]interface{}{
+		"node_count":  uint32(1000),
+		"record_size": uint16(24),
+		"ip_version":  uint16(4),
+	})
+	_, err = NewMmdbReader(buf.Bytes())
+	assert.Error(t, err)
+}
@@ -0,0 +1,342 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package iplookup
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"os"
+)
+
+// Data types of MaxMind DB data section: https://maxmind.github.io/MaxMind-DB/
+const (
+	mmdbTypeExtended = iota
+	mmdbTypePointer
+	mmdbTypeString
+	mmdbTypeDouble
+	mmdbTypeBytes
+	mmdbTypeUint16
+	mmdbTypeUint32
+	mmdbTypeMap
+	mmdbTypeInt32
+	mmdbTypeUint64
+	mmdbTypeUint128
+	mmdbTypeArray
+	mmdbTypeContainer
+	mmdbTypeEndMarker
+	mmdbTypeBool
+	mmdbTypeFloat
+)
+
+const (
+	// mmdbDataSectionSeparatorSize is the size of zeros between search tree and data section
+	mmdbDataSectionSeparatorSize = 16
+	mmdbMaxDecodeDepth           = 32
+)
+
+var (
+	mmdbMetadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+	errMmdbInvalid     = errors.New("invalid mmdb")
+)
+
+type (
+	// MmdbReader reads MaxMind DB format files, e.g. GeoLite2-City.mmdb and GeoLite2-ASN.mmdb.
+	// The whole file is loaded into memory. It is safe for concurrent use.
+	MmdbReader struct {
+		// Metadata is the decoded metadata map of the database
+		Metadata   map[string]interface{}
+		tree       []byte
+		data       mmdbDecoder
+		nodeCount  uint
+		recordSize uint
+		ipVersion  uint
+		// ipv4Start is the node after following 96 zero bits in an IPv6 tree, where IPv4 addresses start
+		ipv4Start uint
+	}
+	mmdbDecoder struct {
+		buf []byte
+	}
+)
+
+// OpenMmdb loads a MaxMind DB file
+func OpenMmdb(path string) (*MmdbReader, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewMmdbReader(b)
+}
+
+// NewMmdbReader creates a reader from content of a MaxMind DB file
+func NewMmdbReader(b []byte) (*MmdbReader, error) {
+	i := bytes.LastIndex(b, mmdbMetadataMarker)
+	if i < 0 {
+		return nil, errors.New("invalid mmdb: metadata marker not found")
+	}
+	md, _, err := (&mmdbDecoder{buf: b[i+len(mmdbMetadataMarker):]}).decode(0, 0)
+	if err != nil {
+		return nil, err
+	}
+	metadata, ok := md.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("invalid mmdb: metadata is not a map")
+	}
+
+	r := &MmdbReader{
+		Metadata:   metadata,
+		nodeCount:  uint(toUint64(metadata["node_count"])),
+		recordSize: uint(toUint64(metadata["record_size"])),
+		ipVersion:  uint(toUint64(metadata["ip_version"])),
+	}
+	if r.recordSize != 24 && r.recordSize != 28 && r.recordSize != 32 {
+		return nil, fmt.Errorf("invalid mmdb: unsupported record size %d", r.recordSize)
+	}
+	if r.ipVersion != 4 && r.ipVersion != 6 {
+		return nil, fmt.Errorf("invalid mmdb: unsupported ip version %d", r.ipVersion)
+	}
+	treeSize := r.nodeCount * r.recordSize / 4
+	if treeSize+mmdbDataSectionSeparatorSize > uint(i) {
+		return nil, errors.New("invalid mmdb: search tree is out of range")
+	}
+	r.tree = b[:treeSize]
+	r.data = mmdbDecoder{buf: b[treeSize+mmdbDataSectionSeparatorSize : i]}
+
+	if r.ipVersion == 6 {
+		node := uint(0)
+		for j := 0; j < 96 && node < r.nodeCount; j++ {
+			node = r.readNode(node, 0)
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+// Lookup returns the record of ip, it returns nil if ip is not found
+func (r *MmdbReader) Lookup(ip net.IP) (interface{}, error) {
+	offset, err := r.lookupOffset(ip)
+	if err != nil || offset < 0 {
+		return nil, err
+	}
+	v, _, err := r.data.decode(uint(offset), 0)
+	return v, err
+}
+
+// lookupOffset returns offset of the record of ip in data section, it returns -1 if ip is not found
+func (r *MmdbReader) lookupOffset(ip net.IP) (int, error) {
+	node := uint(0)
+	bitCount := 128
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		bitCount = 32
+		if r.ipVersion == 6 {
+			node = r.ipv4Start
+		}
+	} else if r.ipVersion == 4 || len(ip) != net.IPv6len {
+		return -1, nil
+	}
+
+	for i := 0; i < bitCount && node < r.nodeCount; i++ {
+		bit := uint(ip[i>>3]>>(7-uint(i&7))) & 1
+		node = r.readNode(node, bit)
+	}
+	if node == r.nodeCount {
+		return -1, nil
+	}
+	if node < r.nodeCount {
+		return -1, errMmdbInvalid
+	}
+	offset := node - r.nodeCount - mmdbDataSectionSeparatorSize
+	if offset >= uint(len(r.data.buf)) {
+		return -1, errMmdbInvalid
+	}
+	return int(offset), nil
+}
+
+func (r *MmdbReader) readNode(node, bit uint) uint {
+	b := r.tree
+	switch r.recordSize {
+	case 24:
+		off := node*6 + bit*3
+		return uint(b[off])<<16 | uint(b[off+1])<<8 | uint(b[off+2])
+	case 28:
+		off := node * 7
+		if bit == 0 {
+			return uint(b[off+3]&0xF0)<<20 | uint(b[off])<<16 | uint(b[off+1])<<8 | uint(b[off+2])
+		}
+		return uint(b[off+3]&0x0F)<<24 | uint(b[off+4])<<16 | uint(b[off+5])<<8 | uint(b[off+6])
+	default:
+		off := node*8 + bit*4
+		return uint(binary.BigEndian.Uint32(b[off:]))
+	}
+}
+
+// decode decodes the value at offset, and returns the value and offset of the next value
+func (d *mmdbDecoder) decode(offset uint, depth int) (interface{}, uint, error) {
+	if depth > mmdbMaxDecodeDepth {
+		return nil, 0, errors.New("invalid mmdb: data is nested too deep")
+	}
+	if offset >= uint(len(d.buf)) {
+		return nil, 0, errMmdbInvalid
+	}
+	ctrl := d.buf[offset]
+	offset++
+	typ := uint(ctrl >> 5)
+
+	if typ == mmdbTypePointer {
+		pointer, next, err := d.decodePointer(ctrl, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		v, _, err := d.decode(pointer, depth+1)
+		return v, next, err
+	}
+
+	if typ == mmdbTypeExtended {
+		if offset >= uint(len(d.buf)) {
+			return nil, 0, errMmdbInvalid
+		}
+		typ = 7 + uint(d.buf[offset])
+		offset++
+	}
+
+	size, offset, err := d.decodeSize(ctrl, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch typ {
+	case mmdbTypeMap:
+		m := make(map[string]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			var k, v interface{}
+			if k, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, 0, errors.New("invalid mmdb: map key is not a string")
+			}
+			if v, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			m[key] = v
+		}
+		return m, offset, nil
+	case mmdbTypeArray:
+		a := make([]interface{}, 0, size)
+		for i := uint(0); i < size; i++ {
+			var v interface{}
+			if v, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			a = append(a, v)
+		}
+		return a, offset, nil
+	case mmdbTypeBool:
+		return size != 0, offset, nil
+	}
+
+	end := offset + size
+	if end > uint(len(d.buf)) {
+		return nil, 0, errMmdbInvalid
+	}
+	b := d.buf[offset:end]
+	switch typ {
+	case mmdbTypeString:
+		return string(b), end, nil
+	case mmdbTypeBytes:
+		return append([]byte(nil), b...), end, nil
+	case mmdbTypeDouble:
+		if size != 8 {
+			return nil, 0, errMmdbInvalid
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), end, nil
+	case mmdbTypeFloat:
+		if size != 4 {
+			return nil, 0, errMmdbInvalid
+		}
+		return math.Float32frombits(binary.BigEndian.Uint32(b)), end, nil
+	case mmdbTypeUint16, mmdbTypeUint32, mmdbTypeUint64:
+		if size > 8 {
+			return nil, 0, errMmdbInvalid
+		}
+		return decodeUint(b), end, nil
+	case mmdbTypeInt32:
+		if size > 4 {
+			return nil, 0, errMmdbInvalid
+		}
+		return int32(decodeUint(b)), end, nil
+	case mmdbTypeUint128:
+		if size > 16 {
+			return nil, 0, errMmdbInvalid
+		}
+		return new(big.Int).SetBytes(b), end, nil
+	default:
+		return nil, 0, fmt.Errorf("invalid mmdb: unsupported data type %d", typ)
+	}
+}
+
+func (d *mmdbDecoder) decodeSize(ctrl byte, offset uint) (uint, uint, error) {
+	size := uint(ctrl & 0x1f)
+	if size < 29 {
+		return size, offset, nil
+	}
+	n := size - 28
+	if offset+n > uint(len(d.buf)) {
+		return 0, 0, errMmdbInvalid
+	}
+	v := uint(decodeUint(d.buf[offset : offset+n]))
+	switch size {
+	case 29:
+		size = 29 + v
+	case 30:
+		size = 285 + v
+	default:
+		size = 65821 + v
+	}
+	return size, offset + n, nil
+}
+
+func (d *mmdbDecoder) decodePointer(ctrl byte, offset uint) (uint, uint, error) {
+	n := uint((ctrl>>3)&0x3) + 1
+	if offset+n > uint(len(d.buf)) {
+		return 0, 0, errMmdbInvalid
+	}
+	v := uint(decodeUint(d.buf[offset : offset+n]))
+	vvv := uint(ctrl & 0x7)
+	switch n {
+	case 1:
+		v = vvv<<8 | v
+	case 2:
+		v = (vvv<<16 | v) + 2048
+	case 3:
+		v = (vvv<<24 | v) + 526336
+	}
+	return v, offset + n, nil
+}
+
+func decodeUint(b []byte) uint64 {
+	v := uint64(0)
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+func toUint64(v interface{}) uint64 {
+	switch x := v.(type) {
+	case uint64:
+		return x
+	case int32:
+		return uint64(x)
+	default:
+		return 0
+	}
+}
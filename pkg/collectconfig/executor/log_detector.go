@@ -95,6 +95,20 @@ func NewLogDetector(key string, from *collectconfig.From, target *collecttask.Co
 			if target.IsTypePod() {
 				matchers = append(matchers, filematch.NewContainerFileMatcher(target))
 			}
+		case filematch.TypeStdout:
+			if target.IsTypePod() {
+				matchers = append(matchers, filematch.NewPodStdoutFileMatcher(target, path.Container))
+			}
+		case filematch.TypePodLogs:
+			if !target.IsTypePod() {
+				continue
+			}
+			m, err := filematch.NewPodLogsFileMatcher(target, path.Pattern, path.MaxMatched)
+			if err != nil {
+				logger.Errorz("NewPodLogsFileMatcher error", zap.Error(err))
+				continue
+			}
+			matchers = append(matchers, m)
 		}
 	}
 	return &LogPathDetector{
@@ -0,0 +1,203 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package logstream
+
+import (
+	"encoding/json"
+	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig/executor/utils"
+	"github.com/traas-stack/holoinsight-agent/pkg/cri/dockerutils"
+	"github.com/traas-stack/holoinsight-agent/pkg/util"
+	"strings"
+)
+
+const (
+	// AttrContainerLogFormat is the FatPath attr key of container stdout log format
+	AttrContainerLogFormat = "containerLogFormat"
+
+	// ContainerLogFormatAuto detects format of every line, because log files of a container may be written by different runtimes.
+	ContainerLogFormatAuto = "auto"
+	// ContainerLogFormatDocker is the format of docker json-file log driver: https://docs.docker.com/config/containers/logging/json-file/
+	ContainerLogFormatDocker = "docker"
+	// ContainerLogFormatCri is the format written by kubelet/containerd/cri-o: "<RFC3339Nano time> <stream> <P|F> <log>"
+	// https://github.com/kubernetes/design-proposals-archive/blob/main/node/kubelet-cri-logging.md
+	ContainerLogFormatCri = "cri"
+
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+
+	criTagPartial = "P"
+)
+
+type (
+	// containerLogDecoder decodes container stdout log lines, and reassembles partial lines.
+	// Runtimes split long log lines into several partial lines (docker splits lines at 16KB, and cri splits lines at 16KB by default too).
+	// Partial lines of stdout and stderr are interleaved, so they are buffered per stream.
+	containerLogDecoder struct {
+		format      string
+		maxLineSize int
+		// stream -> pending partial contents
+		partials map[string][]string
+	}
+	containerLogDecoderStateObj struct {
+		Partials map[string][]string
+	}
+	containerLogEntry struct {
+		stream  string
+		log     string
+		partial bool
+	}
+)
+
+// IsValidContainerLogFormat checks if format is a supported container log format
+func IsValidContainerLogFormat(format string) bool {
+	switch format {
+	case ContainerLogFormatAuto, ContainerLogFormatDocker, ContainerLogFormatCri:
+		return true
+	default:
+		return false
+	}
+}
+
+func newContainerLogDecoder(format string, maxLineSize int) *containerLogDecoder {
+	return &containerLogDecoder{
+		format:      format,
+		maxLineSize: maxLineSize,
+		partials:    make(map[string][]string),
+	}
+}
+
+// decode decodes a raw line. It returns false if the line is a partial line and is buffered.
+// Lines can not be decoded are returned as is with an empty stream.
+func (d *containerLogDecoder) decode(line string) (string, string, bool) {
+	e, ok := d.parse(line)
+	if !ok {
+		return "", line, true
+	}
+
+	pending := d.partials[e.stream]
+	if e.partial {
+		pending = append(pending, e.log)
+		if d.pendingSize(pending) < d.maxLineSize {
+			d.partials[e.stream] = pending
+			return "", "", false
+		}
+		// Force to emit too long lines
+		delete(d.partials, e.stream)
+		return e.stream, strings.Join(pending, ""), true
+	}
+
+	if len(pending) == 0 {
+		return e.stream, e.log, true
+	}
+	delete(d.partials, e.stream)
+	pending = append(pending, e.log)
+	return e.stream, strings.Join(pending, ""), true
+}
+
+func (d *containerLogDecoder) pendingSize(pending []string) int {
+	size := 0
+	for _, s := range pending {
+		size += len(s)
+	}
+	return size
+}
+
+func (d *containerLogDecoder) parse(line string) (containerLogEntry, bool) {
+	switch d.format {
+	case ContainerLogFormatDocker:
+		return parseDockerJsonLog(line)
+	case ContainerLogFormatCri:
+		return parseCriLog(line)
+	default:
+		if strings.HasPrefix(line, "{") {
+			if e, ok := parseDockerJsonLog(line); ok {
+				return e, true
+			}
+		}
+		return parseCriLog(line)
+	}
+}
+
+func (d *containerLogDecoder) clear() {
+	d.partials = make(map[string][]string)
+}
+
+func (d *containerLogDecoder) saveState() *containerLogDecoderStateObj {
+	return &containerLogDecoderStateObj{Partials: d.partials}
+}
+
+func (d *containerLogDecoder) loadState(state *containerLogDecoderStateObj) {
+	d.clear()
+	if state == nil {
+		return
+	}
+	for stream, pending := range state.Partials {
+		d.partials[stream] = pending
+	}
+}
+
+// parseDockerJsonLog parses a docker json log line. Docker splits lines longer than 16KB, all parts except the last one don't end with '\n'.
+func parseDockerJsonLog(line string) (containerLogEntry, bool) {
+	dl := dockerutils.DockerLog{}
+	if err := json.Unmarshal(util.ZeroCopyStringToBytes(line), &dl); err != nil {
+		return containerLogEntry{}, false
+	}
+	return containerLogEntry{
+		stream:  dl.Stream,
+		log:     utils.TrimCRLFString(dl.Log),
+		partial: !strings.HasSuffix(dl.Log, "\n"),
+	}, true
+}
+
+// parseCriLog parses a cri log line: "2016-10-06T00:17:09.669794202Z stdout F log content".
+// The tag field is ':' separated, its first item is 'P' (partial) or 'F' (full).
+func parseCriLog(line string) (containerLogEntry, bool) {
+	timeEnd := strings.IndexByte(line, ' ')
+	if timeEnd <= 0 {
+		return containerLogEntry{}, false
+	}
+	rest := line[timeEnd+1:]
+
+	streamEnd := strings.IndexByte(rest, ' ')
+	if streamEnd < 0 {
+		return containerLogEntry{}, false
+	}
+	stream := rest[:streamEnd]
+	if stream != StreamStdout && stream != StreamStderr {
+		return containerLogEntry{}, false
+	}
+	rest = rest[streamEnd+1:]
+
+	tag := rest
+	log := ""
+	if tagEnd := strings.IndexByte(rest, ' '); tagEnd >= 0 {
+		tag = rest[:tagEnd]
+		log = rest[tagEnd+1:]
+	}
+	if colon := strings.IndexByte(tag, ':'); colon >= 0 {
+		tag = tag[:colon]
+	}
+
+	return containerLogEntry{
+		stream:  stream,
+		log:     log,
+		partial: tag == criTagPartial,
+	}, true
+}
+
+// DecodeContainerLogLines decodes container stdout log lines without keeping state, partial lines at the end are dropped.
+// It returns decoded lines and streams of them.
+func DecodeContainerLogLines(format string, lines []string) ([]string, []string) {
+	d := newContainerLogDecoder(format, DefaultFileConfig.MaxLineSize)
+	decoded := make([]string, 0, len(lines))
+	streams := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if stream, log, ok := d.decode(line); ok {
+			decoded = append(decoded, log)
+			streams = append(streams, stream)
+		}
+	}
+	return decoded, streams
+}
@@ -0,0 +1,95 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package logstream
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestContainerLogDecoder_cri(t *testing.T) {
+	d := newContainerLogDecoder(ContainerLogFormatCri, 1024)
+
+	stream, log, ok := d.decode("2016-10-06T00:17:09.669794202Z stdout F hello world")
+	assert.True(t, ok)
+	assert.Equal(t, StreamStdout, stream)
+	assert.Equal(t, "hello world", log)
+
+	// partial lines of stdout and stderr are interleaved
+	_, _, ok = d.decode("2016-10-06T00:17:09.669794202Z stdout P foo ")
+	assert.False(t, ok)
+	_, _, ok = d.decode("2016-10-06T00:17:09.669794203Z stderr P err1 ")
+	assert.False(t, ok)
+	_, _, ok = d.decode("2016-10-06T00:17:09.669794204Z stdout P bar ")
+	assert.False(t, ok)
+
+	stream, log, ok = d.decode("2016-10-06T00:17:09.669794205Z stderr F err2")
+	assert.True(t, ok)
+	assert.Equal(t, StreamStderr, stream)
+	assert.Equal(t, "err1 err2", log)
+
+	stream, log, ok = d.decode("2016-10-06T00:17:09.669794206Z stdout F baz")
+	assert.True(t, ok)
+	assert.Equal(t, StreamStdout, stream)
+	assert.Equal(t, "foo bar baz", log)
+
+	// empty log
+	stream, log, ok = d.decode("2016-10-06T00:17:09.669794207Z stdout F")
+	assert.True(t, ok)
+	assert.Equal(t, StreamStdout, stream)
+	assert.Equal(t, "", log)
+
+	// not a cri line
+	stream, log, ok = d.decode("hello world")
+	assert.True(t, ok)
+	assert.Equal(t, "", stream)
+	assert.Equal(t, "hello world", log)
+}
+
+func TestContainerLogDecoder_docker(t *testing.T) {
+	d := newContainerLogDecoder(ContainerLogFormatDocker, 1024)
+
+	_, _, ok := d.decode(`{"log":"foo ","stream":"stdout","time":"2023-01-01T00:00:00.000000001Z"}`)
+	assert.False(t, ok)
+
+	stream, log, ok := d.decode(`{"log":"bar\r\n","stream":"stdout","time":"2023-01-01T00:00:00.000000002Z"}`)
+	assert.True(t, ok)
+	assert.Equal(t, StreamStdout, stream)
+	assert.Equal(t, "foo bar", log)
+}
+
+func TestContainerLogDecoder_auto(t *testing.T) {
+	lines, streams := DecodeContainerLogLines(ContainerLogFormatAuto, []string{
+		`{"log":"a\n","stream":"stderr","time":"2023-01-01T00:00:00.000000001Z"}`,
+		"2023-01-01T00:00:00.000000002Z stdout F b",
+		"{not json",
+	})
+	assert.Equal(t, []string{"a", "b", "{not json"}, lines)
+	assert.Equal(t, []string{StreamStderr, StreamStdout, ""}, streams)
+}
+
+func TestContainerLogDecoder_maxLineSize(t *testing.T) {
+	d := newContainerLogDecoder(ContainerLogFormatCri, 8)
+
+	_, _, ok := d.decode("2016-10-06T00:17:09.669794202Z stdout P 1234")
+	assert.False(t, ok)
+
+	stream, log, ok := d.decode("2016-10-06T00:17:09.669794202Z stdout P 5678")
+	assert.True(t, ok)
+	assert.Equal(t, StreamStdout, stream)
+	assert.Equal(t, "12345678", log)
+}
+
+func TestContainerLogDecoder_state(t *testing.T) {
+	d := newContainerLogDecoder(ContainerLogFormatCri, 1024)
+	_, _, ok := d.decode("2016-10-06T00:17:09.669794202Z stdout P foo")
+	assert.False(t, ok)
+
+	d2 := newContainerLogDecoder(ContainerLogFormatCri, 1024)
+	d2.loadState(d.saveState())
+	_, log, ok := d2.decode("2016-10-06T00:17:09.669794202Z stdout F bar")
+	assert.True(t, ok)
+	assert.Equal(t, "foobar", log)
+}
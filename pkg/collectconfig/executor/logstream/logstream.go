@@ -68,7 +68,10 @@ type (
 		// error when read (such as 'no such file or directory')
 		error error
 
-		Lines     []string `json:"-"`
+		Lines []string `json:"-"`
+		// Streams are stream names ('stdout'/'stderr') of Lines, it is only set when reading container stdout logs.
+		// Empty stream means the line can not be decoded.
+		Streams   []string `json:"-"`
 		LogGroups []*LogGroup
 
 		// Whether the next data can be read immediately
@@ -10,7 +10,6 @@ import (
 	"errors"
 	"fmt"
 	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig/executor/utils"
-	"github.com/traas-stack/holoinsight-agent/pkg/cri/dockerutils"
 	"github.com/traas-stack/holoinsight-agent/pkg/logger"
 	"go.uber.org/zap"
 	"io"
@@ -32,7 +31,10 @@ type (
 		MaxLineSize    int
 		MaxIOReadBytes int64
 		// https://docs.docker.com/config/containers/logging/json-file/
+		// Deprecated: use ContainerLogFormat = ContainerLogFormatDocker instead
 		IsDockerJsonLog bool
+		// ContainerLogFormat is the format of container stdout log file, empty means this file is not a container stdout log file
+		ContainerLogFormat string
 	}
 	fileSubLogStream struct {
 		g      *GLogStream
@@ -47,6 +49,8 @@ type (
 		ignoreFirstLine bool
 		inode           uint64
 		fileChanged     bool
+		// decoder is not nil when reading a container stdout log file
+		decoder *containerLogDecoder
 	}
 	fileStateObj struct {
 		Cursor int64
@@ -57,6 +61,7 @@ type (
 		IgnoreFirstLine bool
 		Inode           uint64
 		FileChanged     bool
+		ContainerLog    *containerLogDecoderStateObj
 	}
 )
 
@@ -80,6 +85,9 @@ func NewFileLogStream(key string, config FileConfig) *GLogStream {
 	if config.MaxLineSize < DefaultFileConfig.MaxLineSize {
 		config.MaxLineSize = DefaultFileConfig.MaxLineSize
 	}
+	if config.IsDockerJsonLog && config.ContainerLogFormat == "" {
+		config.ContainerLogFormat = ContainerLogFormatDocker
+	}
 
 	g := &GLogStream{Key: key}
 	sub := &fileSubLogStream{
@@ -89,6 +97,9 @@ func NewFileLogStream(key string, config FileConfig) *GLogStream {
 			lineBuffer: utils.NewLineBuffer(config.MaxLineSize),
 		},
 	}
+	if config.ContainerLogFormat != "" {
+		sub.decoder = newContainerLogDecoder(config.ContainerLogFormat, config.MaxLineSize)
+	}
 	g.sub = sub
 	return g
 }
@@ -142,6 +153,9 @@ func (f *fileSubLogStream) LoadState(i interface{}) error {
 	f.lineBuffer.LoadState(state.LineBuffer)
 	f.ignoreFirstLine = state.IgnoreFirstLine
 	f.fileChanged = state.FileChanged
+	if f.decoder != nil {
+		f.decoder.loadState(state.ContainerLog)
+	}
 
 	for key, resp := range state.Cache {
 		f.g.Cache.Store(key, &cachedRead{
@@ -165,6 +179,11 @@ func (f *fileSubLogStream) SaveState() (interface{}, error) {
 		return true
 	})
 
+	var containerLog *containerLogDecoderStateObj
+	if f.decoder != nil {
+		containerLog = f.decoder.saveState()
+	}
+
 	return &fileStateObj{
 		Offset:          f.offset,
 		Cache:           cache,
@@ -173,6 +192,7 @@ func (f *fileSubLogStream) SaveState() (interface{}, error) {
 		IgnoreFirstLine: f.ignoreFirstLine,
 		Inode:           f.inode,
 		FileChanged:     f.fileChanged,
+		ContainerLog:    containerLog,
 	}, nil
 }
 
@@ -248,21 +268,23 @@ func (f *fileSubLogStream) Read(resp *ReadResponse) error {
 		resp.HasMore = f.offset < fileLength
 
 		var lines []string
+		var streams []string
 		if f.consumeBytes(buf[:n], func(line string) {
 			if DiscardLineWithZeroBytes && strings.Count(line, "\u0000") >= DiscardZeroBytesThreshold {
 				resp.HasBroken = true
-			} else {
-				if f.config.IsDockerJsonLog {
-					if dl, err := dockerutils.DecodeJsonLog(line); err == nil {
-						line = dl.Log
-					}
+			} else if f.decoder != nil {
+				if stream, log, ok := f.decoder.decode(line); ok {
+					lines = append(lines, log)
+					streams = append(streams, stream)
 				}
+			} else {
 				lines = append(lines, line)
 			}
 		}) {
 			resp.HasBroken = true
 		}
 		resp.Lines = lines
+		resp.Streams = streams
 	}
 
 	resp.Range = fmt.Sprintf("%d:%d:%d", f.inode, beginOffset, f.offset)
@@ -325,6 +347,9 @@ func (f *fileSubLogStream) closeFile() {
 	f.fileChanged = false
 	f.lineBuffer.Clear()
 	f.ignoreFirstLine = false
+	if f.decoder != nil {
+		f.decoder.clear()
+	}
 }
 
 func (f *fileSubLogStream) consumeBytes(b []byte, fun func(string)) bool {
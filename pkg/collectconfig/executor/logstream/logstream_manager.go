@@ -264,14 +264,19 @@ func (m *Manager) acquire0(path string, attrs map[string]string, sc *SlsConfig)
 		ls = NewSlsLogStream(*sc)
 	} else {
 		isDockerJsonLog := false
-		if attrs != nil && "true" == attrs[dockerutils.AttrIsDockerJsonLog] {
-			isDockerJsonLog = true
+		containerLogFormat := ""
+		if attrs != nil {
+			isDockerJsonLog = "true" == attrs[dockerutils.AttrIsDockerJsonLog]
+			if format := attrs[AttrContainerLogFormat]; IsValidContainerLogFormat(format) {
+				containerLogFormat = format
+			}
 		}
 		ls = NewFileLogStream(lsKey, FileConfig{
-			Path:            path,
-			MaxLineSize:     DefaultFileConfig.MaxLineSize,
-			MaxIOReadBytes:  DefaultFileConfig.MaxIOReadBytes,
-			IsDockerJsonLog: isDockerJsonLog,
+			Path:               path,
+			MaxLineSize:        DefaultFileConfig.MaxLineSize,
+			MaxIOReadBytes:     DefaultFileConfig.MaxIOReadBytes,
+			IsDockerJsonLog:    isDockerJsonLog,
+			ContainerLogFormat: containerLogFormat,
 		})
 	}
 	ls.Start()
@@ -0,0 +1,252 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package masking
+
+import (
+	"errors"
+	"net"
+	"regexp"
+	"strings"
+)
+
+const (
+	DetectorEmail      = "email"
+	DetectorPhone      = "phone"
+	DetectorIdCard     = "idCard"
+	DetectorCreditCard = "creditCard"
+	DetectorIPv4       = "ipv4"
+	DetectorIPv6       = "ipv6"
+	DetectorJwt        = "jwt"
+	DetectorBearer     = "bearer"
+
+	defaultMaskChar = "*"
+	// maxMaskChars limits the count of mask chars written for a masked part, so long secrets don't produce long masks
+	maxMaskChars = 8
+)
+
+type (
+	// Rule describes what to mask and how to mask it
+	Rule struct {
+		Name       string
+		Expression string
+		// Group is the index of capture group to mask, 0 means the whole match
+		Group int
+		// KeepPrefix is the count of leading chars of masked part to keep
+		KeepPrefix int
+		// KeepSuffix is the count of trailing chars of masked part to keep, e.g. 4 means keep-last-4
+		KeepSuffix int
+		// MaskChar defaults to '*'
+		MaskChar string
+		// validate checks a candidate further, e.g. Luhn check of credit card numbers
+		validate func(string) bool
+	}
+	// Masker masks sensitive data of strings using rules in order. It is safe for concurrent use.
+	Masker struct {
+		rules []*compiledRule
+	}
+	compiledRule struct {
+		*Rule
+		regexp *regexp.Regexp
+	}
+)
+
+var (
+	builtinRules = map[string]*Rule{
+		DetectorEmail: {
+			Expression: `([A-Za-z0-9._%+\-]+)@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`,
+			Group:      1,
+			KeepPrefix: 1,
+		},
+		// Mainland China mobile numbers with optional country code
+		DetectorPhone: {
+			Expression: `(?:\+?86[\- ]?)?\b1[3-9]\d{9}\b`,
+			KeepSuffix: 4,
+		},
+		// Mainland China resident ID card numbers
+		DetectorIdCard: {
+			Expression: `\b\d{17}[\dXx]\b`,
+			KeepSuffix: 4,
+			validate:   validIdCard,
+		},
+		DetectorCreditCard: {
+			Expression: `\b\d(?:[\- ]?\d){12,18}\b`,
+			KeepSuffix: 4,
+			validate:   luhn,
+		},
+		DetectorIPv4: {
+			Expression: `\b(?:\d{1,3}\.){3}\d{1,3}\b`,
+			validate:   validIP,
+		},
+		// Both ends must be hex groups on word boundaries, so that 'std::vector' and 'Foo::bar' are not matched
+		DetectorIPv6: {
+			Expression: `\b[0-9A-Fa-f]{1,4}(?::[0-9A-Fa-f]{0,4}){1,6}:[0-9A-Fa-f]{1,4}\b`,
+			validate:   validIPv6,
+		},
+		DetectorJwt: {
+			Expression: `\beyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+`,
+		},
+		DetectorBearer: {
+			Expression: `(?i)\bbearer\s+([A-Za-z0-9\-._~+/]+=*)`,
+			Group:      1,
+		},
+	}
+	// DefaultDetectors are applied in this order, longer and more specific patterns go first
+	DefaultDetectors = []string{DetectorJwt, DetectorBearer, DetectorEmail, DetectorIdCard, DetectorCreditCard, DetectorPhone, DetectorIPv4, DetectorIPv6}
+)
+
+// BuiltinRule returns a copy of built-in rule of detector
+func BuiltinRule(detector string) (*Rule, bool) {
+	r, ok := builtinRules[detector]
+	if !ok {
+		return nil, false
+	}
+	c := *r
+	c.Name = detector
+	return &c, true
+}
+
+// NewMasker creates a Masker using built-in detectors and custom rules. Detectors are applied before custom rules.
+func NewMasker(detectors []string, rules []*Rule) (*Masker, error) {
+	m := &Masker{}
+	for _, detector := range detectors {
+		r, ok := BuiltinRule(detector)
+		if !ok {
+			return nil, errors.New("unsupported masking detector: " + detector)
+		}
+		if err := m.add(r); err != nil {
+			return nil, err
+		}
+	}
+	for _, r := range rules {
+		if err := m.add(r); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *Masker) add(r *Rule) error {
+	if r.Expression == "" {
+		return errors.New("empty masking expression: " + r.Name)
+	}
+	re, err := regexp.Compile(r.Expression)
+	if err != nil {
+		return err
+	}
+	if r.Group < 0 || r.Group > re.NumSubexp() {
+		return errors.New("invalid masking group: " + r.Name)
+	}
+	m.rules = append(m.rules, &compiledRule{Rule: r, regexp: re})
+	return nil
+}
+
+// Mask masks all sensitive data of s
+func (m *Masker) Mask(s string) string {
+	if m == nil {
+		return s
+	}
+	for _, r := range m.rules {
+		s = r.mask(s)
+	}
+	return s
+}
+
+// MaskAll masks strings in place
+func (m *Masker) MaskAll(ss []string) {
+	if m == nil {
+		return
+	}
+	for i := range ss {
+		ss[i] = m.Mask(ss[i])
+	}
+}
+
+func (r *compiledRule) mask(s string) string {
+	matches := r.regexp.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, match := range matches {
+		begin, end := match[2*r.Group], match[2*r.Group+1]
+		if begin < 0 {
+			continue
+		}
+		if r.validate != nil && !r.validate(s[match[0]:match[1]]) {
+			continue
+		}
+		sb.WriteString(s[last:begin])
+		sb.WriteString(r.maskPart(s[begin:end]))
+		last = end
+	}
+	if last == 0 {
+		return s
+	}
+	sb.WriteString(s[last:])
+	return sb.String()
+}
+
+func (r *compiledRule) maskPart(part string) string {
+	keepPrefix, keepSuffix := r.KeepPrefix, r.KeepSuffix
+	// Never keep the whole part
+	if keepPrefix+keepSuffix >= len(part) {
+		keepPrefix, keepSuffix = 0, 0
+	}
+	maskChar := r.MaskChar
+	if maskChar == "" {
+		maskChar = defaultMaskChar
+	}
+	n := len(part) - keepPrefix - keepSuffix
+	if n > maxMaskChars {
+		n = maxMaskChars
+	}
+	return part[:keepPrefix] + strings.Repeat(maskChar, n) + part[len(part)-keepSuffix:]
+}
+
+// luhn checks credit card numbers: https://en.wikipedia.org/wiki/Luhn_algorithm
+func luhn(s string) bool {
+	sum := 0
+	digits := 0
+	double := false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+		double = !double
+	}
+	return digits >= 13 && sum%10 == 0
+}
+
+// validIdCard checks the check digit of a mainland China resident ID card number (GB 11643-1999)
+func validIdCard(s string) bool {
+	weights := [17]int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+	sum := 0
+	for i := 0; i < 17; i++ {
+		sum += int(s[i]-'0') * weights[i]
+	}
+	checks := "10X98765432"
+	return checks[sum%11] == s[17] || (s[17] == 'x' && checks[sum%11] == 'X')
+}
+
+func validIP(s string) bool {
+	return net.ParseIP(s) != nil
+}
+
+// validIPv6 also requires a digit, because identifiers like 'Cafe::Add' are syntactically valid addresses
+func validIPv6(s string) bool {
+	return strings.Count(s, ":") >= 2 && strings.ContainsAny(s, "0123456789") && net.ParseIP(s) != nil
+}
@@ -8,6 +8,8 @@ import (
 	"errors"
 	"fmt"
 	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig"
+	"github.com/traas-stack/holoinsight-agent/pkg/util"
+	"time"
 )
 
 const (
@@ -15,6 +17,17 @@ const (
 	multilineWhatNext
 	defaultMaxLinesPerGroup = 1024
 	maxLinesPerGroup        = 4096
+	// maxMultilineTimeout limits the time to hold a pending log group, a larger value delays the log too much
+	maxMultilineTimeout = 30 * time.Second
+)
+
+const (
+	multilineModeWhere     = "where"
+	multilineModeIndent    = "indent"
+	multilineModeBackslash = "backslash"
+	multilineModeJava      = "java"
+	multilineModePython    = "python"
+	multilineModeGo        = "go"
 )
 
 type (
@@ -22,6 +35,41 @@ type (
 		where    XWhere
 		what     uint8
 		maxLines int
+		// timeout is max time to wait for more lines of the pending log group when reaching the file end
+		timeout time.Duration
+	}
+	// multilinePreset is a built-in line matching rule
+	multilinePreset struct {
+		expression string
+		what       uint8
+	}
+)
+
+var (
+	multilinePresets = map[string]multilinePreset{
+		multilineModeIndent: {
+			expression: `^\s`,
+			what:       multilineWhatPrevious,
+		},
+		multilineModeBackslash: {
+			expression: `\\$`,
+			what:       multilineWhatNext,
+		},
+		// "\tat com.foo.Bar.baz(Bar.java:10)", "\t... 3 more", "Caused by: ...", "java.lang.IllegalStateException: msg"
+		multilineModeJava: {
+			expression: `^(\s|Caused by:|Suppressed:|[\w$.]+(Exception|Error|Throwable)(:|$))`,
+			what:       multilineWhatPrevious,
+		},
+		// "Traceback (most recent call last):", "  File \"x.py\", line 1, in <module>", "ValueError: msg"
+		multilineModePython: {
+			expression: `^(\s|$|Traceback \(most recent call last\):|During handling of the above exception|The above exception was the direct cause|[\w.]+(Error|Exception|Warning|Exit|Interrupt)(:|$))`,
+			what:       multilineWhatPrevious,
+		},
+		// "goroutine 1 [running]:", "main.main()", "\t/app/main.go:10 +0x1d", "created by main.main", "exit status 2"
+		multilineModeGo: {
+			expression: `^(\s|$|goroutine \d+ \[|created by |\[signal |exit status |[\w./\-]+(\.\(\*?\w+\))?\.[\w.]+\(.*\)$)`,
+			what:       multilineWhatPrevious,
+		},
 	}
 )
 
@@ -29,22 +77,43 @@ func parseMultiline(cfg *collectconfig.FromLogMultiline) (*xMultiline, error) {
 	if cfg == nil || !cfg.Enabled {
 		return nil, nil
 	}
-	if cfg.Where == nil {
-		return nil, errors.New("multiline.where is nil")
-	}
+
+	var w XWhere
 	what := multilineWhatPrevious
-	switch cfg.What {
-	case "previous":
-		what = multilineWhatPrevious
-	case "next":
-		what = multilineWhatNext
+	switch cfg.Mode {
+	case "", multilineModeWhere:
+		if cfg.Where == nil {
+			return nil, errors.New("multiline.where is nil")
+		}
+		switch cfg.What {
+		case "previous":
+			what = multilineWhatPrevious
+		case "next":
+			what = multilineWhatNext
+		default:
+			return nil, fmt.Errorf("invalid what [%s]", cfg.What)
+		}
+		var err error
+		w, err = parseWhere(cfg.Where)
+		if err != nil {
+			return nil, err
+		}
 	default:
-		return nil, fmt.Errorf("invalid what [%s]", cfg.What)
-	}
-	w, err := parseWhere(cfg.Where)
-	if err != nil {
-		return nil, err
+		preset, ok := multilinePresets[cfg.Mode]
+		if !ok {
+			return nil, fmt.Errorf("invalid mode [%s]", cfg.Mode)
+		}
+		var err error
+		w, err = parseWhere(&collectconfig.Where{Regexp: &collectconfig.MRegexp{
+			Elect:      &collectconfig.Elect{Type: collectconfig.EElectLine},
+			Expression: preset.expression,
+		}})
+		if err != nil {
+			return nil, err
+		}
+		what = preset.what
 	}
+
 	maxLines := cfg.MaxLines
 	if maxLines <= 0 {
 		maxLines = defaultMaxLinesPerGroup
@@ -52,9 +121,17 @@ func parseMultiline(cfg *collectconfig.FromLogMultiline) (*xMultiline, error) {
 	if maxLines > maxLinesPerGroup {
 		maxLines = maxLinesPerGroup
 	}
+	timeout := util.ParseDurationDefault(cfg.Timeout, 0)
+	if timeout < 0 {
+		timeout = 0
+	}
+	if timeout > maxMultilineTimeout {
+		timeout = maxMultilineTimeout
+	}
 	return &xMultiline{
 		where:    w,
 		what:     what,
 		maxLines: maxLines,
+		timeout:  timeout,
 	}, nil
 }
@@ -0,0 +1,125 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package executor
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig"
+	"testing"
+	"time"
+)
+
+// groupMultilineLines feeds lines to a multilineAccumulator and returns the log groups, pending logs are flushed at the end
+func groupMultilineLines(t *testing.T, cfg *collectconfig.FromLogMultiline, lines []string) [][]string {
+	m, err := parseMultiline(cfg)
+	require.NoError(t, err)
+	a := newMultilineAccumulator(m)
+
+	var groups [][]string
+	ctx := &LogContext{}
+	for _, line := range lines {
+		ctx.log = &LogGroup{Line: line, Lines: []string{line}}
+		g, err := a.add(ctx)
+		require.NoError(t, err)
+		if g != nil {
+			groups = append(groups, g.Lines)
+		}
+	}
+	if g := a.getAndClearPending(); g != nil {
+		groups = append(groups, g.Lines)
+	}
+	return groups
+}
+
+func TestMultiline_modes(t *testing.T) {
+	groups := groupMultilineLines(t, &collectconfig.FromLogMultiline{Enabled: true, Mode: "indent"}, []string{
+		"a",
+		"  a1",
+		"\ta2",
+		"b",
+	})
+	assert.Equal(t, [][]string{{"a", "  a1", "\ta2"}, {"b"}}, groups)
+
+	groups = groupMultilineLines(t, &collectconfig.FromLogMultiline{Enabled: true, Mode: "backslash"}, []string{
+		"a",
+		`b \`,
+		`  b1 \`,
+		"  b2",
+		"c",
+	})
+	assert.Equal(t, [][]string{{"a"}, {`b \`, `  b1 \`, "  b2"}, {"c"}}, groups)
+
+	groups = groupMultilineLines(t, &collectconfig.FromLogMultiline{Enabled: true, Mode: "java"}, []string{
+		"2023-07-22 12:00:00,000 ERROR request failed",
+		"java.lang.IllegalStateException: bad state",
+		"\tat com.foo.Bar.baz(Bar.java:10)",
+		"Caused by: java.io.IOException: closed",
+		"\t... 3 more",
+		"2023-07-22 12:00:01,000 INFO ok",
+	})
+	assert.Len(t, groups, 2)
+	assert.Len(t, groups[0], 5)
+
+	groups = groupMultilineLines(t, &collectconfig.FromLogMultiline{Enabled: true, Mode: "python"}, []string{
+		"2023-07-22 12:00:00 ERROR request failed",
+		"Traceback (most recent call last):",
+		`  File "app.py", line 3, in <module>`,
+		"    main()",
+		"ValueError: bad value",
+		"2023-07-22 12:00:01 INFO ok",
+	})
+	assert.Len(t, groups, 2)
+	assert.Len(t, groups[0], 5)
+
+	groups = groupMultilineLines(t, &collectconfig.FromLogMultiline{Enabled: true, Mode: "go"}, []string{
+		"panic: runtime error: index out of range [1] with length 0",
+		"",
+		"goroutine 1 [running]:",
+		"main.main()",
+		"\t/app/main.go:10 +0x1d",
+		"github.com/foo/bar.(*Server).Serve(0xc000010000)",
+		"exit status 2",
+		"2023-07-22 12:00:01 INFO restarted",
+	})
+	assert.Len(t, groups, 2)
+	assert.Len(t, groups[0], 7)
+
+	_, err := parseMultiline(&collectconfig.FromLogMultiline{Enabled: true, Mode: "unknown"})
+	assert.Error(t, err)
+}
+
+func TestMultiline_flushTimeout(t *testing.T) {
+	m, err := parseMultiline(&collectconfig.FromLogMultiline{Enabled: true, Mode: "indent", Timeout: "2s"})
+	require.NoError(t, err)
+	a := newMultilineAccumulator(m)
+
+	ctx := &LogContext{log: &LogGroup{Line: "a", Lines: []string{"a"}}}
+	_, err = a.add(ctx)
+	require.NoError(t, err)
+
+	now := time.Now()
+	assert.Nil(t, a.flush(true, now.Add(time.Minute)))
+	assert.Nil(t, a.flush(false, now))
+
+	// more lines arrive before timeout
+	ctx.log = &LogGroup{Line: "  a1", Lines: []string{"  a1"}}
+	_, err = a.add(ctx)
+	require.NoError(t, err)
+
+	g := a.flush(false, time.Now().Add(3*time.Second))
+	require.NotNil(t, g)
+	assert.Equal(t, []string{"a", "  a1"}, g.Lines)
+	assert.Nil(t, a.flush(false, time.Now().Add(3*time.Second)))
+
+	// without timeout, pending logs are flushed immediately when reaching the file end
+	m, err = parseMultiline(&collectconfig.FromLogMultiline{Enabled: true, Mode: "indent"})
+	require.NoError(t, err)
+	a = newMultilineAccumulator(m)
+	ctx.log = &LogGroup{Line: "b", Lines: []string{"b"}}
+	_, err = a.add(ctx)
+	require.NoError(t, err)
+	assert.NotNil(t, a.flush(false, time.Now()))
+}
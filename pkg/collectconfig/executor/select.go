@@ -13,6 +13,9 @@ import (
 const (
 	logSamplesMaxCount  = 100
 	logSamplesMaxLength = 64 * 1024
+
+	logSamplesStrategyFirst     = "first"
+	logSamplesStrategyReservoir = "reservoir"
 )
 
 type (
@@ -47,6 +50,8 @@ type (
 		Where     XWhere
 		MaxCount  int
 		MaxLength int
+		Strategy  string
+		Dedup     bool
 	}
 )
 
@@ -144,9 +149,19 @@ func parseLogSamples(c *collectconfig.LogSamples) (*xLogSamples, error) {
 	if c.MaxLength > logSamplesMaxLength {
 		c.MaxLength = logSamplesMaxLength
 	}
+	strategy := c.Strategy
+	switch strategy {
+	case "":
+		strategy = logSamplesStrategyFirst
+	case logSamplesStrategyFirst, logSamplesStrategyReservoir:
+	default:
+		return nil, errors.New("unsupported log samples strategy " + strategy)
+	}
 	return &xLogSamples{
 		Where:     where,
 		MaxCount:  c.MaxCount,
 		MaxLength: c.MaxLength,
+		Strategy:  strategy,
+		Dedup:     c.Dedup,
 	}, nil
 }
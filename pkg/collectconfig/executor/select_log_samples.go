@@ -0,0 +1,82 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package executor
+
+import (
+	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig/executor/storage"
+	"hash/fnv"
+	"math/rand"
+)
+
+// add adds log sample to point according to sampling strategy.
+// lines are raw lines of the log. getSample builds the sample (e.g. masked and truncated lines) lazily, only when the sample is kept.
+func (x *xLogSamples) add(point *storage.Point, lines []string, getSample func() []string) {
+	if x.MaxCount <= 0 {
+		return
+	}
+
+	if !x.Dedup {
+		point.LogSamplesSeen++
+		if index := x.pickSlot(point); index >= 0 {
+			x.put(point, index, getSample(), 0)
+		}
+		return
+	}
+
+	// Identical log samples don't consume sampling slots. They are compared by hashes of raw lines, which is much cheaper than building samples.
+	// Hashes of evicted samples are not remembered, so an evicted sample is counted as a new one when it appears again.
+	hash := hashLogSample(lines)
+	for i, h := range point.LogSampleHashes {
+		if h == hash {
+			point.LogSampleCounts[i]++
+			return
+		}
+	}
+	point.LogSamplesSeen++
+	if index := x.pickSlot(point); index >= 0 {
+		x.put(point, index, getSample(), hash)
+	}
+}
+
+// pickSlot returns the index of point.LogSamples to store the new sample, returns -1 if the new sample is dropped
+func (x *xLogSamples) pickSlot(point *storage.Point) int {
+	if len(point.LogSamples) < x.MaxCount {
+		return len(point.LogSamples)
+	}
+	if x.Strategy != logSamplesStrategyReservoir {
+		return -1
+	}
+	// Algorithm R: the n-th sample replaces a random kept sample with probability MaxCount/n
+	if j := rand.Intn(int(point.LogSamplesSeen)); j < x.MaxCount {
+		return j
+	}
+	return -1
+}
+
+func (x *xLogSamples) put(point *storage.Point, index int, sample []string, hash uint64) {
+	if index == len(point.LogSamples) {
+		point.LogSamples = append(point.LogSamples, sample)
+		if x.Dedup {
+			point.LogSampleCounts = append(point.LogSampleCounts, 1)
+			point.LogSampleHashes = append(point.LogSampleHashes, hash)
+		}
+		return
+	}
+	point.LogSamples[index] = sample
+	if x.Dedup {
+		point.LogSampleCounts[index] = 1
+		point.LogSampleHashes[index] = hash
+	}
+}
+
+func hashLogSample(lines []string) uint64 {
+	h := fnv.New64a()
+	for _, line := range lines {
+		h.Write([]byte(line))
+		// separates lines, so that ["ab"] and ["a", "b"] are different
+		h.Write([]byte{'\n'})
+	}
+	return h.Sum64()
+}
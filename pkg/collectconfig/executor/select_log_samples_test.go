@@ -0,0 +1,101 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package executor
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig"
+	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig/executor/storage"
+	"github.com/traas-stack/holoinsight-agent/pkg/model"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func addLogSamples(x *xLogSamples, point *storage.Point, lines ...string) {
+	for _, line := range lines {
+		line := line
+		x.add(point, []string{line}, func() []string { return []string{line} })
+	}
+}
+
+func TestLogSamples_first(t *testing.T) {
+	x, err := parseLogSamples(&collectconfig.LogSamples{Enabled: true, MaxCount: 2})
+	require.NoError(t, err)
+	point := &storage.Point{}
+	addLogSamples(x, point, "a", "b", "c")
+	assert.Equal(t, [][]string{{"a"}, {"b"}}, point.LogSamples)
+	assert.Nil(t, point.LogSampleCounts)
+
+	_, err = parseLogSamples(&collectconfig.LogSamples{Enabled: true, Strategy: "unknown"})
+	assert.Error(t, err)
+}
+
+func TestLogSamples_reservoir(t *testing.T) {
+	x, err := parseLogSamples(&collectconfig.LogSamples{Enabled: true, MaxCount: 10, Strategy: "reservoir"})
+	require.NoError(t, err)
+
+	// samples after the first MaxCount ones must have a chance to be kept
+	late := 0
+	for round := 0; round < 20; round++ {
+		point := &storage.Point{}
+		for i := 0; i < 100; i++ {
+			addLogSamples(x, point, strconv.Itoa(i))
+		}
+		assert.Len(t, point.LogSamples, 10)
+		assert.Equal(t, int32(100), point.LogSamplesSeen)
+		for _, sample := range point.LogSamples {
+			if i, _ := strconv.Atoi(sample[0]); i >= 10 {
+				late++
+			}
+		}
+	}
+	assert.Greater(t, late, 0)
+}
+
+func TestLogSamples_dedup(t *testing.T) {
+	x, err := parseLogSamples(&collectconfig.LogSamples{Enabled: true, MaxCount: 2, Dedup: true})
+	require.NoError(t, err)
+	point := &storage.Point{}
+	addLogSamples(x, point, "a", "b", "a", "c", "b", "a")
+	assert.Equal(t, [][]string{{"a"}, {"b"}}, point.LogSamples)
+	assert.Equal(t, []int32{3, 2}, point.LogSampleCounts)
+}
+
+func TestLogSamples_dedupLazy(t *testing.T) {
+	x, err := parseLogSamples(&collectconfig.LogSamples{Enabled: true, MaxCount: 2, Dedup: true})
+	require.NoError(t, err)
+	point := &storage.Point{}
+	built := 0
+	for _, lines := range [][]string{{"a b"}, {"a", "b"}, {"a b"}, {"a b"}, {"c"}} {
+		lines := lines
+		x.add(point, lines, func() []string {
+			built++
+			return []string{lines[0][:1]}
+		})
+	}
+	// samples are built only when they are kept, and are deduplicated by raw lines instead of built samples
+	assert.Equal(t, 2, built)
+	assert.Equal(t, [][]string{{"a"}, {"a"}}, point.LogSamples)
+	assert.Equal(t, []int32{3, 1}, point.LogSampleCounts)
+	assert.Equal(t, int32(3), point.LogSamplesSeen)
+}
+
+func TestDetailConsumer_budget(t *testing.T) {
+	c := &detailConsumer{parent: &Consumer{}, maxBytesPerMinute: 100}
+	row := &model.Row{TagValues: []string{"0123456789"}, FieldValues: []float64{1, 2}}
+	size := estimateRowBytes(row)
+	assert.Equal(t, 34, size)
+
+	now := time.Unix(1690000000, 0)
+	assert.True(t, c.consumeBudget(size, now))
+	assert.True(t, c.consumeBudget(size, now))
+	assert.False(t, c.consumeBudget(size, now))
+	assert.False(t, c.consumeBudget(size, now))
+
+	// budget is reset every minute
+	assert.True(t, c.consumeBudget(size, now.Add(time.Minute)))
+}
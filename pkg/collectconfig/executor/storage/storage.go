@@ -53,6 +53,13 @@ type (
 		Values     []interface{}
 		// LogSamples is the log samples for this point.
 		LogSamples [][]string
+		// LogSampleCounts is the count of every log sample in LogSamples, it is only used when dedup is enabled.
+		LogSampleCounts []int32
+		// LogSampleHashes is the hash of raw lines of every log sample in LogSamples, it is only used when dedup is enabled.
+		LogSampleHashes []uint64
+		// LogSamplesSeen is the count of log samples matched in this point (distinct ones if dedup is enabled), it is used by reservoir sampling.
+		// With dedup and reservoir sampling, a sample evicted from LogSamples is counted again when it appears again.
+		LogSamplesSeen int32
 	}
 )
 
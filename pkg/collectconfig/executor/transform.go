@@ -78,6 +78,24 @@ func parseTransformFilter(filter *collectconfig.TransformFilterConf) (XTransform
 		parsed = &xDiscardFilter{}
 	case "cleanurlv1":
 		parsed = &xCleanUrlV1Filter{}
+	case "maskv1":
+		parsed = &xMaskV1Filter{conf: filter.MaskV1}
+	case "useragentv1":
+		parsed = &xUserAgentV1Filter{conf: filter.UserAgentV1}
+	case "urlpartv1":
+		parsed = &xUrlPartV1Filter{conf: filter.UrlPartV1}
+	case "iplookupv1":
+		parsed = &xIPLookupV1Filter{conf: filter.IPLookupV1}
+	case "durationv1":
+		parsed = &xDurationV1Filter{conf: filter.DurationV1}
+	case "bytesizev1":
+		parsed = &xByteSizeV1Filter{conf: filter.ByteSizeV1}
+	case "mathv1":
+		parsed = &xMathV1Filter{conf: filter.MathV1}
+	case "timestampv1":
+		parsed = &xTimestampV1Filter{conf: filter.TimestampV1}
+	case "expressionv1":
+		parsed = &xExpressionV1Filter{conf: filter.ExpressionV1}
 	default:
 		// Ignore this filter as if it is not exist. This could lead to unexpected result.
 		return nil, errors.New("unsupported transform filter " + util.ToJsonString(filter))
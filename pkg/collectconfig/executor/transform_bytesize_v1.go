@@ -0,0 +1,83 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package executor
+
+import (
+	"errors"
+	"github.com/spf13/cast"
+	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig"
+	"strings"
+)
+
+type (
+	// xByteSizeV1Filter parses byte sizes like '10KB' '2MiB' '512m' into numbers in target unit
+	xByteSizeV1Filter struct {
+		conf        *collectconfig.TransformFilterByteSizeV1
+		unit        float64
+		defaultUnit float64
+	}
+)
+
+var (
+	// byteSizePrefixes maps lower case unit prefixes to bytes.
+	// All of them are powers of 1024 like JVM, nginx and Docker, so 'KB' 'K' 'KiB' are the same.
+	byteSizePrefixes = map[string]float64{
+		"":   1,
+		"k":  1 << 10,
+		"m":  1 << 20,
+		"g":  1 << 30,
+		"t":  1 << 40,
+		"ki": 1 << 10,
+		"mi": 1 << 20,
+		"gi": 1 << 30,
+		"ti": 1 << 40,
+	}
+)
+
+// parseByteSizeUnit returns bytes of unit. Prefixes are case-insensitive, but the suffix must be 'B', because 'b' means bit (e.g. 'Mb').
+func parseByteSizeUnit(unit string) (float64, bool) {
+	prefix := unit
+	if strings.HasSuffix(unit, "B") {
+		prefix = unit[:len(unit)-1]
+	} else if unit == "" || strings.HasSuffix(unit, "b") {
+		return 0, false
+	}
+	bytes, ok := byteSizePrefixes[strings.ToLower(prefix)]
+	return bytes, ok
+}
+
+func (x *xByteSizeV1Filter) Init() error {
+	unit := x.conf.Unit
+	if unit == "" {
+		unit = "B"
+	}
+	defaultUnit := x.conf.DefaultUnit
+	if defaultUnit == "" {
+		defaultUnit = unit
+	}
+	var ok bool
+	if x.unit, ok = parseByteSizeUnit(unit); !ok {
+		return errors.New("byteSizeV1: unsupported unit " + unit)
+	}
+	if x.defaultUnit, ok = parseByteSizeUnit(defaultUnit); !ok {
+		return errors.New("byteSizeV1: unsupported default unit " + defaultUnit)
+	}
+	return nil
+}
+
+func (x *xByteSizeV1Filter) Filter(ctx *LogContext) (interface{}, error) {
+	number, unit, err := splitNumberUnit(cast.ToString(ctx.contextValue))
+	if err != nil {
+		return nil, err
+	}
+	if unit == "" {
+		return number * x.defaultUnit / x.unit, nil
+	}
+	u, ok := parseByteSizeUnit(unit)
+	if !ok {
+		return nil, errors.New("byteSizeV1: unsupported unit " + unit)
+	}
+	return number * u / x.unit, nil
+}
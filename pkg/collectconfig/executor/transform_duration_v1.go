@@ -0,0 +1,95 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package executor
+
+import (
+	"errors"
+	"github.com/spf13/cast"
+	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// xDurationV1Filter parses durations like '12ms' '1.2s' '350us' into numbers in target unit
+	xDurationV1Filter struct {
+		conf        *collectconfig.TransformFilterDurationV1
+		unit        float64
+		defaultUnit float64
+	}
+)
+
+var (
+	// durationUnits maps unit to nanoseconds
+	durationUnits = map[string]float64{
+		"ns":  float64(time.Nanosecond),
+		"us":  float64(time.Microsecond),
+		"µs":  float64(time.Microsecond),
+		"ms":  float64(time.Millisecond),
+		"s":   float64(time.Second),
+		"sec": float64(time.Second),
+		"m":   float64(time.Minute),
+		"min": float64(time.Minute),
+		"h":   float64(time.Hour),
+		"d":   float64(24 * time.Hour),
+	}
+)
+
+func (x *xDurationV1Filter) Init() error {
+	unit := x.conf.Unit
+	if unit == "" {
+		unit = "ms"
+	}
+	defaultUnit := x.conf.DefaultUnit
+	if defaultUnit == "" {
+		defaultUnit = unit
+	}
+	var ok bool
+	if x.unit, ok = durationUnits[strings.ToLower(unit)]; !ok {
+		return errors.New("durationV1: unsupported unit " + unit)
+	}
+	if x.defaultUnit, ok = durationUnits[strings.ToLower(defaultUnit)]; !ok {
+		return errors.New("durationV1: unsupported default unit " + defaultUnit)
+	}
+	return nil
+}
+
+func (x *xDurationV1Filter) Filter(ctx *LogContext) (interface{}, error) {
+	s := cast.ToString(ctx.contextValue)
+	number, unit, err := splitNumberUnit(s)
+	if err != nil {
+		return nil, err
+	}
+	if unit == "" {
+		return number * x.defaultUnit / x.unit, nil
+	}
+	if u, ok := durationUnits[strings.ToLower(unit)]; ok {
+		return number * u / x.unit, nil
+	}
+	// composite durations like '1h2m3s'
+	d, err := time.ParseDuration(strings.TrimSpace(s))
+	if err != nil {
+		return nil, err
+	}
+	return float64(d) / x.unit, nil
+}
+
+// splitNumberUnit splits a string like '1.2 s' into number 1.2 and unit 's'
+func splitNumberUnit(s string) (float64, string, error) {
+	s = strings.TrimSpace(s)
+	i := 0
+	for ; i < len(s); i++ {
+		c := s[i]
+		if !(c >= '0' && c <= '9' || c == '.' || i == 0 && (c == '-' || c == '+')) {
+			break
+		}
+	}
+	number, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, "", err
+	}
+	return number, strings.TrimSpace(s[i:]), nil
+}
@@ -0,0 +1,35 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package executor
+
+import (
+	"errors"
+	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig"
+)
+
+type (
+	// xExpressionV1Filter replaces the current value with the result of an expression.
+	// The current value is available as variable 'value' in the expression.
+	xExpressionV1Filter struct {
+		conf *collectconfig.TransformFilterExpressionV1
+		x    *xExpression
+	}
+)
+
+func (x *xExpressionV1Filter) Init() error {
+	if x.conf == nil {
+		return errors.New("expressionV1: conf is nil")
+	}
+	compiled, err := compileExpression(x.conf.Expression, x.conf.Timeout)
+	if err != nil {
+		return err
+	}
+	x.x = compiled
+	return nil
+}
+
+func (x *xExpressionV1Filter) Filter(ctx *LogContext) (interface{}, error) {
+	return x.x.Elect(ctx)
+}
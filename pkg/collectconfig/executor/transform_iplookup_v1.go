@@ -0,0 +1,106 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package executor
+
+import (
+	"errors"
+	"github.com/spf13/cast"
+	"github.com/traas-stack/holoinsight-agent/pkg/appconfig"
+	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig"
+	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig/executor/iplookup"
+	"github.com/traas-stack/holoinsight-agent/pkg/logger"
+	"go.uber.org/zap"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type (
+	// xIPLookupV1Filter looks up an ip against MaxMind DB files and the CIDR table configured per agent
+	xIPLookupV1Filter struct {
+		conf   *collectconfig.TransformFilterIPLookupV1
+		lookup *iplookup.Lookup
+		field  func(l *iplookup.Lookup, ip net.IP) string
+	}
+)
+
+var (
+	ipLookupOnce sync.Once
+	ipLookup     *iplookup.Lookup
+	ipLookupErr  error
+)
+
+// getIPLookup returns the Lookup built from agent config. Invalid geo files are skipped.
+func getIPLookup() (*iplookup.Lookup, error) {
+	ipLookupOnce.Do(func() {
+		conf := appconfig.StdAgentConfig.Data.IPLookup
+		var readers []*iplookup.MmdbReader
+		for _, path := range conf.GeoFiles {
+			r, err := iplookup.OpenMmdb(path)
+			if err != nil {
+				logger.Errorz("[transform] fail to open geo file", zap.String("path", path), zap.Error(err))
+				continue
+			}
+			readers = append(readers, r)
+		}
+		ipLookup, ipLookupErr = iplookup.NewLookup(readers, conf.Zones)
+	})
+	return ipLookup, ipLookupErr
+}
+
+func (x *xIPLookupV1Filter) Init() error {
+	switch x.conf.Field {
+	case "", "country":
+		x.field = func(l *iplookup.Lookup, ip net.IP) string { return l.Geo(ip).Country }
+	case "countryName":
+		x.field = func(l *iplookup.Lookup, ip net.IP) string { return l.Geo(ip).CountryName }
+	case "city":
+		x.field = func(l *iplookup.Lookup, ip net.IP) string { return l.Geo(ip).City }
+	case "asn":
+		x.field = func(l *iplookup.Lookup, ip net.IP) string {
+			if asn := l.Geo(ip).ASN; asn > 0 {
+				return strconv.FormatUint(asn, 10)
+			}
+			return ""
+		}
+	case "asnOrg":
+		x.field = func(l *iplookup.Lookup, ip net.IP) string { return l.Geo(ip).ASNOrg }
+	case "zone":
+		x.field = func(l *iplookup.Lookup, ip net.IP) string { return l.Zone(ip) }
+	default:
+		return errors.New("unsupported ip lookup field: " + x.conf.Field)
+	}
+
+	lookup, err := getIPLookup()
+	if err != nil {
+		return err
+	}
+	x.lookup = lookup
+	return nil
+}
+
+func (x *xIPLookupV1Filter) Filter(ctx *LogContext) (interface{}, error) {
+	ip := parseIP(cast.ToString(ctx.contextValue))
+	if ip == nil {
+		return x.conf.DefaultValue, nil
+	}
+	if s := x.field(x.lookup, ip); s != "" {
+		return s, nil
+	}
+	return x.conf.DefaultValue, nil
+}
+
+// parseIP parses an ip, it also accepts 'ip:port' and '[ipv6]:port'
+func parseIP(s string) net.IP {
+	s = strings.TrimSpace(s)
+	if ip := net.ParseIP(s); ip != nil {
+		return ip
+	}
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		return net.ParseIP(host)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package executor
+
+import (
+	"github.com/spf13/cast"
+	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig"
+	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig/executor/masking"
+)
+
+type (
+	// xMaskV1Filter masks sensitive data of the current value
+	xMaskV1Filter struct {
+		conf   *collectconfig.TransformFilterMaskV1
+		masker *masking.Masker
+	}
+)
+
+func (x *xMaskV1Filter) Init() error {
+	detectors := x.conf.Detectors
+	if len(detectors) == 0 && len(x.conf.Rules) == 0 {
+		detectors = masking.DefaultDetectors
+	}
+	rules := make([]*masking.Rule, 0, len(x.conf.Rules))
+	for _, r := range x.conf.Rules {
+		rules = append(rules, &masking.Rule{
+			Name:       r.Name,
+			Expression: r.Expression,
+			Group:      r.Group,
+			KeepPrefix: r.KeepPrefix,
+			KeepSuffix: r.KeepSuffix,
+			MaskChar:   r.MaskChar,
+		})
+	}
+	masker, err := masking.NewMasker(detectors, rules)
+	if err != nil {
+		return err
+	}
+	x.masker = masker
+	return nil
+}
+
+func (x *xMaskV1Filter) Filter(ctx *LogContext) (interface{}, error) {
+	return x.masker.Mask(cast.ToString(ctx.contextValue)), nil
+}
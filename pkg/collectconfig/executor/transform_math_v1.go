@@ -0,0 +1,52 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package executor
+
+import (
+	"errors"
+	"github.com/spf13/cast"
+	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig"
+	"math"
+	"strings"
+)
+
+type (
+	// xMathV1Filter does arithmetic on numbers: multiply, divide, add, round
+	xMathV1Filter struct {
+		conf *collectconfig.TransformFilterMathV1
+	}
+)
+
+func (x *xMathV1Filter) Init() error {
+	if x.conf.Divide != nil && *x.conf.Divide == 0 {
+		return errors.New("mathV1: divide by zero")
+	}
+	return nil
+}
+
+func (x *xMathV1Filter) Filter(ctx *LogContext) (interface{}, error) {
+	v := ctx.contextValue
+	if s, ok := v.(string); ok {
+		v = strings.TrimSpace(s)
+	}
+	f, err := cast.ToFloat64E(v)
+	if err != nil {
+		return nil, err
+	}
+	if x.conf.Multiply != nil {
+		f *= *x.conf.Multiply
+	}
+	if x.conf.Divide != nil {
+		f /= *x.conf.Divide
+	}
+	if x.conf.Add != nil {
+		f += *x.conf.Add
+	}
+	if x.conf.Round != nil {
+		scale := math.Pow10(*x.conf.Round)
+		f = math.Round(f*scale) / scale
+	}
+	return f, nil
+}
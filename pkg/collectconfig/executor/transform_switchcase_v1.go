@@ -79,6 +79,10 @@ func fillDefaultElect(where *collectconfig.Where) {
 				continue
 			}
 			electField := fieldValue.Elem().FieldByName("Elect")
+			// Some predicates (e.g. fieldEquals) have no 'Elect' field
+			if !electField.IsValid() {
+				continue
+			}
 			if electField.IsNil() {
 				electField.Set(reflect.ValueOf(collectconfig.CElectContext))
 			}
@@ -15,6 +15,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"testing"
+	"time"
 )
 
 func loadTransformConf(name string) (*collectconfig.TransformConf, error) {
@@ -198,3 +199,163 @@ func TestTransform_cleanurl(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "/a/b", ret)
 }
+
+func TestTransform_mask(t *testing.T) {
+	c, err := loadTransformFilter("transforms/mask.yaml")
+	assert.NoError(t, err)
+	assert.NotNil(t, c)
+
+	ret, err := c.Filter(&LogContext{contextValue: "user=alice@example.com order=1234567890 Authorization: Bearer abc.def"})
+	assert.NoError(t, err)
+	assert.Equal(t, "user=a****@example.com order=******7890 Authorization: Bearer *******", ret)
+}
+
+func TestTransform_useragent(t *testing.T) {
+	c, err := loadTransformFilter("transforms/useragent.yaml")
+	assert.NoError(t, err)
+	assert.NotNil(t, c)
+
+	ret, err := c.Filter(&LogContext{contextValue: "Mozilla/5.0 (iPhone; CPU iPhone OS 16_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.5 Mobile/15E148 Safari/604.1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "iOS", ret)
+}
+
+func TestTransform_urlpart(t *testing.T) {
+	c, err := loadTransformFilter("transforms/urlpart.yaml")
+	assert.NoError(t, err)
+	assert.NotNil(t, c)
+
+	ret, err := c.Filter(&LogContext{contextValue: "https://example.org/a/b?appId=holoinsight&c=d"})
+	assert.NoError(t, err)
+	assert.Equal(t, "holoinsight", ret)
+
+	ret, err = c.Filter(&LogContext{contextValue: "/a/b?c=d"})
+	assert.NoError(t, err)
+	assert.Equal(t, "unknown", ret)
+
+	assert.Equal(t, "b", pathSegment("/a/b/", -1))
+	assert.Equal(t, "a", pathSegment("/a/b/", 0))
+	assert.Equal(t, "", pathSegment("/a/b/", 2))
+}
+
+func TestTransform_cidr(t *testing.T) {
+	c, err := loadTransformFilter("transforms/cidr.yaml")
+	assert.NoError(t, err)
+	assert.NotNil(t, c)
+
+	for ip, expected := range map[string]string{
+		"10.1.2.3":        "internal",
+		"100.64.1.1:80":   "internal",
+		"[::1]:8080":      "internal",
+		"8.8.8.8":         "external",
+		"not an ip":       "external",
+		"2001:4860::8888": "external",
+	} {
+		ret, err := c.Filter(&LogContext{contextValue: ip})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, ret, ip)
+	}
+}
+
+func TestTransform_duration(t *testing.T) {
+	c, err := loadTransformFilter("transforms/duration.yaml")
+	assert.NoError(t, err)
+	assert.NotNil(t, c)
+
+	for s, expected := range map[string]float64{
+		"12ms":   12,
+		"1.2s":   1200,
+		"350us":  0.35,
+		"350":    0.35,
+		"1 min":  60000,
+		"1h2m3s": 3723000,
+	} {
+		ret, err := c.Filter(&LogContext{contextValue: s})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, ret, s)
+	}
+
+	_, err = c.Filter(&LogContext{contextValue: "abc"})
+	assert.Error(t, err)
+}
+
+func TestTransform_bytesize(t *testing.T) {
+	c, err := loadTransformFilter("transforms/bytesize.yaml")
+	assert.NoError(t, err)
+	assert.NotNil(t, c)
+
+	for s, expected := range map[string]float64{
+		"10KB":  10,
+		"10kB":  10,
+		"2MiB":  2048,
+		"2Mi":   2048,
+		"512":   0.5,
+		"1.5 g": 1.5 * 1024 * 1024,
+	} {
+		ret, err := c.Filter(&LogContext{contextValue: s})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, ret, s)
+	}
+
+	// bits and unknown units
+	for _, s := range []string{"1PB", "1Mb", "1kb", "1b"} {
+		_, err = c.Filter(&LogContext{contextValue: s})
+		assert.Error(t, err, s)
+	}
+}
+
+func TestTransform_timestamp(t *testing.T) {
+	c, err := loadTransformFilter("transforms/timestamp.yaml")
+	assert.NoError(t, err)
+	assert.NotNil(t, c)
+
+	ret, err := c.Filter(&LogContext{contextValue: "2023-07-22 12:26:40.123"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1690000000123), ret)
+
+	x := &xTimestampV1Filter{conf: &collectconfig.TransformFilterTimestampV1{
+		ToFormat: FormatGolangLayout,
+		ToLayout: time.RFC3339,
+		Timezone: "UTC",
+	}}
+	assert.NoError(t, x.Init())
+	for s, expected := range map[string]string{
+		"1690000000":          "2023-07-22T04:26:40Z",
+		"1690000000123":       "2023-07-22T04:26:40Z",
+		"1690000000123456789": "2023-07-22T04:26:40Z",
+		"1690000000.123":      "2023-07-22T04:26:40Z",
+	} {
+		ret, err := x.Filter(&LogContext{contextValue: s})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, ret, s)
+	}
+
+	// fractional seconds in auto mode
+	x = &xTimestampV1Filter{conf: &collectconfig.TransformFilterTimestampV1{}}
+	assert.NoError(t, x.Init())
+	ret, err = x.Filter(&LogContext{contextValue: "1690000000.123"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1690000000123), ret)
+
+	// timezone of the target is used when timezone is not configured
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	assert.NoError(t, err)
+	x = &xTimestampV1Filter{conf: &collectconfig.TransformFilterTimestampV1{
+		FromFormat: FormatGolangLayout,
+		FromLayout: "2006-01-02 15:04:05.000",
+		ToFormat:   FormatGolangLayout,
+		ToLayout:   "15:04",
+	}}
+	assert.NoError(t, x.Init())
+	ret, err = x.Filter(&LogContext{contextValue: "2023-07-22 12:26:40.123", tz: shanghai})
+	assert.NoError(t, err)
+	assert.Equal(t, "12:26", ret)
+	x = &xTimestampV1Filter{conf: &collectconfig.TransformFilterTimestampV1{
+		FromFormat: FormatGolangLayout,
+		FromLayout: "2006-01-02 15:04:05.000",
+	}}
+	assert.NoError(t, x.Init())
+	ret, err = x.Filter(&LogContext{contextValue: "2023-07-22 12:26:40.123", tz: shanghai})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1690000000123), ret)
+}
@@ -0,0 +1,177 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package executor
+
+import (
+	"errors"
+	"github.com/spf13/cast"
+	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig"
+	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig/executor/timeparser"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatUnixMicro = "unixMicro"
+	FormatUnixNano  = "unixNano"
+)
+
+type (
+	// xTimestampV1Filter converts timestamps between formats
+	xTimestampV1Filter struct {
+		conf       *collectconfig.TransformFilterTimestampV1
+		fromFormat string
+		toFormat   string
+		// tz is the configured timezone, timezone of the target is used if it is nil
+		tz *time.Location
+		// w is the detected time style when fromFormat is auto
+		w *timeparser.TimeElectorWrapper
+	}
+)
+
+func (x *xTimestampV1Filter) Init() error {
+	x.fromFormat = x.conf.FromFormat
+	if x.fromFormat == "" {
+		x.fromFormat = FormatAuto
+	}
+	x.toFormat = x.conf.ToFormat
+	if x.toFormat == "" {
+		x.toFormat = FormatUnixMilli
+	}
+
+	if x.conf.Timezone != "" {
+		tz, err := time.LoadLocation(x.conf.Timezone)
+		if err != nil {
+			return err
+		}
+		x.tz = tz
+	}
+
+	switch x.fromFormat {
+	case FormatAuto, FormatUnix, FormatUnixMilli, FormatUnixMicro, FormatUnixNano:
+	case FormatGolangLayout:
+		if x.conf.FromLayout == "" {
+			return errors.New("timestampV1: fromLayout is required")
+		}
+	default:
+		return errors.New("timestampV1: unsupported fromFormat " + x.fromFormat)
+	}
+	switch x.toFormat {
+	case FormatUnix, FormatUnixMilli, FormatUnixMicro, FormatUnixNano:
+	case FormatGolangLayout:
+		if x.conf.ToLayout == "" {
+			return errors.New("timestampV1: toLayout is required")
+		}
+	default:
+		return errors.New("timestampV1: unsupported toFormat " + x.toFormat)
+	}
+	return nil
+}
+
+func (x *xTimestampV1Filter) Filter(ctx *LogContext) (interface{}, error) {
+	tz := x.tz
+	if tz == nil {
+		tz = ctx.tz
+	}
+	if tz == nil {
+		tz = time.Local
+	}
+	t, err := x.parse(strings.TrimSpace(cast.ToString(ctx.contextValue)), tz)
+	if err != nil {
+		return nil, err
+	}
+	switch x.toFormat {
+	case FormatUnix:
+		return t.Unix(), nil
+	case FormatUnixMilli:
+		return t.UnixMilli(), nil
+	case FormatUnixMicro:
+		return t.UnixMicro(), nil
+	case FormatUnixNano:
+		return t.UnixNano(), nil
+	default:
+		return t.In(tz).Format(x.conf.ToLayout), nil
+	}
+}
+
+func (x *xTimestampV1Filter) parse(s string, tz *time.Location) (time.Time, error) {
+	switch x.fromFormat {
+	case FormatGolangLayout:
+		return time.ParseInLocation(x.conf.FromLayout, s, tz)
+	case FormatAuto:
+		if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return parseUnixTimestamp(s, guessUnixFormat(s))
+		}
+		// only seconds have fractions, e.g. '1690000000.123'
+		if isFractionalUnix(s) {
+			return parseUnixTimestamp(s, FormatUnix)
+		}
+		if x.w == nil {
+			if x.w = timeparser.DetectTimeElectFromLine(s); x.w == nil {
+				return time.Time{}, errors.New("timestampV1: unknown time format " + s)
+			}
+		}
+		ms := x.w.Elector.Parse(x.w.Style, s, tz)
+		if ms == timeparser.ParseError {
+			// style may change, detect again next time
+			x.w = nil
+			return time.Time{}, errors.New("timestampV1: fail to parse time " + s)
+		}
+		return time.UnixMilli(ms), nil
+	default:
+		return parseUnixTimestamp(s, x.fromFormat)
+	}
+}
+
+// isFractionalUnix returns true if s looks like unix seconds with fractions, e.g. '1690000000.123'
+func isFractionalUnix(s string) bool {
+	index := strings.IndexByte(s, '.')
+	if index <= 0 || index == len(s)-1 {
+		return false
+	}
+	if _, err := strconv.ParseInt(s[:index], 10, 64); err != nil {
+		return false
+	}
+	_, err := strconv.ParseUint(s[index+1:], 10, 64)
+	return err == nil
+}
+
+// guessUnixFormat guesses precision of a unix timestamp by its digits count
+func guessUnixFormat(s string) string {
+	switch n := len(strings.TrimLeft(s, "-")); {
+	case n <= 10:
+		return FormatUnix
+	case n <= 13:
+		return FormatUnixMilli
+	case n <= 16:
+		return FormatUnixMicro
+	default:
+		return FormatUnixNano
+	}
+}
+
+func parseUnixTimestamp(s string, format string) (time.Time, error) {
+	// seconds may have fractions, e.g. '1690000000.123'
+	if format == FormatUnix {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.UnixMilli(int64(f * 1000)), nil
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	switch format {
+	case FormatUnixMilli:
+		return time.UnixMilli(i), nil
+	case FormatUnixMicro:
+		return time.UnixMicro(i), nil
+	default:
+		return time.Unix(0, i), nil
+	}
+}
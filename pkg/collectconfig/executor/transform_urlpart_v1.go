@@ -0,0 +1,77 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package executor
+
+import (
+	"errors"
+	"github.com/spf13/cast"
+	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig"
+	"net/url"
+	"strings"
+)
+
+type (
+	// xUrlPartV1Filter extracts a part from a URL or a request uri (e.g. '/a/b?c=d')
+	xUrlPartV1Filter struct {
+		conf *collectconfig.TransformFilterUrlPartV1
+	}
+)
+
+func (x *xUrlPartV1Filter) Init() error {
+	switch x.conf.Part {
+	case "scheme", "host", "port", "path", "query", "segment":
+	case "param":
+		if x.conf.Param == "" {
+			return errors.New("urlPartV1: param is required")
+		}
+	default:
+		return errors.New("urlPartV1: unsupported part " + x.conf.Part)
+	}
+	return nil
+}
+
+func (x *xUrlPartV1Filter) Filter(ctx *LogContext) (interface{}, error) {
+	u, err := url.Parse(cast.ToString(ctx.contextValue))
+	if err != nil {
+		return x.conf.DefaultValue, nil
+	}
+
+	part := ""
+	switch x.conf.Part {
+	case "scheme":
+		part = u.Scheme
+	case "host":
+		part = u.Hostname()
+	case "port":
+		part = u.Port()
+	case "path":
+		part = u.Path
+	case "query":
+		part = u.RawQuery
+	case "param":
+		// ParseQuery returns values parsed before the first error, it is good enough for logs
+		values, _ := url.ParseQuery(u.RawQuery)
+		part = values.Get(x.conf.Param)
+	case "segment":
+		part = pathSegment(u.Path, x.conf.Index)
+	}
+
+	if part == "" {
+		return x.conf.DefaultValue, nil
+	}
+	return part, nil
+}
+
+// pathSegment returns the index-th non-empty segment of path, negative index counts from the end
+func pathSegment(path string, index int) string {
+	segments := strings.FieldsFunc(path, func(r rune) bool { return r == '/' })
+	if index < 0 {
+		index += len(segments)
+	}
+	if index < 0 || index >= len(segments) {
+		return ""
+	}
+	return segments[index]
+}
@@ -0,0 +1,51 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package executor
+
+import (
+	"errors"
+	"github.com/spf13/cast"
+	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig"
+	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig/executor/useragent"
+)
+
+type (
+	// xUserAgentV1Filter extracts browser/os/device from a User-Agent string
+	xUserAgentV1Filter struct {
+		conf   *collectconfig.TransformFilterUserAgentV1
+		parser *useragent.Parser
+		field  func(c *useragent.Client) string
+	}
+)
+
+func (x *xUserAgentV1Filter) Init() error {
+	switch x.conf.Field {
+	case "", "browser":
+		x.field = func(c *useragent.Client) string { return c.Browser.Family }
+	case "browserVersion":
+		x.field = func(c *useragent.Client) string { return c.Browser.Version() }
+	case "browserMajor":
+		x.field = func(c *useragent.Client) string { return c.Browser.Major }
+	case "os":
+		x.field = func(c *useragent.Client) string { return c.OS.Family }
+	case "osVersion":
+		x.field = func(c *useragent.Client) string { return c.OS.Version() }
+	case "device":
+		x.field = func(c *useragent.Client) string { return c.Device }
+	default:
+		return errors.New("unsupported user agent field: " + x.conf.Field)
+	}
+
+	parser, err := useragent.Default()
+	if err != nil {
+		return err
+	}
+	x.parser = parser
+	return nil
+}
+
+func (x *xUserAgentV1Filter) Filter(ctx *LogContext) (interface{}, error) {
+	return x.field(x.parser.Parse(cast.ToString(ctx.contextValue))), nil
+}
@@ -0,0 +1,240 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package useragent
+
+import (
+	_ "embed"
+	"github.com/golang/groupcache/lru"
+	"github.com/traas-stack/holoinsight-agent/pkg/util"
+	"gopkg.in/yaml.v3"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// Other is the family of unknown user agents, OSes and devices
+	Other = "Other"
+
+	DefaultCacheSize = 1024
+	// maxCachedLength limits length of cached user agents, too long user agents are rare and usually malicious
+	maxCachedLength = 512
+)
+
+type (
+	// Client is the result of parsing a User-Agent string
+	Client struct {
+		Browser Version
+		OS      Version
+		Device  string
+	}
+	// Version is a family with its version parts
+	Version struct {
+		Family string
+		Major  string
+		Minor  string
+		Patch  string
+	}
+	// Parser parses User-Agent strings using a regex database. Results are cached in a LRU cache. It is safe for concurrent use.
+	Parser struct {
+		browsers []*versionParser
+		oses     []*versionParser
+		devices  []*deviceParser
+
+		mutex sync.Mutex
+		cache *lru.Cache
+	}
+	versionParser struct {
+		regexp       *regexp.Regexp
+		replacements [4]string
+	}
+	deviceParser struct {
+		regexp      *regexp.Regexp
+		replacement string
+	}
+	regexesConf struct {
+		UserAgentParsers []*regexConf `yaml:"user_agent_parsers"`
+		OSParsers        []*regexConf `yaml:"os_parsers"`
+		DeviceParsers    []*regexConf `yaml:"device_parsers"`
+	}
+	regexConf struct {
+		Regex             string `yaml:"regex"`
+		RegexFlag         string `yaml:"regex_flag"`
+		FamilyReplacement string `yaml:"family_replacement"`
+		V1Replacement     string `yaml:"v1_replacement"`
+		V2Replacement     string `yaml:"v2_replacement"`
+		OSReplacement     string `yaml:"os_replacement"`
+		OSV1Replacement   string `yaml:"os_v1_replacement"`
+		OSV2Replacement   string `yaml:"os_v2_replacement"`
+		DeviceReplacement string `yaml:"device_replacement"`
+	}
+)
+
+var (
+	//go:embed regexes.yaml
+	embeddedRegexes []byte
+
+	defaultParserOnce sync.Once
+	defaultParser     *Parser
+	defaultParserErr  error
+
+	groupRefPattern = regexp.MustCompile(`\$\d`)
+)
+
+// Default returns the shared parser backed by the embedded regex database
+func Default() (*Parser, error) {
+	defaultParserOnce.Do(func() {
+		defaultParser, defaultParserErr = NewParser(embeddedRegexes, DefaultCacheSize)
+	})
+	return defaultParser, defaultParserErr
+}
+
+// NewParser creates a parser from a regex database in yaml format
+func NewParser(regexes []byte, cacheSize int) (*Parser, error) {
+	conf := &regexesConf{}
+	if err := yaml.Unmarshal(regexes, conf); err != nil {
+		return nil, err
+	}
+	if cacheSize <= 0 {
+		cacheSize = DefaultCacheSize
+	}
+
+	p := &Parser{cache: lru.New(cacheSize)}
+	for _, c := range conf.UserAgentParsers {
+		r, err := compile(c)
+		if err != nil {
+			return nil, err
+		}
+		p.browsers = append(p.browsers, &versionParser{
+			regexp:       r,
+			replacements: [4]string{c.FamilyReplacement, c.V1Replacement, c.V2Replacement},
+		})
+	}
+	for _, c := range conf.OSParsers {
+		r, err := compile(c)
+		if err != nil {
+			return nil, err
+		}
+		p.oses = append(p.oses, &versionParser{
+			regexp:       r,
+			replacements: [4]string{c.OSReplacement, c.OSV1Replacement, c.OSV2Replacement},
+		})
+	}
+	for _, c := range conf.DeviceParsers {
+		r, err := compile(c)
+		if err != nil {
+			return nil, err
+		}
+		p.devices = append(p.devices, &deviceParser{regexp: r, replacement: c.DeviceReplacement})
+	}
+	return p, nil
+}
+
+func compile(c *regexConf) (*regexp.Regexp, error) {
+	expr := c.Regex
+	if c.RegexFlag != "" {
+		expr = "(?" + c.RegexFlag + ")" + expr
+	}
+	return regexp.Compile(expr)
+}
+
+// Parse parses a User-Agent string. The returned Client is shared by callers, it must not be modified.
+func (p *Parser) Parse(ua string) *Client {
+	cacheable := len(ua) <= maxCachedLength
+	if cacheable {
+		p.mutex.Lock()
+		v, ok := p.cache.Get(ua)
+		p.mutex.Unlock()
+		if ok {
+			return v.(*Client)
+		}
+	}
+
+	c := &Client{
+		Browser: parseVersion(p.browsers, ua),
+		OS:      parseVersion(p.oses, ua),
+		Device:  p.parseDevice(ua),
+	}
+
+	if cacheable {
+		// The key must not share memory with ua, which is usually a substring of a log line
+		key := util.DeepCopyString(ua)
+		p.mutex.Lock()
+		p.cache.Add(key, c)
+		p.mutex.Unlock()
+	}
+	return c
+}
+
+func parseVersion(parsers []*versionParser, ua string) Version {
+	for _, vp := range parsers {
+		groups := vp.regexp.FindStringSubmatch(ua)
+		if groups == nil {
+			continue
+		}
+		var parts [4]string
+		for i := range parts {
+			if vp.replacements[i] != "" {
+				parts[i] = replaceGroups(vp.replacements[i], groups)
+			} else if i+1 < len(groups) {
+				parts[i] = groups[i+1]
+			}
+		}
+		if parts[0] == "" {
+			parts[0] = Other
+		}
+		return Version{Family: parts[0], Major: parts[1], Minor: parts[2], Patch: parts[3]}
+	}
+	return Version{Family: Other}
+}
+
+func (p *Parser) parseDevice(ua string) string {
+	for _, dp := range p.devices {
+		groups := dp.regexp.FindStringSubmatch(ua)
+		if groups == nil {
+			continue
+		}
+		device := ""
+		if dp.replacement != "" {
+			device = replaceGroups(dp.replacement, groups)
+		} else if len(groups) > 1 {
+			device = groups[1]
+		}
+		if device = strings.TrimSpace(device); device != "" {
+			return device
+		}
+	}
+	return Other
+}
+
+// replaceGroups replaces '$N' with group N
+func replaceGroups(s string, groups []string) string {
+	if !strings.Contains(s, "$") {
+		return s
+	}
+	return strings.TrimSpace(groupRefPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		i, _ := strconv.Atoi(ref[1:])
+		if i < len(groups) {
+			return groups[i]
+		}
+		return ""
+	}))
+}
+
+// Version returns version string of the family, e.g. '16.5'
+func (v Version) Version() string {
+	var sb strings.Builder
+	for _, part := range []string{v.Major, v.Minor, v.Patch} {
+		if part == "" {
+			break
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte('.')
+		}
+		sb.WriteString(part)
+	}
+	return sb.String()
+}
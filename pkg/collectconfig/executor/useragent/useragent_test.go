@@ -0,0 +1,52 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package useragent
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParser_Parse(t *testing.T) {
+	p, err := Default()
+	require.NoError(t, err)
+
+	cases := []struct {
+		ua      string
+		browser string
+		version string
+		os      string
+		osVer   string
+		device  string
+	}{
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/114.0.0.0 Safari/537.36",
+			"Chrome", "114.0.0", "Windows", "10", Other},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/114.0.0.0 Safari/537.36 Edg/114.0.1823.67",
+			"Edge", "114.0.1823", "Windows", "10", Other},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 16_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.5 Mobile/15E148 Safari/604.1",
+			"Mobile Safari", "16.5", "iOS", "16.5", "iPhone"},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.5 Safari/605.1.15",
+			"Safari", "16.5", "Mac OS X", "10.15.7", "Mac"},
+		{"Mozilla/5.0 (Linux; Android 13; SM-S918B) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/114.0.5735.196 Mobile Safari/537.36",
+			"Chrome Mobile", "114.0.5735", "Android", "13", "Samsung SM-S918B"},
+		{"Mozilla/5.0 (Linux; Android 12; Pixel 6 Build/SD1A.210817.036) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/114.0.5735.196 Mobile Safari/537.36 MicroMessenger/8.0.38.2400",
+			"WeChat", "8.0.38", "Android", "12", "Pixel 6"},
+		{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			"Googlebot", "2.1", Other, "", "Spider"},
+		{"curl/7.88.1", "curl", "7.88.1", Other, "", Other},
+		{"", Other, "", Other, "", Other},
+	}
+	for _, c := range cases {
+		client := p.Parse(c.ua)
+		assert.Equal(t, c.browser, client.Browser.Family, c.ua)
+		assert.Equal(t, c.version, client.Browser.Version(), c.ua)
+		assert.Equal(t, c.os, client.OS.Family, c.ua)
+		assert.Equal(t, c.osVer, client.OS.Version(), c.ua)
+		assert.Equal(t, c.device, client.Device, c.ua)
+		// cached
+		assert.Same(t, client, p.Parse(c.ua))
+	}
+}
@@ -59,5 +59,45 @@ func parseWhere(w *collectconfig.Where) (XWhere, error) {
 		return parseIn(w.In)
 	}
 
+	if w.Cidr != nil {
+		return parseCidr(w.Cidr)
+	}
+
+	if w.StartsWith != nil {
+		return parseStartsWith(w.StartsWith)
+	}
+
+	if w.EndsWith != nil {
+		return parseEndsWith(w.EndsWith)
+	}
+
+	if w.Equals != nil {
+		return parseEquals(w.Equals)
+	}
+
+	if w.Exists != nil {
+		return parseExists(w.Exists)
+	}
+
+	if w.IsEmpty != nil {
+		return parseIsEmpty(w.IsEmpty)
+	}
+
+	if w.FieldEquals != nil {
+		return parseFieldEquals(w.FieldEquals)
+	}
+
+	if w.LengthOp != nil {
+		return parseLengthOp(w.LengthOp)
+	}
+
+	if w.StringOp != nil {
+		return parseStringOp(w.StringOp)
+	}
+
+	if w.Expression != nil {
+		return parseExpressionWhere(w.Expression)
+	}
+
 	return &xWhereAlwaysTrue{}, nil
 }
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package executor
+
+import (
+	"errors"
+	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig"
+	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig/executor/iplookup"
+	"github.com/traas-stack/holoinsight-agent/pkg/util"
+	"net"
+)
+
+type (
+	xCidr struct {
+		elect   XElect
+		ipNets  []*net.IPNet
+		private bool
+		zones   []string
+		lookup  *iplookup.Lookup
+	}
+)
+
+func (x *xCidr) Test(ctx *LogContext) (ret bool, _ error) {
+	we := ctx.whereEvent
+	if we != nil {
+		we.Name = "cidr"
+		defer func() {
+			we.Result = ret
+		}()
+	}
+
+	str, err := x.elect.ElectString(ctx)
+	if err != nil {
+		return false, err
+	}
+	ip := parseIP(str)
+	if ip == nil {
+		return false, nil
+	}
+	if x.private && (ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast()) {
+		return true, nil
+	}
+	for _, ipNet := range x.ipNets {
+		if ipNet.Contains(ip) {
+			return true, nil
+		}
+	}
+	if len(x.zones) > 0 {
+		if zone := x.lookup.Zone(ip); zone != "" && util.StringSliceContains(x.zones, zone) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func parseCidr(c *collectconfig.MCidr) (XWhere, error) {
+	elect, err := parseElect(c.Elect)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.Values) == 0 && !c.Private && len(c.Zones) == 0 {
+		return nil, errors.New("cidr: values, private and zones are all empty")
+	}
+	x := &xCidr{
+		elect:   elect,
+		private: c.Private,
+		zones:   c.Zones,
+	}
+	for _, value := range c.Values {
+		ipNet, err := iplookup.ParseCidr(value)
+		if err != nil {
+			return nil, err
+		}
+		x.ipNets = append(x.ipNets, ipNet)
+	}
+	if len(x.zones) > 0 {
+		if x.lookup, err = getIPLookup(); err != nil {
+			return nil, err
+		}
+	}
+	return x, nil
+}
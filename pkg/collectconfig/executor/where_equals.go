@@ -0,0 +1,93 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package executor
+
+import (
+	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig"
+	"strings"
+)
+
+type (
+	xEquals struct {
+		elect      XElect
+		value      string
+		ignoreCase bool
+	}
+	xFieldEquals struct {
+		left       XElect
+		right      XElect
+		ignoreCase bool
+	}
+)
+
+func (x *xEquals) Test(ctx *LogContext) (ret bool, _ error) {
+	we := ctx.whereEvent
+	if we != nil {
+		we.Name = "equals"
+		defer func() {
+			we.Result = ret
+		}()
+	}
+
+	s, err := x.elect.ElectString(ctx)
+	if err != nil {
+		return false, err
+	}
+	if x.ignoreCase {
+		return strings.EqualFold(s, x.value), nil
+	}
+	return s == x.value, nil
+}
+
+func (x *xFieldEquals) Test(ctx *LogContext) (ret bool, _ error) {
+	we := ctx.whereEvent
+	if we != nil {
+		we.Name = "fieldEquals"
+		defer func() {
+			we.Result = ret
+		}()
+	}
+
+	left, err := x.left.ElectString(ctx)
+	if err != nil {
+		return false, err
+	}
+	right, err := x.right.ElectString(ctx)
+	if err != nil {
+		return false, err
+	}
+	if x.ignoreCase {
+		return strings.EqualFold(left, right), nil
+	}
+	return left == right, nil
+}
+
+func parseEquals(r *collectconfig.MEquals) (XWhere, error) {
+	elect, err := parseElect(r.Elect)
+	if err != nil {
+		return nil, err
+	}
+	return &xEquals{
+		elect:      elect,
+		value:      r.Value,
+		ignoreCase: r.IgnoreCase,
+	}, nil
+}
+
+func parseFieldEquals(r *collectconfig.MFieldEquals) (XWhere, error) {
+	left, err := parseElect(r.Left)
+	if err != nil {
+		return nil, err
+	}
+	right, err := parseElect(r.Right)
+	if err != nil {
+		return nil, err
+	}
+	return &xFieldEquals{
+		left:       left,
+		right:      right,
+		ignoreCase: r.IgnoreCase,
+	}, nil
+}
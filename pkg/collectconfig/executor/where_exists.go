@@ -0,0 +1,66 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package executor
+
+import (
+	"github.com/spf13/cast"
+	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig"
+)
+
+type (
+	// xExists tests whether the elected field exists. A field is missing if elect fails or returns nil (e.g. JSON null).
+	xExists struct {
+		elect XElect
+	}
+	// xIsEmpty tests whether the elected field is missing or an empty string
+	xIsEmpty struct {
+		elect XElect
+	}
+)
+
+func (x *xExists) Test(ctx *LogContext) (ret bool, _ error) {
+	we := ctx.whereEvent
+	if we != nil {
+		we.Name = "exists"
+		defer func() {
+			we.Result = ret
+		}()
+	}
+
+	v, err := x.elect.Elect(ctx)
+	return err == nil && v != nil, nil
+}
+
+func (x *xIsEmpty) Test(ctx *LogContext) (ret bool, _ error) {
+	we := ctx.whereEvent
+	if we != nil {
+		we.Name = "isEmpty"
+		defer func() {
+			we.Result = ret
+		}()
+	}
+
+	v, err := x.elect.Elect(ctx)
+	if err != nil || v == nil {
+		return true, nil
+	}
+	return cast.ToString(v) == "", nil
+}
+
+func parseExists(r *collectconfig.MExists) (XWhere, error) {
+	elect, err := parseElect(r.Elect)
+	if err != nil {
+		return nil, err
+	}
+	return &xExists{elect: elect}, nil
+}
+
+func parseIsEmpty(r *collectconfig.MIsEmpty) (XWhere, error) {
+	elect, err := parseElect(r.Elect)
+	if err != nil {
+		return nil, err
+	}
+	return &xIsEmpty{elect: elect}, nil
+}
@@ -0,0 +1,39 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package executor
+
+import "github.com/traas-stack/holoinsight-agent/pkg/collectconfig"
+
+type (
+	// xExpressionWhere tests whether the result of an expression is truthy.
+	// Evaluation errors are counted in ConsumerStat and treated as false.
+	xExpressionWhere struct {
+		x *xExpression
+	}
+)
+
+func (x *xExpressionWhere) Test(ctx *LogContext) (ret bool, _ error) {
+	we := ctx.whereEvent
+	if we != nil {
+		we.Name = "expression"
+		defer func() {
+			we.Result = ret
+		}()
+	}
+
+	obj, err := x.x.eval(ctx)
+	if err != nil {
+		return false, nil
+	}
+	return !obj.IsFalsy(), nil
+}
+
+func parseExpressionWhere(r *collectconfig.MExpression) (XWhere, error) {
+	x, err := compileExpression(r.Expression, r.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &xExpressionWhere{x: x}, nil
+}
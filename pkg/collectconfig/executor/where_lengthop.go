@@ -0,0 +1,101 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package executor
+
+import (
+	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig"
+	"unicode/utf8"
+)
+
+type (
+	// xLengthOp compares the length of the elected string, all non-nil conditions must be satisfied
+	xLengthOp struct {
+		elect XElect
+		conf  *collectconfig.MLengthOp
+	}
+	// xStringOp compares the elected string in lexicographical order, all non-nil conditions must be satisfied
+	xStringOp struct {
+		elect XElect
+		conf  *collectconfig.MStringOp
+	}
+)
+
+func (x *xLengthOp) Test(ctx *LogContext) (ret bool, _ error) {
+	we := ctx.whereEvent
+	if we != nil {
+		we.Name = "lengthOp"
+		defer func() {
+			we.Result = ret
+		}()
+	}
+
+	s, err := x.elect.ElectString(ctx)
+	if err != nil {
+		return false, err
+	}
+	n := utf8.RuneCountInString(s)
+	c := x.conf
+	if c.Eq != nil && !(n == *c.Eq) {
+		return false, nil
+	}
+	if c.Gt != nil && !(n > *c.Gt) {
+		return false, nil
+	}
+	if c.Gte != nil && !(n >= *c.Gte) {
+		return false, nil
+	}
+	if c.Lt != nil && !(n < *c.Lt) {
+		return false, nil
+	}
+	if c.Lte != nil && !(n <= *c.Lte) {
+		return false, nil
+	}
+	return true, nil
+}
+
+func (x *xStringOp) Test(ctx *LogContext) (ret bool, _ error) {
+	we := ctx.whereEvent
+	if we != nil {
+		we.Name = "stringOp"
+		defer func() {
+			we.Result = ret
+		}()
+	}
+
+	s, err := x.elect.ElectString(ctx)
+	if err != nil {
+		return false, err
+	}
+	c := x.conf
+	if c.Gt != nil && !(s > *c.Gt) {
+		return false, nil
+	}
+	if c.Gte != nil && !(s >= *c.Gte) {
+		return false, nil
+	}
+	if c.Lt != nil && !(s < *c.Lt) {
+		return false, nil
+	}
+	if c.Lte != nil && !(s <= *c.Lte) {
+		return false, nil
+	}
+	return true, nil
+}
+
+func parseLengthOp(op *collectconfig.MLengthOp) (XWhere, error) {
+	elect, err := parseElect(op.Elect)
+	if err != nil {
+		return nil, err
+	}
+	return &xLengthOp{elect: elect, conf: op}, nil
+}
+
+func parseStringOp(op *collectconfig.MStringOp) (XWhere, error) {
+	elect, err := parseElect(op.Elect)
+	if err != nil {
+		return nil, err
+	}
+	return &xStringOp{elect: elect, conf: op}, nil
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package executor
+
+import (
+	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig"
+	"strings"
+)
+
+type (
+	// xStartsWith tests prefixes or suffixes of the elected string
+	xStartsWith struct {
+		name       string
+		elect      XElect
+		values     []string
+		ignoreCase bool
+		match      func(s, value string) bool
+	}
+)
+
+func (x *xStartsWith) Test(ctx *LogContext) (ret bool, _ error) {
+	we := ctx.whereEvent
+	if we != nil {
+		we.Name = x.name
+		defer func() {
+			we.Result = ret
+		}()
+	}
+
+	s, err := x.elect.ElectString(ctx)
+	if err != nil {
+		return false, err
+	}
+	if x.ignoreCase {
+		s = strings.ToLower(s)
+	}
+	for _, value := range x.values {
+		if x.match(s, value) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func parseStartsWith(r *collectconfig.MStartsWith) (XWhere, error) {
+	return newStartsWith("startsWith", r.Elect, r.Values, r.IgnoreCase, strings.HasPrefix)
+}
+
+func parseEndsWith(r *collectconfig.MEndsWith) (XWhere, error) {
+	return newStartsWith("endsWith", r.Elect, r.Values, r.IgnoreCase, strings.HasSuffix)
+}
+
+func newStartsWith(name string, e *collectconfig.Elect, values []string, ignoreCase bool, match func(s, value string) bool) (XWhere, error) {
+	elect, err := parseElect(e)
+	if err != nil {
+		return nil, err
+	}
+	x := &xStartsWith{
+		name:       name,
+		elect:      elect,
+		values:     values,
+		ignoreCase: ignoreCase,
+		match:      match,
+	}
+	if ignoreCase {
+		x.values = make([]string, len(values))
+		for i, value := range values {
+			x.values[i] = strings.ToLower(value)
+		}
+	}
+	return x, nil
+}
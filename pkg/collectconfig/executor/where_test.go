@@ -0,0 +1,83 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package executor
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traas-stack/holoinsight-agent/pkg/collectconfig"
+	"testing"
+)
+
+func testWhere(t *testing.T, whereJson string, ctx *LogContext) bool {
+	w := &collectconfig.Where{}
+	require.NoError(t, json.Unmarshal([]byte(whereJson), w))
+	x, err := parseWhere(w)
+	require.NoError(t, err)
+	ret, err := x.Test(ctx)
+	require.NoError(t, err)
+	return ret
+}
+
+func TestWhere_richPredicates(t *testing.T) {
+	ctx := &LogContext{columnMap: map[string]interface{}{
+		"path":    "/api/Users/1",
+		"file":    "report.CSV",
+		"method":  "get",
+		"from":    "alice",
+		"to":      "Alice",
+		"empty":   "",
+		"null":    nil,
+		"version": "1.10.2",
+		"date":    "2023-07-22",
+		"name":    "霍洛",
+	}}
+
+	cases := []struct {
+		where    string
+		expected bool
+	}{
+		{`{"startsWith":{"elect":{"type":"refName","refName":{"name":"path"}},"values":["/health","/api/"]}}`, true},
+		{`{"startsWith":{"elect":{"type":"refName","refName":{"name":"path"}},"values":["/API/"]}}`, false},
+		{`{"startsWith":{"elect":{"type":"refName","refName":{"name":"path"}},"values":["/API/USERS"],"ignoreCase":true}}`, true},
+		{`{"endsWith":{"elect":{"type":"refName","refName":{"name":"file"}},"values":[".csv"],"ignoreCase":true}}`, true},
+		{`{"endsWith":{"elect":{"type":"refName","refName":{"name":"file"}},"values":[".csv"]}}`, false},
+		{`{"equals":{"elect":{"type":"refName","refName":{"name":"method"}},"value":"GET","ignoreCase":true}}`, true},
+		{`{"equals":{"elect":{"type":"refName","refName":{"name":"method"}},"value":"GET"}}`, false},
+		{`{"exists":{"elect":{"type":"refName","refName":{"name":"empty"}}}}`, true},
+		{`{"exists":{"elect":{"type":"refName","refName":{"name":"null"}}}}`, false},
+		{`{"exists":{"elect":{"type":"refName","refName":{"name":"missing"}}}}`, false},
+		{`{"exists":{"elect":{"type":"refName","refName":{"name":"$.missing.a"}}}}`, false},
+		{`{"isEmpty":{"elect":{"type":"refName","refName":{"name":"empty"}}}}`, true},
+		{`{"isEmpty":{"elect":{"type":"refName","refName":{"name":"missing"}}}}`, true},
+		{`{"isEmpty":{"elect":{"type":"refName","refName":{"name":"method"}}}}`, false},
+		{`{"fieldEquals":{"left":{"type":"refName","refName":{"name":"from"}},"right":{"type":"refName","refName":{"name":"to"}}}}`, false},
+		{`{"fieldEquals":{"left":{"type":"refName","refName":{"name":"from"}},"right":{"type":"refName","refName":{"name":"to"}},"ignoreCase":true}}`, true},
+		{`{"lengthOp":{"elect":{"type":"refName","refName":{"name":"name"}},"eq":2}}`, true},
+		{`{"lengthOp":{"elect":{"type":"refName","refName":{"name":"path"}},"gt":5,"lte":12}}`, true},
+		{`{"lengthOp":{"elect":{"type":"refName","refName":{"name":"path"}},"lt":12}}`, false},
+		{`{"stringOp":{"elect":{"type":"refName","refName":{"name":"date"}},"gte":"2023-07-01","lt":"2023-08-01"}}`, true},
+		{`{"stringOp":{"elect":{"type":"refName","refName":{"name":"date"}},"gt":"2023-07-22"}}`, false},
+		// lexicographical order, not version order
+		{`{"stringOp":{"elect":{"type":"refName","refName":{"name":"version"}},"gt":"1.9"}}`, false},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expected, testWhere(t, c.where, ctx), c.where)
+	}
+}
+
+func TestWhere_fillDefaultElect_fieldEquals(t *testing.T) {
+	w := &collectconfig.Where{FieldEquals: &collectconfig.MFieldEquals{
+		Left:  collectconfig.CElectContext,
+		Right: collectconfig.CElectContext,
+	}}
+	fillDefaultElect(w)
+	x, err := parseWhere(w)
+	require.NoError(t, err)
+	ret, err := x.Test(&LogContext{contextValue: "a"})
+	assert.NoError(t, err)
+	assert.True(t, ret)
+}
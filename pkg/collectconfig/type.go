@@ -14,6 +14,8 @@ const (
 	EElectPathVar   = "pathvar"
 	EElectContext   = "context"
 	EElectRefVar    = "refVar"
+	// EElectExpression elects the result of an expression
+	EElectExpression = "expression"
 )
 
 const (
@@ -51,6 +53,12 @@ type (
 		MaxCount int `json:"maxCount"`
 		// MaxCount max string length of every log sample
 		MaxLength int `json:"maxLength"`
+		// Strategy decides which log samples are kept when there are more than MaxCount samples in a window:
+		// first (default): keep the first MaxCount samples
+		// reservoir: keep MaxCount random samples across the window (reservoir sampling)
+		Strategy string `json:"strategy,omitempty"`
+		// Dedup merges log samples with identical raw lines and counts them
+		Dedup bool `json:"dedup,omitempty"`
 	}
 	SelectOne struct {
 		// TODO
@@ -138,12 +146,16 @@ type (
 		// /home/admin/logs/foo/{time:yy}/{time:MM}/{time:dd}/{time:HH}/foo.log
 		// used when type==glob
 		// used when type==regexp
+		// used when type==podlogs, it is a pattern of base names of files under emptyDir/hostPath volumes, defaults to '*.log'
 		Pattern string `json:"pattern"`
 		// used when type==regexp
 		Dir string `json:"dir"`
 		// Limit how many files can be matched using this FromLogPath object.
 		// 0 means agent defaults (maybe 10)
 		MaxMatched int `json:"maxMatched"`
+		// used when type==stdout
+		// K8s container name of the pod, empty means the main biz container
+		Container string `json:"container"`
 	}
 	// TODO 遵循 logstash 风格
 	FromLogMultiline struct {
@@ -155,6 +167,15 @@ type (
 		// limit max logs in a log group
 		MaxLines int    `json:"maxLines"`
 		What     string `json:"what"`
+		// Mode is the way to group lines, defaults to 'where' which uses Where and What.
+		// Built-in modes which ignore Where and What:
+		// indent: lines starting with whitespaces belong to the previous line
+		// backslash: lines ending with '\' are continued by the next line
+		// java/python/go: stack traces of java exceptions, python tracebacks and go panics belong to the previous line
+		Mode string `json:"mode,omitempty"`
+		// Timeout is max time to wait for more lines of the pending log group when reaching the file end, e.g. '3s'.
+		// The pending log group is flushed immediately when reaching the file end if Timeout is empty.
+		Timeout string `json:"timeout,omitempty"`
 	}
 	FromLogParse struct {
 		// 有的parse代价太大, 可以在parse前做一次过滤减少parse的量
@@ -206,11 +227,21 @@ type (
 		Regexp    *ElectRegexp  `json:"regexp,omitempty"`
 		RefMeta   *ElectRegMeta `json:"refMeta,omitempty"`
 		PathVar   *ElectPathVar `json:"pathVar,omitempty"`
+		// Expression is used when Type is 'expression'
+		Expression *ElectExpression `json:"expression,omitempty"`
 
 		// Every elect can have its own transform, named adhoc transform.
 		// It will be executed everytime elect is called.
 		Transform *TransformConf `json:"transform,omitempty"`
 	}
+	// ElectExpression is an expression of tengo language: https://github.com/d5/tengo
+	// Available variables: line, lines, columns, vars, tags, path, target (pod meta) and value (context value).
+	// Stdlib modules math, text, times, json, base64, hex and enum are imported implicitly.
+	ElectExpression struct {
+		Expression string `json:"expression" yaml:"expression"`
+		// Timeout is the time budget of one evaluation, defaults to 10ms
+		Timeout string `json:"timeout,omitempty" yaml:"timeout"`
+	}
 	ElectPathVar struct {
 		Name string `json:"name"`
 	}
@@ -264,6 +295,16 @@ type (
 		NumberBetween *MNumberBetween `json:"numberBetween,omitempty" yaml:"numberBetween"`
 		Regexp        *MRegexp        `json:"regexp,omitempty" yaml:"regexp"`
 		NumberOp      *MNumberOp      `json:"numberOp,omitempty" yaml:"numberOp"`
+		Cidr          *MCidr          `json:"cidr,omitempty" yaml:"cidr"`
+		StartsWith    *MStartsWith    `json:"startsWith,omitempty" yaml:"startsWith"`
+		EndsWith      *MEndsWith      `json:"endsWith,omitempty" yaml:"endsWith"`
+		Equals        *MEquals        `json:"equals,omitempty" yaml:"equals"`
+		Exists        *MExists        `json:"exists,omitempty" yaml:"exists"`
+		IsEmpty       *MIsEmpty       `json:"isEmpty,omitempty" yaml:"isEmpty"`
+		FieldEquals   *MFieldEquals   `json:"fieldEquals,omitempty" yaml:"fieldEquals"`
+		LengthOp      *MLengthOp      `json:"lengthOp,omitempty" yaml:"lengthOp"`
+		StringOp      *MStringOp      `json:"stringOp,omitempty" yaml:"stringOp"`
+		Expression    *MExpression    `json:"expression,omitempty" yaml:"expression"`
 	}
 	MNumberOp struct {
 		Elect *Elect   `json:"elect" yaml:"elect"`
@@ -308,6 +349,71 @@ type (
 		Values     []string `json:"values" yaml:"values"`
 		IgnoreCase bool     `json:"ignoreCase" yaml:"ignoreCase"`
 	}
+	// MStartsWith tests whether the elected string starts with any of Values
+	MStartsWith struct {
+		Elect      *Elect   `json:"elect" yaml:"elect"`
+		Values     []string `json:"values" yaml:"values"`
+		IgnoreCase bool     `json:"ignoreCase" yaml:"ignoreCase"`
+	}
+	// MEndsWith tests whether the elected string ends with any of Values
+	MEndsWith struct {
+		Elect      *Elect   `json:"elect" yaml:"elect"`
+		Values     []string `json:"values" yaml:"values"`
+		IgnoreCase bool     `json:"ignoreCase" yaml:"ignoreCase"`
+	}
+	// MEquals tests whether the elected string equals to Value
+	MEquals struct {
+		Elect      *Elect `json:"elect" yaml:"elect"`
+		Value      string `json:"value" yaml:"value"`
+		IgnoreCase bool   `json:"ignoreCase" yaml:"ignoreCase"`
+	}
+	// MExists tests whether the elected field exists, it is mainly used for JSON fields
+	MExists struct {
+		Elect *Elect `json:"elect" yaml:"elect"`
+	}
+	// MIsEmpty tests whether the elected field is missing or an empty string
+	MIsEmpty struct {
+		Elect *Elect `json:"elect" yaml:"elect"`
+	}
+	// MFieldEquals tests whether two elected strings are equal
+	MFieldEquals struct {
+		Left       *Elect `json:"left" yaml:"left"`
+		Right      *Elect `json:"right" yaml:"right"`
+		IgnoreCase bool   `json:"ignoreCase" yaml:"ignoreCase"`
+	}
+	// MLengthOp compares the length (in characters) of the elected string
+	MLengthOp struct {
+		Elect *Elect `json:"elect" yaml:"elect"`
+		Eq    *int   `json:"eq" yaml:"eq"`
+		Gt    *int   `json:"gt" yaml:"gt"`
+		Gte   *int   `json:"gte" yaml:"gte"`
+		Lt    *int   `json:"lt" yaml:"lt"`
+		Lte   *int   `json:"lte" yaml:"lte"`
+	}
+	// MStringOp compares the elected string in lexicographical order, e.g. tests whether a 'yyyy-MM-dd' date is in a range.
+	// It is not suitable for versions, because '1.10' < '1.9' in lexicographical order.
+	MStringOp struct {
+		Elect *Elect  `json:"elect" yaml:"elect"`
+		Gt    *string `json:"gt" yaml:"gt"`
+		Gte   *string `json:"gte" yaml:"gte"`
+		Lt    *string `json:"lt" yaml:"lt"`
+		Lte   *string `json:"lte" yaml:"lte"`
+	}
+	// MExpression tests whether the result of an expression is truthy, see ElectExpression for details of expressions
+	MExpression struct {
+		Expression string `json:"expression" yaml:"expression"`
+		Timeout    string `json:"timeout,omitempty" yaml:"timeout"`
+	}
+	// MCidr tests whether an ip belongs to any of CIDRs
+	MCidr struct {
+		Elect *Elect `json:"elect" yaml:"elect"`
+		// Values are CIDRs or plain ips, e.g. '10.0.0.0/8'
+		Values []string `json:"values" yaml:"values"`
+		// Private matches private, loopback and link-local ips
+		Private bool `json:"private" yaml:"private"`
+		// Zones matches ips whose zone labels (configured per agent) are in Zones
+		Zones []string `json:"zones" yaml:"zones"`
+	}
 	ExecuteRule struct {
 		Type string `json:"type" yaml:"type"`
 		// 5s 5000单位毫秒
@@ -331,6 +437,9 @@ type (
 	Details struct {
 		// If Enabled is true, the elect results will be reported as details
 		Enabled bool `json:"enabled"`
+		// MaxBytesPerMinute is the byte budget of details output per minute, rows exceeding the budget are dropped.
+		// Defaults to 8MB.
+		MaxBytesPerMinute int `json:"maxBytesPerMinute,omitempty"`
 	}
 )
 
@@ -4,6 +4,11 @@
 
 package collectconfig
 
+const (
+	LogAnalysisAlgorithmSimilarity = "similarity"
+	LogAnalysisAlgorithmDrain      = "drain"
+)
+
 type (
 	LogAnalysisConf struct {
 		// patterns to match, will be visited in order, break when first match
@@ -12,6 +17,31 @@ type (
 		MaxUnknownPatterns int `json:"maxUnknownPatterns"`
 		// truncate log if length(bytes) exceed MaxLogLength, defaults to 300
 		MaxLogLength int `json:"maxLogLength"`
+		// Algorithm used to analyze unknown logs: 'similarity'(default) or 'drain'
+		Algorithm string `json:"algorithm"`
+		// Drain options, used when Algorithm is 'drain'
+		Drain *LogAnalysisDrainConf `json:"drain"`
+		// NewPatterns detects patterns never seen before
+		NewPatterns *LogAnalysisNewPatternsConf `json:"newPatterns"`
+	}
+	LogAnalysisDrainConf struct {
+		// depth of parse tree, defaults to 4
+		Depth int `json:"depth"`
+		// min similarity of a log and a template to be merged, defaults to 0.4
+		SimilarityThreshold float64 `json:"similarityThreshold"`
+		// max children of an internal node of parse tree, defaults to 100
+		MaxChildren int `json:"maxChildren"`
+		// max templates kept in memory, defaults to 512
+		MaxTemplates int `json:"maxTemplates"`
+	}
+	LogAnalysisNewPatternsConf struct {
+		Enabled bool `json:"enabled"`
+		// A pattern is new if it has not been seen within horizon, defaults to '24h'
+		Horizon string `json:"horizon"`
+		// Patterns seen during warmup after start are not reported as new, defaults to '10m'
+		Warmup string `json:"warmup"`
+		// max new pattern events reported per time window, defaults to 10
+		MaxEvents int `json:"maxEvents"`
 	}
 	LogAnalysisPatternConf struct {
 		// pattern name
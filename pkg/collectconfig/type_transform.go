@@ -21,6 +21,15 @@ type (
 		RegexpReplaceV1 *TransformFilterRegexpReplaceV1 `json:"regexpReplaceV1" yaml:"regexpReplaceV1"`
 		DiscardV1       *struct{}                       `json:"discardV1" yaml:"discardV1"`
 		CleanUrlV1      *TransformFilterCleanUrlV1      `json:"cleanUrlV1" yaml:"cleanUrlV1"`
+		MaskV1          *TransformFilterMaskV1          `json:"maskV1" yaml:"maskV1"`
+		UserAgentV1     *TransformFilterUserAgentV1     `json:"userAgentV1" yaml:"userAgentV1"`
+		UrlPartV1       *TransformFilterUrlPartV1       `json:"urlPartV1" yaml:"urlPartV1"`
+		IPLookupV1      *TransformFilterIPLookupV1      `json:"ipLookupV1" yaml:"ipLookupV1"`
+		DurationV1      *TransformFilterDurationV1      `json:"durationV1" yaml:"durationV1"`
+		ByteSizeV1      *TransformFilterByteSizeV1      `json:"byteSizeV1" yaml:"byteSizeV1"`
+		MathV1          *TransformFilterMathV1          `json:"mathV1" yaml:"mathV1"`
+		TimestampV1     *TransformFilterTimestampV1     `json:"timestampV1" yaml:"timestampV1"`
+		ExpressionV1    *TransformFilterExpressionV1    `json:"expressionV1" yaml:"expressionV1"`
 	}
 	// TransformFilterAppendV1 represents appending suffix to the current value
 	TransformFilterAppendV1 struct {
@@ -72,4 +81,88 @@ type (
 	}
 	TransformFilterCleanUrlV1 struct {
 	}
+	// TransformFilterMaskV1 represents masking sensitive data of the current value
+	TransformFilterMaskV1 struct {
+		// Detectors are names of built-in detectors: email/phone/idCard/creditCard/ipv4/ipv6/jwt/bearer.
+		// Empty Detectors and empty Rules means using all built-in detectors.
+		Detectors []string `json:"detectors,omitempty" yaml:"detectors"`
+		// Rules are custom masking rules, they are applied after detectors
+		Rules []*TransformFilterMaskV1Rule `json:"rules,omitempty" yaml:"rules"`
+	}
+	// TransformFilterMaskV1Rule represents a custom masking rule
+	TransformFilterMaskV1Rule struct {
+		Name       string `json:"name,omitempty" yaml:"name"`
+		Expression string `json:"expression" yaml:"expression"`
+		// Group is the index of capture group to mask, 0 means the whole match
+		Group int `json:"group,omitempty" yaml:"group"`
+		// KeepPrefix is the count of leading chars of masked part to keep
+		KeepPrefix int `json:"keepPrefix,omitempty" yaml:"keepPrefix"`
+		// KeepSuffix is the count of trailing chars of masked part to keep, e.g. 4 means keep-last-4
+		KeepSuffix int `json:"keepSuffix,omitempty" yaml:"keepSuffix"`
+		// MaskChar defaults to '*'
+		MaskChar string `json:"maskChar,omitempty" yaml:"maskChar"`
+	}
+	// TransformFilterUserAgentV1 represents extracting a field from the current value which is a User-Agent string
+	TransformFilterUserAgentV1 struct {
+		// Field is one of browser/browserVersion/browserMajor/os/osVersion/device, defaults to browser
+		Field string `json:"field,omitempty" yaml:"field"`
+	}
+	// TransformFilterUrlPartV1 represents extracting a part from the current value which is a URL or a request uri
+	TransformFilterUrlPartV1 struct {
+		// Part is one of scheme/host/port/path/query/param/segment
+		Part string `json:"part" yaml:"part"`
+		// Param is the name of query param, used when Part is 'param'
+		Param string `json:"param,omitempty" yaml:"param"`
+		// Index is the index of path segment, used when Part is 'segment'. Negative index counts from the end, -1 means the last segment.
+		Index int `json:"index,omitempty" yaml:"index"`
+		// DefaultValue is used when the part is missing
+		DefaultValue string `json:"defaultValue,omitempty" yaml:"defaultValue"`
+	}
+	// TransformFilterIPLookupV1 represents looking up the current value which is an ip against local databases configured per agent
+	TransformFilterIPLookupV1 struct {
+		// Field is one of country/countryName/city/asn/asnOrg/zone, defaults to country
+		Field string `json:"field,omitempty" yaml:"field"`
+		// DefaultValue is used when the ip is invalid or not found
+		DefaultValue string `json:"defaultValue,omitempty" yaml:"defaultValue"`
+	}
+	// TransformFilterDurationV1 represents parsing the current value which is a duration (e.g. '12ms' '1.2s' '350us') into a number in Unit
+	TransformFilterDurationV1 struct {
+		// Unit is the target unit: ns/us/ms/s/m/h/d, defaults to ms
+		Unit string `json:"unit,omitempty" yaml:"unit"`
+		// DefaultUnit is the unit of values without unit suffix, defaults to Unit
+		DefaultUnit string `json:"defaultUnit,omitempty" yaml:"defaultUnit"`
+	}
+	// TransformFilterByteSizeV1 represents parsing the current value which is a byte size (e.g. '10KB' '2MiB') into a number in Unit.
+	// All units are powers of 1024 like JVM, nginx and Docker: KB, KiB, Ki and K are the same.
+	// Unit prefixes are case-insensitive, but bits such as 'Mb' and 'kb' are not supported.
+	TransformFilterByteSizeV1 struct {
+		// Unit is the target unit, defaults to B
+		Unit string `json:"unit,omitempty" yaml:"unit"`
+		// DefaultUnit is the unit of values without unit suffix, defaults to Unit
+		DefaultUnit string `json:"defaultUnit,omitempty" yaml:"defaultUnit"`
+	}
+	// TransformFilterMathV1 represents arithmetic on the current value, steps are executed in order: multiply, divide, add, round
+	TransformFilterMathV1 struct {
+		Multiply *float64 `json:"multiply,omitempty" yaml:"multiply"`
+		Divide   *float64 `json:"divide,omitempty" yaml:"divide"`
+		Add      *float64 `json:"add,omitempty" yaml:"add"`
+		// Round rounds the result to Round decimal places
+		Round *int `json:"round,omitempty" yaml:"round"`
+	}
+	// TransformFilterTimestampV1 represents converting the current value which is a timestamp from one format to another
+	TransformFilterTimestampV1 struct {
+		// FromFormat is one of auto/unix/unixMilli/unixMicro/unixNano/golangLayout, defaults to auto
+		FromFormat string `json:"fromFormat,omitempty" yaml:"fromFormat"`
+		FromLayout string `json:"fromLayout,omitempty" yaml:"fromLayout"`
+		// ToFormat is one of unix/unixMilli/unixMicro/unixNano/golangLayout, defaults to unixMilli
+		ToFormat string `json:"toFormat,omitempty" yaml:"toFormat"`
+		ToLayout string `json:"toLayout,omitempty" yaml:"toLayout"`
+		// Timezone is used when parsing or formatting layouts, defaults to timezone of the target, or local timezone if unknown
+		Timezone string `json:"timezone,omitempty" yaml:"timezone"`
+	}
+	// TransformFilterExpressionV1 represents replacing the current value with the result of an expression, see ElectExpression for details of expressions
+	TransformFilterExpressionV1 struct {
+		Expression string `json:"expression" yaml:"expression"`
+		Timeout    string `json:"timeout,omitempty" yaml:"timeout"`
+	}
 )
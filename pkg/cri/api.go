@@ -147,4 +147,6 @@ const (
 	Rund = "rund"
 
 	ContainerEngineFeatureCopy ContainerEngineFeature = iota
+	// ContainerEngineFeatureExecInput means that ExecRequest.Input can be streamed to stdin of exec process
+	ContainerEngineFeatureExecInput
 )
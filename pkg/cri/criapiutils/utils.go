@@ -0,0 +1,51 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package criapiutils
+
+import (
+	"context"
+	"errors"
+	"github.com/traas-stack/holoinsight-agent/pkg/core"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+	"time"
+)
+
+// Well known sock addresses of CRI runtimes, containerd also implements CRI API.
+const (
+	CrioDefaultAddress  = "/var/run/crio/crio.sock"
+	CrioDefaultAddress2 = "/run/crio/crio.sock"
+)
+
+// NewClientFromEnv create a CRI runtime service client based on the agreed environment configuration information.
+func NewClientFromEnv(addrs ...string) (runtimeapi.RuntimeServiceClient, *grpc.ClientConn, *runtimeapi.VersionResponse, error) {
+	defaultAddrs := make([]string, 0, len(addrs)+2)
+	defaultAddrs = append(defaultAddrs, addrs...)
+	defaultAddrs = append(defaultAddrs, CrioDefaultAddress, CrioDefaultAddress2)
+
+	addr := core.FindFirstSockInHostfs("CRI_SOCK", defaultAddrs...)
+	if addr == "" {
+		return nil, nil, nil, errors.New("no cri sock")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, "unix://"+addr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithBlock())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	client := runtimeapi.NewRuntimeServiceClient(conn)
+	versionResp, err := client.Version(ctx, &runtimeapi.VersionRequest{})
+	if err != nil {
+		conn.Close()
+		return nil, nil, nil, err
+	}
+	return client, conn, versionResp, nil
+}
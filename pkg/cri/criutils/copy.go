@@ -211,6 +211,10 @@ func CopyFromContainerByMount(ctx context.Context, i cri.Interface, c *cri.Conta
 
 // CopyToContainerByTar copies file to container using tar
 func CopyToContainerByTar(ctx context.Context, i cri.Interface, c *cri.Container, srcPath, dstPath string) (retErr error) {
+	// tar reads file from stdin
+	if !i.Engine().Supports(cri.ContainerEngineFeatureExecInput) {
+		return ErrUnsupported
+	}
 	if l, ok := c.Attributes.Load(copyToContainerByTarDisabled); ok && l.(bool) {
 		return ErrDisabled
 	}
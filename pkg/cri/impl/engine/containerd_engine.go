@@ -382,6 +382,8 @@ func (e *ContainerdContainerEngine) Supports(feature cri.ContainerEngineFeature)
 	switch feature {
 	case cri.ContainerEngineFeatureCopy:
 		return false
+	case cri.ContainerEngineFeatureExecInput:
+		return true
 	default:
 		return false
 	}
@@ -0,0 +1,171 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package engine
+
+import (
+	"context"
+	apievents "github.com/containerd/containerd/api/events"
+	"github.com/containerd/containerd/events"
+	"github.com/containerd/typeurl"
+	"github.com/traas-stack/holoinsight-agent/pkg/cri"
+	"github.com/traas-stack/holoinsight-agent/pkg/logger"
+	"github.com/traas-stack/holoinsight-agent/pkg/util"
+	"go.uber.org/zap"
+	"time"
+)
+
+const (
+	containerdTopicTaskOOM  = "/tasks/oom"
+	containerdTopicTaskExit = "/tasks/exit"
+)
+
+type (
+	// ContainerdEventSubscriber subscribes containerd events, it is implemented by *containerd.Client
+	ContainerdEventSubscriber interface {
+		Subscribe(ctx context.Context, filters ...string) (<-chan *events.Envelope, <-chan error)
+	}
+	// ContainerdOOMManager is the containerd version of OOMManager.
+	// It listens to task OOM and exit events of containerd, and emits the same OOM metrics as OOMManager.
+	// Container exits with non-zero exit code are recorded as abnormal exits.
+	ContainerdOOMManager struct {
+		CRI         cri.Interface
+		Subscriber  ContainerdEventSubscriber
+		oomRecoder  *oomRecoder
+		exitRecoder *oomRecoder
+		stopCh      chan struct{}
+	}
+)
+
+func NewContainerdOOMManager(i cri.Interface, subscriber ContainerdEventSubscriber) *ContainerdOOMManager {
+	return &ContainerdOOMManager{
+		CRI:         i,
+		Subscriber:  subscriber,
+		oomRecoder:  newOOMRecorder(),
+		exitRecoder: newOOMRecorder(),
+		stopCh:      make(chan struct{}),
+	}
+}
+
+func (m *ContainerdOOMManager) Start() {
+	go m.listenContainerdLoop()
+	go m.emitLoop()
+}
+
+func (m *ContainerdOOMManager) Stop() {
+	close(m.stopCh)
+}
+
+func (m *ContainerdOOMManager) isStopped() bool {
+	select {
+	case <-m.stopCh:
+		return true
+	default:
+		return false
+	}
+}
+
+func (m *ContainerdOOMManager) listenContainerdLoop() {
+	// Filters are ORed, conditions in a filter are ANDed
+	filters := []string{
+		`topic=="` + containerdTopicTaskOOM + `",namespace=="` + k8sNs + `"`,
+		`topic=="` + containerdTopicTaskExit + `",namespace=="` + k8sNs + `"`,
+	}
+
+	for {
+		if m.isStopped() {
+			return
+		}
+
+		func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			logger.Criz("[digest] listen to containerd events")
+			envelopeCh, errCh := m.Subscriber.Subscribe(ctx, filters...)
+			for {
+				select {
+				case envelope, ok := <-envelopeCh:
+					if !ok {
+						time.Sleep(time.Second)
+						return
+					}
+					m.handleEnvelope(envelope)
+				case err := <-errCh:
+					logger.Metaz("[event] containerd error", zap.Error(err))
+					// slow down
+					time.Sleep(time.Second)
+					return
+				case <-m.stopCh:
+					return
+				}
+			}
+		}()
+	}
+}
+
+func (m *ContainerdOOMManager) handleEnvelope(envelope *events.Envelope) {
+	if envelope == nil || envelope.Event == nil || envelope.Namespace != k8sNs {
+		return
+	}
+	v, err := typeurl.UnmarshalAny(envelope.Event)
+	if err != nil {
+		logger.Metaz("[event] unmarshal containerd event error", zap.String("topic", envelope.Topic), zap.Error(err))
+		return
+	}
+
+	switch e := v.(type) {
+	case *apievents.TaskOOM:
+		logger.Criz("[event]", zap.String("cid", e.ContainerID), zap.String("topic", envelope.Topic))
+		if ctr, ok := m.getContainer(e.ContainerID); ok {
+			logger.Metaz("[oom]",
+				zap.String("ns", ctr.Pod.Namespace),
+				zap.String("pod", ctr.Pod.Name),
+				zap.String("container", ctr.K8sContainerName))
+			m.oomRecoder.add(ctr)
+		}
+	case *apievents.TaskExit:
+		// Exits of exec processes have different ids from their containers
+		if e.ID != e.ContainerID || e.ExitStatus == 0 {
+			return
+		}
+		logger.Criz("[event]", zap.String("cid", e.ContainerID), zap.String("topic", envelope.Topic), zap.Uint32("exitStatus", e.ExitStatus))
+		if ctr, ok := m.getContainer(e.ContainerID); ok {
+			logger.Metaz("[exit]",
+				zap.String("ns", ctr.Pod.Namespace),
+				zap.String("pod", ctr.Pod.Name),
+				zap.String("container", ctr.K8sContainerName),
+				zap.Uint32("exitStatus", e.ExitStatus))
+			m.exitRecoder.add(ctr)
+		}
+	}
+}
+
+func (m *ContainerdOOMManager) getContainer(cid string) (*cri.Container, bool) {
+	ctr, ok := m.CRI.GetContainerByCid(cid)
+	if !ok || ctr.IsSandbox() {
+		// When oom, container and its sandbox all emit oom
+		return nil, false
+	}
+	return ctr, true
+}
+
+func (m *ContainerdOOMManager) emitLoop() {
+	timer, emitTime := util.NewAlignedTimer(time.Minute, 2*time.Second, true, false)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			m.emitMetrics(emitTime)
+			emitTime = timer.Next()
+		case <-m.stopCh:
+			return
+		}
+	}
+}
+
+func (m *ContainerdOOMManager) emitMetrics(emitTime time.Time) {
+	metrics := buildContainerCountMetrics("k8s_pod_oom", m.CRI, m.oomRecoder.getAndClear(), emitTime)
+	metrics = append(metrics, buildContainerCountMetrics("k8s_pod_abnormal_exit", m.CRI, m.exitRecoder.getAndClear(), emitTime)...)
+	writeMetrics("[oom]", metrics)
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package engine
+
+import (
+	"context"
+	apievents "github.com/containerd/containerd/api/events"
+	"github.com/containerd/containerd/events"
+	"github.com/containerd/typeurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/traas-stack/holoinsight-agent/pkg/cri"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+	"time"
+)
+
+type (
+	fakeSubscriber struct {
+		envelopes chan *events.Envelope
+		errs      chan error
+	}
+	fakeCri struct {
+		cri.Interface
+		pod        *cri.Pod
+		containers map[string]*cri.Container
+	}
+)
+
+func (s *fakeSubscriber) Subscribe(ctx context.Context, filters ...string) (<-chan *events.Envelope, <-chan error) {
+	return s.envelopes, s.errs
+}
+
+func (f *fakeCri) GetContainerByCid(cid string) (*cri.Container, bool) {
+	c, ok := f.containers[cid]
+	return c, ok
+}
+
+func (f *fakeCri) GetAllPods() []*cri.Pod {
+	return []*cri.Pod{f.pod}
+}
+
+func newFakeCri() *fakeCri {
+	pod := &cri.Pod{Pod: &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo", UID: "uid1"}}}
+	return &fakeCri{
+		pod: pod,
+		containers: map[string]*cri.Container{
+			"app":     {Id: "app", Pod: pod, K8sContainerName: "app", ContainerRole: cri.ContainerRoleBiz},
+			"sandbox": {Id: "sandbox", Pod: pod, ContainerRole: cri.ContainerRoleSandbox},
+		},
+	}
+}
+
+func newEnvelope(t *testing.T, topic string, event interface{}) *events.Envelope {
+	any, err := typeurl.MarshalAny(event)
+	assert.NoError(t, err)
+	return &events.Envelope{Timestamp: time.Now(), Namespace: k8sNs, Topic: topic, Event: any}
+}
+
+func TestContainerdOOMManager(t *testing.T) {
+	subscriber := &fakeSubscriber{
+		envelopes: make(chan *events.Envelope),
+		errs:      make(chan error),
+	}
+	m := NewContainerdOOMManager(newFakeCri(), subscriber)
+	go m.listenContainerdLoop()
+	defer m.Stop()
+
+	for _, e := range []*events.Envelope{
+		newEnvelope(t, containerdTopicTaskOOM, &apievents.TaskOOM{ContainerID: "sandbox"}),
+		newEnvelope(t, containerdTopicTaskOOM, &apievents.TaskOOM{ContainerID: "app"}),
+		newEnvelope(t, containerdTopicTaskExit, &apievents.TaskExit{ContainerID: "app", ID: "app", ExitStatus: 137}),
+		// exec process
+		newEnvelope(t, containerdTopicTaskExit, &apievents.TaskExit{ContainerID: "app", ID: "exec1", ExitStatus: 1}),
+		newEnvelope(t, containerdTopicTaskExit, &apievents.TaskExit{ContainerID: "app", ID: "app", ExitStatus: 0}),
+		// unknown container
+		newEnvelope(t, containerdTopicTaskOOM, &apievents.TaskOOM{ContainerID: "other"}),
+	} {
+		subscriber.envelopes <- e
+	}
+	// an unbuffered send returns once the previous envelope has been handled
+	subscriber.envelopes <- &events.Envelope{}
+
+	oom := m.oomRecoder.getAndClear()
+	assert.Len(t, oom, 1)
+	assert.Equal(t, 1, oom["app"].count)
+	exits := m.exitRecoder.getAndClear()
+	assert.Len(t, exits, 1)
+	assert.Equal(t, 1, exits["app"].count)
+
+	m.oomRecoder.add(m.CRI.(*fakeCri).containers["app"])
+	metrics := buildContainerCountMetrics("k8s_pod_oom", m.CRI, m.oomRecoder.getAndClear(), time.Now())
+	assert.Len(t, metrics, 1)
+	assert.Equal(t, "app", metrics[0].Tags["container"])
+	assert.Equal(t, float64(1), metrics[0].Value)
+
+	metrics = buildContainerCountMetrics("k8s_pod_oom", m.CRI, m.oomRecoder.getAndClear(), time.Now())
+	assert.Len(t, metrics, 1)
+	assert.Equal(t, "-", metrics[0].Tags["container"])
+	assert.Equal(t, float64(0), metrics[0].Value)
+}
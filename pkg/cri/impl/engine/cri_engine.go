@@ -0,0 +1,354 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/traas-stack/holoinsight-agent/pkg/cri"
+	"github.com/traas-stack/holoinsight-agent/pkg/logger"
+	"go.uber.org/zap"
+	"io"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxCriExecInputSize is the max size of ExecRequest.Input, since input is passed as a command argument
+	maxCriExecInputSize = 64 * 1024
+	// criDefaultExecTimeout is used when ctx has no deadline
+	criDefaultExecTimeout    = time.Minute
+	crioMountPointAnnotation = "io.kubernetes.cri-o.MountPoint"
+)
+
+type (
+	// CriContainerEngine is a generic container engine built on the k8s CRI runtime service gRPC API.
+	// It is used for runtimes without a dedicated engine, such as CRI-O.
+	// CRI API has no stdin streaming and no copy, Exec only supports small inputs by passing them as arguments.
+	CriContainerEngine struct {
+		Client runtimeapi.RuntimeServiceClient
+		// RuntimeName is the name reported by CRI Version API, such as 'cri-o'
+		RuntimeName string
+		mutex       sync.RWMutex
+		// sandboxIds contains sandbox ids of last ListAllContainers, CRI API has different status APIs for sandboxes and containers
+		sandboxIds map[string]struct{}
+	}
+	// criVerboseInfo is the verbose info of container or sandbox status, both CRI-O and containerd fill these fields
+	criVerboseInfo struct {
+		Pid         int         `json:"pid"`
+		SandboxID   string      `json:"sandboxID"`
+		RuntimeSpec *specs.Spec `json:"runtimeSpec"`
+	}
+)
+
+var (
+	// Make sure *CriContainerEngine impl cri.ContainerEngine
+	_ cri.ContainerEngine = &CriContainerEngine{}
+
+	errCriExecInputTooLarge = errors.New("exec input is too large for cri engine")
+)
+
+func (e *CriContainerEngine) Init() error {
+	e.sandboxIds = make(map[string]struct{})
+	return nil
+}
+
+func (e *CriContainerEngine) Type() string {
+	if e.RuntimeName != "" {
+		return e.RuntimeName
+	}
+	return "cri"
+}
+
+func (e *CriContainerEngine) ListAllContainers(ctx context.Context) ([]*cri.EngineSimpleContainer, error) {
+	sandboxResp, err := e.Client.ListPodSandbox(ctx, &runtimeapi.ListPodSandboxRequest{})
+	if err != nil {
+		return nil, err
+	}
+	containerResp, err := e.Client.ListContainers(ctx, &runtimeapi.ListContainersRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	sandboxIds := make(map[string]struct{}, len(sandboxResp.Items))
+	items := make([]*cri.EngineSimpleContainer, 0, len(sandboxResp.Items)+len(containerResp.Containers))
+	for _, sandbox := range sandboxResp.Items {
+		sandboxIds[sandbox.Id] = struct{}{}
+		items = append(items, &cri.EngineSimpleContainer{
+			ID:     sandbox.Id,
+			Labels: sandbox.Labels,
+			Source: sandbox,
+		})
+	}
+	for _, container := range containerResp.Containers {
+		items = append(items, &cri.EngineSimpleContainer{
+			ID:     container.Id,
+			Labels: container.Labels,
+			Source: container,
+		})
+	}
+
+	e.mutex.Lock()
+	e.sandboxIds = sandboxIds
+	e.mutex.Unlock()
+	return items, nil
+}
+
+func (e *CriContainerEngine) isSandbox(cid string) bool {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	_, ok := e.sandboxIds[cid]
+	return ok
+}
+
+func parseCriVerboseInfo(info map[string]string) *criVerboseInfo {
+	ret := &criVerboseInfo{}
+	if s, ok := info["info"]; ok {
+		if err := json.Unmarshal([]byte(s), ret); err != nil {
+			logger.Debugz("[cri] parse verbose info error", zap.Error(err))
+		}
+	}
+	return ret
+}
+
+func (e *CriContainerEngine) GetContainerDetail(ctx context.Context, cid string) (*cri.EngineDetailContainer, error) {
+	if e.isSandbox(cid) {
+		return e.getSandboxDetail(ctx, cid)
+	}
+
+	resp, err := e.Client.ContainerStatus(ctx, &runtimeapi.ContainerStatusRequest{ContainerId: cid, Verbose: true})
+	if err != nil {
+		return nil, err
+	}
+	status := resp.Status
+	if status == nil {
+		return nil, fmt.Errorf("no status for container %s", cid)
+	}
+	info := parseCriVerboseInfo(resp.Info)
+
+	detail := &cri.EngineDetailContainer{
+		ID:        status.Id,
+		Labels:    status.Labels,
+		Source:    status,
+		SandboxId: info.SandboxID,
+		LogPath:   status.LogPath,
+		Runtime:   "unknown",
+		State: cri.ContainerState{
+			Pid:    info.Pid,
+			Status: convertCriContainerState(status.State),
+		},
+	}
+	if status.Metadata != nil {
+		detail.Name = status.Metadata.Name
+	}
+
+	if spec := info.RuntimeSpec; spec != nil {
+		if spec.Process != nil {
+			detail.Env = spec.Process.Env
+		}
+		detail.Hostname = spec.Hostname
+		// CRI-O records overlay merged dir of container rootfs in annotations.
+		// The rootfs is visible from host for runc and crun, so it can be treated as runc.
+		if mergedDir := spec.Annotations[crioMountPointAnnotation]; mergedDir != "" {
+			detail.MergedDir = mergedDir
+			detail.Runtime = cri.Runc
+		}
+		if detail.SandboxId == "" {
+			detail.SandboxId = spec.Annotations["io.kubernetes.cri-o.SandboxID"]
+		}
+	}
+
+	for _, mount := range status.Mounts {
+		detail.Mounts = append(detail.Mounts, &cri.MountPoint{
+			Source:      mount.HostPath,
+			Destination: mount.ContainerPath,
+			RW:          !mount.Readonly,
+		})
+	}
+
+	return detail, nil
+}
+
+func (e *CriContainerEngine) getSandboxDetail(ctx context.Context, cid string) (*cri.EngineDetailContainer, error) {
+	resp, err := e.Client.PodSandboxStatus(ctx, &runtimeapi.PodSandboxStatusRequest{PodSandboxId: cid, Verbose: true})
+	if err != nil {
+		return nil, err
+	}
+	status := resp.Status
+	if status == nil {
+		return nil, fmt.Errorf("no status for sandbox %s", cid)
+	}
+	info := parseCriVerboseInfo(resp.Info)
+
+	detail := &cri.EngineDetailContainer{
+		ID:        status.Id,
+		Labels:    status.Labels,
+		Source:    status,
+		IsSandbox: true,
+		Runtime:   "unknown",
+		State: cri.ContainerState{
+			Pid:    info.Pid,
+			Status: "exited",
+		},
+	}
+	if status.State == runtimeapi.PodSandboxState_SANDBOX_READY {
+		detail.State.Status = "running"
+	}
+	if status.Metadata != nil {
+		detail.Name = status.Metadata.Name
+	}
+	if spec := info.RuntimeSpec; spec != nil {
+		detail.Hostname = spec.Hostname
+		if spec.Linux != nil {
+			for _, ns := range spec.Linux.Namespaces {
+				if ns.Type == specs.NetworkNamespace && ns.Path != "" {
+					detail.NetworkMode = "netns:" + ns.Path
+				}
+			}
+		}
+	}
+	if status.Linux != nil && status.Linux.Namespaces != nil && status.Linux.Namespaces.Options != nil {
+		options := status.Linux.Namespaces.Options
+		if options.Network == runtimeapi.NamespaceMode_NODE {
+			detail.NetworkMode = "host"
+		}
+		if options.Pid == runtimeapi.NamespaceMode_NODE {
+			detail.PidMode = "host"
+		}
+	}
+	return detail, nil
+}
+
+func convertCriContainerState(state runtimeapi.ContainerState) string {
+	switch state {
+	case runtimeapi.ContainerState_CONTAINER_CREATED:
+		return "created"
+	case runtimeapi.ContainerState_CONTAINER_RUNNING:
+		return "running"
+	case runtimeapi.ContainerState_CONTAINER_EXITED:
+		return "exited"
+	default:
+		return "unknown"
+	}
+}
+
+// buildCriExecCmd wraps cmd to support env, working dir and small input, since ExecSync only accepts a cmd
+func buildCriExecCmd(req cri.ExecRequest) ([]string, error) {
+	cmd := req.Cmd
+	if len(req.Env) > 0 {
+		cmd = append(append([]string{"env"}, req.Env...), cmd...)
+	}
+	if req.WorkingDir != "" {
+		cmd = append([]string{"sh", "-c", `cd "$0" && exec "$@"`, req.WorkingDir}, cmd...)
+	}
+	if req.Input != nil {
+		input, err := io.ReadAll(io.LimitReader(req.Input, maxCriExecInputSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(input) > maxCriExecInputSize {
+			return nil, errCriExecInputTooLarge
+		}
+		cmd = append([]string{"sh", "-c", `echo "$0" | base64 -d | "$@"`, base64.StdEncoding.EncodeToString(input)}, cmd...)
+	}
+	return cmd, nil
+}
+
+func (e *CriContainerEngine) Exec(ctx context.Context, c *cri.Container, req cri.ExecRequest) (cri.ExecResult, error) {
+	invalidResult := cri.ExecResult{Cmd: strings.Join(req.Cmd, " "), ExitCode: -1}
+
+	cmd, err := buildCriExecCmd(req)
+	if err != nil {
+		return invalidResult, err
+	}
+
+	timeout := criDefaultExecTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	timeoutSeconds := int64(timeout.Seconds())
+	if timeoutSeconds < 1 {
+		timeoutSeconds = 1
+	}
+
+	resp, err := e.Client.ExecSync(ctx, &runtimeapi.ExecSyncRequest{
+		ContainerId: c.Id,
+		Cmd:         cmd,
+		Timeout:     timeoutSeconds,
+	})
+	if err != nil {
+		return invalidResult, err
+	}
+
+	stdout := bytes.NewBuffer(resp.Stdout)
+	stderr := bytes.NewBuffer(resp.Stderr)
+	// When exec successfully but with exitCode!=0, I wrap it as an error. This forces developers to handle errors.
+	if resp.ExitCode != 0 {
+		err = fmt.Errorf("exitcode=[%d] stdout=[%s] stderr=[%s]", resp.ExitCode, stdout.String(), stderr.String())
+	}
+	return cri.ExecResult{Cmd: invalidResult.Cmd, ExitCode: int(resp.ExitCode), Stdout: stdout, Stderr: stderr}, err
+}
+
+// ExecAsync runs ExecSync in background, outputs are available after command exits
+func (e *CriContainerEngine) ExecAsync(ctx context.Context, c *cri.Container, req cri.ExecRequest) (cri.ExecAsyncResult, error) {
+	resultCh := make(chan cri.ExecAsyncResultCode, 1)
+	stdoutR, stdoutW := io.Pipe()
+	stderrR, stderrW := io.Pipe()
+
+	go func() {
+		result, err := e.Exec(ctx, c, req)
+		if result.Stdout != nil {
+			go func() {
+				io.Copy(stdoutW, result.Stdout)
+				stdoutW.Close()
+			}()
+		} else {
+			stdoutW.Close()
+		}
+		if result.Stderr != nil {
+			go func() {
+				io.Copy(stderrW, result.Stderr)
+				stderrW.Close()
+			}()
+		} else {
+			stderrW.Close()
+		}
+		resultCh <- cri.ExecAsyncResultCode{Code: result.ExitCode, Err: err}
+	}()
+
+	return cri.ExecAsyncResult{
+		Cmd:    strings.Join(req.Cmd, " "),
+		Result: resultCh,
+		Stdout: stdoutR,
+		Stderr: stderrR,
+	}, nil
+}
+
+func (e *CriContainerEngine) CopyToContainer(ctx context.Context, c *cri.Container, src, dst string) error {
+	return errors.New("CopyToContainer unsupported")
+}
+
+func (e *CriContainerEngine) CopyFromContainer(ctx context.Context, c *cri.Container, src, dst string) error {
+	return errors.New("CopyFromContainer unsupported")
+}
+
+func (e *CriContainerEngine) Supports(feature cri.ContainerEngineFeature) bool {
+	switch feature {
+	case cri.ContainerEngineFeatureCopy:
+		return false
+	case cri.ContainerEngineFeatureExecInput:
+		// CRI ExecSync has no stdin, input (at most maxCriExecInputSize) is passed by 'sh -c' and 'base64 -d', so the container must have sh and base64.
+		return true
+	default:
+		return false
+	}
+}
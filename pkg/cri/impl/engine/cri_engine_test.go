@@ -0,0 +1,116 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package engine
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/traas-stack/holoinsight-agent/pkg/cri"
+	"google.golang.org/grpc"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+	"strings"
+	"testing"
+)
+
+type fakeRuntimeServiceClient struct {
+	runtimeapi.RuntimeServiceClient
+	execReq *runtimeapi.ExecSyncRequest
+}
+
+func (f *fakeRuntimeServiceClient) ListPodSandbox(ctx context.Context, in *runtimeapi.ListPodSandboxRequest, opts ...grpc.CallOption) (*runtimeapi.ListPodSandboxResponse, error) {
+	return &runtimeapi.ListPodSandboxResponse{Items: []*runtimeapi.PodSandbox{{
+		Id:     "sandbox1",
+		Labels: map[string]string{"io.kubernetes.pod.uid": "uid1"},
+	}}}, nil
+}
+
+func (f *fakeRuntimeServiceClient) ListContainers(ctx context.Context, in *runtimeapi.ListContainersRequest, opts ...grpc.CallOption) (*runtimeapi.ListContainersResponse, error) {
+	return &runtimeapi.ListContainersResponse{Containers: []*runtimeapi.Container{{
+		Id:           "app1",
+		PodSandboxId: "sandbox1",
+		Labels:       map[string]string{"io.kubernetes.pod.uid": "uid1", "io.kubernetes.container.name": "app"},
+	}}}, nil
+}
+
+func (f *fakeRuntimeServiceClient) PodSandboxStatus(ctx context.Context, in *runtimeapi.PodSandboxStatusRequest, opts ...grpc.CallOption) (*runtimeapi.PodSandboxStatusResponse, error) {
+	return &runtimeapi.PodSandboxStatusResponse{
+		Status: &runtimeapi.PodSandboxStatus{
+			Id:       in.PodSandboxId,
+			Metadata: &runtimeapi.PodSandboxMetadata{Name: "demo"},
+			State:    runtimeapi.PodSandboxState_SANDBOX_READY,
+			Linux: &runtimeapi.LinuxPodSandboxStatus{Namespaces: &runtimeapi.Namespace{Options: &runtimeapi.NamespaceOption{
+				Network: runtimeapi.NamespaceMode_NODE,
+			}}},
+		},
+		Info: map[string]string{"info": `{"pid": 100}`},
+	}, nil
+}
+
+func (f *fakeRuntimeServiceClient) ContainerStatus(ctx context.Context, in *runtimeapi.ContainerStatusRequest, opts ...grpc.CallOption) (*runtimeapi.ContainerStatusResponse, error) {
+	return &runtimeapi.ContainerStatusResponse{
+		Status: &runtimeapi.ContainerStatus{
+			Id:       in.ContainerId,
+			Metadata: &runtimeapi.ContainerMetadata{Name: "app"},
+			State:    runtimeapi.ContainerState_CONTAINER_RUNNING,
+			LogPath:  "/var/log/pods/default_demo_uid1/app/0.log",
+			Mounts:   []*runtimeapi.Mount{{ContainerPath: "/logs", HostPath: "/var/lib/kubelet/pods/uid1/volumes/logs"}},
+		},
+		Info: map[string]string{"info": `{"pid": 200, "sandboxID": "sandbox1", "runtimeSpec": {"hostname": "demo",
+"process": {"env": ["A=1"]}, "annotations": {"io.kubernetes.cri-o.MountPoint": "/var/lib/containers/storage/overlay/x/merged"}}}`},
+	}, nil
+}
+
+func (f *fakeRuntimeServiceClient) ExecSync(ctx context.Context, in *runtimeapi.ExecSyncRequest, opts ...grpc.CallOption) (*runtimeapi.ExecSyncResponse, error) {
+	f.execReq = in
+	return &runtimeapi.ExecSyncResponse{Stdout: []byte("ok"), ExitCode: 0}, nil
+}
+
+func TestCriContainerEngine(t *testing.T) {
+	client := &fakeRuntimeServiceClient{}
+	e := &CriContainerEngine{Client: client}
+	assert.NoError(t, e.Init())
+	assert.False(t, e.Supports(cri.ContainerEngineFeatureCopy))
+	assert.True(t, e.Supports(cri.ContainerEngineFeatureExecInput))
+
+	containers, err := e.ListAllContainers(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, containers, 2)
+
+	sandbox, err := e.GetContainerDetail(context.Background(), "sandbox1")
+	assert.NoError(t, err)
+	assert.True(t, sandbox.IsSandbox)
+	assert.True(t, sandbox.State.IsRunning())
+	assert.Equal(t, 100, sandbox.State.Pid)
+	assert.Equal(t, "host", sandbox.NetworkMode)
+
+	app, err := e.GetContainerDetail(context.Background(), "app1")
+	assert.NoError(t, err)
+	assert.False(t, app.IsSandbox)
+	assert.True(t, app.State.IsRunning())
+	assert.Equal(t, 200, app.State.Pid)
+	assert.Equal(t, "sandbox1", app.SandboxId)
+	assert.Equal(t, []string{"A=1"}, app.Env)
+	assert.Equal(t, "demo", app.Hostname)
+	assert.Equal(t, cri.Runc, app.Runtime)
+	assert.Equal(t, "/var/lib/containers/storage/overlay/x/merged", app.MergedDir)
+	assert.Equal(t, "/var/log/pods/default_demo_uid1/app/0.log", app.LogPath)
+	assert.Len(t, app.Mounts, 1)
+	assert.True(t, app.Mounts[0].RW)
+
+	result, err := e.Exec(context.Background(), &cri.Container{Id: "app1"}, cri.ExecRequest{
+		Cmd:   []string{"cat"},
+		Env:   []string{"A=1"},
+		Input: strings.NewReader("hello"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", result.Stdout.String())
+	assert.Equal(t, []string{"sh", "-c", `echo "$0" | base64 -d | "$@"`, "aGVsbG8=", "env", "A=1", "cat"}, client.execReq.Cmd)
+
+	_, err = e.Exec(context.Background(), &cri.Container{Id: "app1"}, cri.ExecRequest{
+		Cmd:   []string{"cat"},
+		Input: strings.NewReader(strings.Repeat("a", maxCriExecInputSize+1)),
+	})
+	assert.Equal(t, errCriExecInputTooLarge, err)
+}
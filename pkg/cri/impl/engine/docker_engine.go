@@ -219,6 +219,8 @@ func (e *DockerContainerEngine) Supports(feature cri.ContainerEngineFeature) boo
 	case cri.ContainerEngineFeatureCopy:
 		// TODO Whether copy is supported depends on the lower-level container runtime rather than the higher-level container runtime.
 		return !e.isPouch
+	case cri.ContainerEngineFeatureExecInput:
+		return true
 	default:
 		return false
 	}
@@ -13,12 +13,8 @@ import (
 	"github.com/traas-stack/holoinsight-agent/pkg/cri"
 	"github.com/traas-stack/holoinsight-agent/pkg/cri/dockerutils"
 	"github.com/traas-stack/holoinsight-agent/pkg/logger"
-	"github.com/traas-stack/holoinsight-agent/pkg/meta"
-	"github.com/traas-stack/holoinsight-agent/pkg/model"
-	"github.com/traas-stack/holoinsight-agent/pkg/plugin/output/gateway"
 	"github.com/traas-stack/holoinsight-agent/pkg/util"
 	"go.uber.org/zap"
-	k8stypes "k8s.io/apimachinery/pkg/types"
 	"time"
 )
 
@@ -135,48 +131,6 @@ func (m *OOMManager) emitLoop() {
 }
 
 func (m *OOMManager) emitOOMMetrics(emitTime time.Time) {
-	record := m.oomRecoder.getAndClear()
-
-	// k8s_pod_oom
-	var metrics []*model.Metric
-
-	processed := make(map[k8stypes.UID]struct{})
-	for _, item := range record {
-		tags := meta.ExtractContainerCommonTags(item.container)
-		processed[item.container.Pod.UID] = struct{}{}
-
-		metrics = append(metrics, &model.Metric{
-			Name:      "k8s_pod_oom",
-			Tags:      tags,
-			Timestamp: emitTime.UnixMilli(),
-			Value:     float64(item.count),
-		})
-	}
-	pods := m.CRI.GetAllPods()
-	for _, pod := range pods {
-		if _, ok := processed[pod.UID]; ok {
-			continue
-		}
-		processed[pod.UID] = struct{}{}
-		tags := meta.ExtractPodCommonTags(pod.Pod)
-		tags["container"] = "-"
-		metrics = append(metrics, &model.Metric{
-			Name:      "k8s_pod_oom",
-			Tags:      tags,
-			Timestamp: emitTime.UnixMilli(),
-			Value:     float64(0),
-		})
-	}
-
-	// TODO Decoupling data production and consumption
-	begin := time.Now()
-	err := gateway.GetWriteService().WriteV1(context.Background(), &gateway.WriteV1Request{
-		Batch: metrics,
-	})
-	cost := time.Now().Sub(begin)
-	if err == nil {
-		logger.Infoz("[oom] report success", zap.Int("metrics", len(metrics)), zap.Duration("cost", cost))
-	} else {
-		logger.Errorz("[oom] report error", zap.Int("metrics", len(metrics)), zap.Duration("cost", cost), zap.Error(err))
-	}
+	metrics := buildContainerCountMetrics("k8s_pod_oom", m.CRI, m.oomRecoder.getAndClear(), emitTime)
+	writeMetrics("[oom]", metrics)
 }
@@ -0,0 +1,66 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package engine
+
+import (
+	"context"
+	"github.com/traas-stack/holoinsight-agent/pkg/cri"
+	"github.com/traas-stack/holoinsight-agent/pkg/logger"
+	"github.com/traas-stack/holoinsight-agent/pkg/meta"
+	"github.com/traas-stack/holoinsight-agent/pkg/model"
+	"github.com/traas-stack/holoinsight-agent/pkg/plugin/output/gateway"
+	"go.uber.org/zap"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"time"
+)
+
+// buildContainerCountMetrics builds metrics of recorded container events such as OOM.
+// Pods without any record are reported with value 0 and container '-'.
+func buildContainerCountMetrics(name string, i cri.Interface, record map[string]*recordItem, emitTime time.Time) []*model.Metric {
+	var metrics []*model.Metric
+
+	processed := make(map[k8stypes.UID]struct{})
+	for _, item := range record {
+		tags := meta.ExtractContainerCommonTags(item.container)
+		processed[item.container.Pod.UID] = struct{}{}
+
+		metrics = append(metrics, &model.Metric{
+			Name:      name,
+			Tags:      tags,
+			Timestamp: emitTime.UnixMilli(),
+			Value:     float64(item.count),
+		})
+	}
+	pods := i.GetAllPods()
+	for _, pod := range pods {
+		if _, ok := processed[pod.UID]; ok {
+			continue
+		}
+		processed[pod.UID] = struct{}{}
+		tags := meta.ExtractPodCommonTags(pod.Pod)
+		tags["container"] = "-"
+		metrics = append(metrics, &model.Metric{
+			Name:      name,
+			Tags:      tags,
+			Timestamp: emitTime.UnixMilli(),
+			Value:     float64(0),
+		})
+	}
+	return metrics
+}
+
+func writeMetrics(logPrefix string, metrics []*model.Metric) {
+	// TODO Decoupling data production and consumption
+	begin := time.Now()
+	err := gateway.GetWriteService().WriteV1(context.Background(), &gateway.WriteV1Request{
+		Batch: metrics,
+	})
+	cost := time.Now().Sub(begin)
+	if err == nil {
+		logger.Infoz(logPrefix+" report success", zap.Int("metrics", len(metrics)), zap.Duration("cost", cost))
+	} else {
+		logger.Errorz(logPrefix+" report error", zap.Int("metrics", len(metrics)), zap.Duration("cost", cost), zap.Error(err))
+	}
+}
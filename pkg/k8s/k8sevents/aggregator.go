@@ -0,0 +1,152 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package k8sevents
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"time"
+)
+
+type (
+	// eventAggregator deduplicates and rate-limits k8s events, and counts occurrences of events by reason/kind/namespace/type.
+	// It is not thread safe.
+	eventAggregator struct {
+		// seen is event uid -> last seen occurrence
+		seen map[types.UID]*seenEvent
+		// limits is 'involved object + reason' -> window of reported events
+		limits map[string]*limitWindow
+		counts map[countKey]int64
+		// throttled is the number of events not reported because of rate limit
+		throttled int64
+		// perObjectLimit is max number of reported events of an involved object and reason in a window
+		perObjectLimit int
+		// totalLimit is max number of reported events in a window
+		totalLimit  int
+		totalWindow limitWindow
+		window      time.Duration
+		seenTTL     time.Duration
+	}
+	seenEvent struct {
+		count int32
+		time  time.Time
+	}
+	limitWindow struct {
+		start time.Time
+		count int
+	}
+	countKey struct {
+		namespace string
+		kind      string
+		reason    string
+		eventType string
+	}
+)
+
+func newEventAggregator(perObjectLimit, totalLimit int, window, seenTTL time.Duration) *eventAggregator {
+	return &eventAggregator{
+		seen:           make(map[types.UID]*seenEvent),
+		limits:         make(map[string]*limitWindow),
+		counts:         make(map[countKey]int64),
+		perObjectLimit: perObjectLimit,
+		totalLimit:     totalLimit,
+		window:         window,
+		seenTTL:        seenTTL,
+	}
+}
+
+// eventCount returns number of occurrences of an event, events created by events.k8s.io/v1 use series instead of count
+func eventCount(e *v1.Event) int32 {
+	if e.Series != nil && e.Series.Count > 0 {
+		return e.Series.Count
+	}
+	if e.Count > 0 {
+		return e.Count
+	}
+	return 1
+}
+
+// seed marks existing events as seen without counting or reporting them.
+// It is used on list, otherwise all historical events will be reported again after every relist.
+func (a *eventAggregator) seed(e *v1.Event, now time.Time) {
+	a.seen[e.UID] = &seenEvent{count: eventCount(e), time: now}
+}
+
+// add records an event, returns whether it should be reported.
+// An event is duplicated if its count is not increased since last time, such as an update of other fields.
+func (a *eventAggregator) add(e *v1.Event, now time.Time) bool {
+	count := eventCount(e)
+	delta := int64(count)
+	if s, ok := a.seen[e.UID]; ok {
+		if count <= s.count {
+			s.time = now
+			return false
+		}
+		delta = int64(count - s.count)
+		s.count = count
+		s.time = now
+	} else {
+		a.seen[e.UID] = &seenEvent{count: count, time: now}
+	}
+
+	a.counts[countKey{
+		namespace: e.InvolvedObject.Namespace,
+		kind:      e.InvolvedObject.Kind,
+		reason:    e.Reason,
+		eventType: e.Type,
+	}] += delta
+
+	if !a.allow(limitKey(e), now) {
+		a.throttled++
+		return false
+	}
+	return true
+}
+
+func limitKey(e *v1.Event) string {
+	o := &e.InvolvedObject
+	return o.Kind + "/" + o.Namespace + "/" + o.Name + "/" + e.Reason
+}
+
+func (a *eventAggregator) allow(key string, now time.Time) bool {
+	if now.Sub(a.totalWindow.start) >= a.window {
+		a.totalWindow = limitWindow{start: now}
+	}
+	if a.totalWindow.count >= a.totalLimit {
+		return false
+	}
+
+	w, ok := a.limits[key]
+	if !ok || now.Sub(w.start) >= a.window {
+		w = &limitWindow{start: now}
+		a.limits[key] = w
+	}
+	if w.count >= a.perObjectLimit {
+		return false
+	}
+	w.count++
+	a.totalWindow.count++
+	return true
+}
+
+// flush returns counts since last flush and evicts expired states
+func (a *eventAggregator) flush(now time.Time) (map[countKey]int64, int64) {
+	counts := a.counts
+	throttled := a.throttled
+	a.counts = make(map[countKey]int64)
+	a.throttled = 0
+
+	for uid, s := range a.seen {
+		if now.Sub(s.time) > a.seenTTL {
+			delete(a.seen, uid)
+		}
+	}
+	for key, w := range a.limits {
+		if now.Sub(w.start) >= a.window {
+			delete(a.limits, key)
+		}
+	}
+	return counts, throttled
+}
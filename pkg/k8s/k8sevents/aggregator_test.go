@@ -0,0 +1,65 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package k8sevents
+
+import (
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+	"time"
+)
+
+func newEvent(uid, pod, reason string, count int32) *v1.Event {
+	return &v1.Event{
+		ObjectMeta:     metav1.ObjectMeta{UID: types.UID(uid), Namespace: "default"},
+		InvolvedObject: v1.ObjectReference{Kind: "Pod", Namespace: "default", Name: pod},
+		Reason:         reason,
+		Type:           v1.EventTypeWarning,
+		Count:          count,
+	}
+}
+
+func TestEventAggregator(t *testing.T) {
+	a := newEventAggregator(2, 100, time.Minute, time.Hour)
+	now := time.Now()
+
+	// historical events are not reported or counted
+	a.seed(newEvent("1", "demo", "BackOff", 3), now)
+	assert.False(t, a.add(newEvent("1", "demo", "BackOff", 3), now))
+	// count increases
+	assert.True(t, a.add(newEvent("1", "demo", "BackOff", 5), now))
+	assert.True(t, a.add(newEvent("2", "demo", "BackOff", 1), now))
+	// rate-limited, but still counted
+	assert.False(t, a.add(newEvent("3", "demo", "BackOff", 1), now))
+	assert.True(t, a.add(newEvent("4", "demo", "OOMKilling", 1), now))
+	// a new window
+	assert.True(t, a.add(newEvent("5", "demo", "BackOff", 1), now.Add(time.Minute)))
+
+	counts, throttled := a.flush(now.Add(time.Minute))
+	assert.Equal(t, int64(1), throttled)
+	backOff := countKey{namespace: "default", kind: "Pod", reason: "BackOff", eventType: v1.EventTypeWarning}
+	assert.Equal(t, int64(5), counts[backOff])
+	assert.Equal(t, int64(1), counts[countKey{namespace: "default", kind: "Pod", reason: "OOMKilling", eventType: v1.EventTypeWarning}])
+
+	// seen states expire
+	counts, _ = a.flush(now.Add(2 * time.Hour))
+	assert.Empty(t, counts)
+	assert.Empty(t, a.seen)
+	assert.Empty(t, a.limits)
+}
+
+func TestConvertToReportEvent(t *testing.T) {
+	e := newEvent("1", "demo", "BackOff", 3)
+	e.Message = "Back-off restarting failed container"
+	e.LastTimestamp = metav1.NewTime(time.UnixMilli(1690000000000))
+	re := convertToReportEvent(e)
+	assert.Equal(t, "k8s_event", re.PayloadType)
+	assert.Equal(t, int64(1690000000000), re.EventTimestamp)
+	assert.Equal(t, "demo", re.Tags["pod"])
+	assert.Equal(t, int64(3), re.Numbers["count"])
+	assert.Equal(t, e.Message, re.Strings["message"])
+}
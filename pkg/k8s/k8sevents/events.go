@@ -0,0 +1,245 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package k8sevents
+
+import (
+	"context"
+	"github.com/traas-stack/holoinsight-agent/pkg/k8s/listwatchext"
+	"github.com/traas-stack/holoinsight-agent/pkg/logger"
+	"github.com/traas-stack/holoinsight-agent/pkg/meta"
+	"github.com/traas-stack/holoinsight-agent/pkg/model"
+	"github.com/traas-stack/holoinsight-agent/pkg/plugin/output/gateway"
+	"github.com/traas-stack/holoinsight-agent/pkg/server/registry"
+	"github.com/traas-stack/holoinsight-agent/pkg/server/registry/pb"
+	"github.com/traas-stack/holoinsight-agent/pkg/util"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"sync"
+	"time"
+)
+
+const (
+	defaultListWatchRestartInterval = 10 * time.Minute
+	defaultFlushInterval            = time.Minute
+	defaultPerObjectLimit           = 5
+	defaultTotalLimit               = 600
+	defaultSeenTTL                  = 2 * time.Hour
+	maxMessageLength                = 1024
+)
+
+type (
+	// Watcher watches core/v1 events of the whole cluster.
+	// Every new occurrence of an event is reported to registry as a 'k8s_event' DIGEST event unless it is rate-limited,
+	// and occurrences are counted as 'k8s_event_count' metrics by namespace/kind/reason/type every minute.
+	Watcher interface {
+		Start()
+		Stop()
+	}
+	eventWatcher struct {
+		rs         *registry.Service
+		clientset  *kubernetes.Clientset
+		mutex      sync.Mutex
+		aggregator *eventAggregator
+		stopSig    *util.StopSignal
+	}
+)
+
+func NewWatcher(rs *registry.Service, clientset *kubernetes.Clientset) Watcher {
+	return &eventWatcher{
+		rs:         rs,
+		clientset:  clientset,
+		aggregator: newEventAggregator(defaultPerObjectLimit, defaultTotalLimit, defaultFlushInterval, defaultSeenTTL),
+		stopSig:    util.NewStopSignal(),
+	}
+}
+
+func (w *eventWatcher) Start() {
+	go w.runLoop()
+	go w.flushLoop()
+}
+
+func (w *eventWatcher) Stop() {
+	w.stopSig.StopAndWait()
+}
+
+// runLoop restarts list&watch periodically as what k8ssync does
+func (w *eventWatcher) runLoop() {
+	start := time.NewTimer(0)
+	defer start.Stop()
+
+	stop := time.NewTimer(defaultListWatchRestartInterval)
+	defer stop.Stop()
+
+	var stopCh chan struct{}
+	for {
+		select {
+		case <-start.C:
+			stopCh = make(chan struct{})
+			logger.Metaz("[k8sevents] start")
+			w.runOnce(stopCh)
+			stop.Reset(defaultListWatchRestartInterval)
+		case <-stop.C:
+			close(stopCh)
+			stopCh = nil
+			logger.Metaz("[k8sevents] stop and restart after 1 s")
+			start.Reset(time.Second)
+		case <-w.stopSig.C:
+			if stopCh != nil {
+				close(stopCh)
+			}
+			return
+		}
+	}
+}
+
+func (w *eventWatcher) runOnce(stopCh <-chan struct{}) {
+	lw := listwatchext.NewListWatchFromClient(w.clientset.CoreV1().RESTClient(), "events", v1.NamespaceAll)
+	helper := listwatchext.NewListWatchHelper(lw, listwatchext.ListWatchCallback{
+		OnList: func(items []runtime.Object) {
+			now := time.Now()
+			w.mutex.Lock()
+			defer w.mutex.Unlock()
+			for _, item := range items {
+				if e, ok := item.(*v1.Event); ok {
+					w.aggregator.seed(e, now)
+				}
+			}
+			logger.Metaz("[k8sevents] list", zap.Int("size", len(items)))
+		},
+		OnEvent: func(e watch.Event) {
+			if e.Type != watch.Added && e.Type != watch.Modified {
+				return
+			}
+			event, ok := e.Object.(*v1.Event)
+			if !ok {
+				return
+			}
+			w.mutex.Lock()
+			report := w.aggregator.add(event, time.Now())
+			w.mutex.Unlock()
+			if report {
+				w.rs.ReportEventAsync(convertToReportEvent(event))
+			}
+		},
+	})
+	go helper.Run(stopCh)
+}
+
+func (w *eventWatcher) flushLoop() {
+	defer w.stopSig.StopDone()
+
+	timer, _ := util.NewAlignedTimer(defaultFlushInterval, time.Second, true, false)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			metricTime := timer.NextEmitTime().Truncate(defaultFlushInterval).Add(-defaultFlushInterval)
+			w.flushOnce(metricTime)
+			timer.Next()
+		case <-w.stopSig.C:
+			return
+		}
+	}
+}
+
+func (w *eventWatcher) flushOnce(metricTime time.Time) {
+	w.mutex.Lock()
+	counts, throttled := w.aggregator.flush(time.Now())
+	w.mutex.Unlock()
+
+	metrics := buildMetrics(metricTime, counts, throttled)
+	err := gateway.GetWriteService().WriteV1(context.Background(), &gateway.WriteV1Request{
+		Batch: metrics,
+	})
+	logger.Infoz("[k8sevents] flush",
+		zap.Time("metricTime", metricTime),
+		zap.Int("metrics", len(metrics)),
+		zap.Int64("throttled", throttled),
+		zap.Error(err))
+}
+
+func buildMetrics(metricTime time.Time, counts map[countKey]int64, throttled int64) []*model.Metric {
+	ts := metricTime.UnixMilli()
+	metrics := make([]*model.Metric, 0, len(counts)+1)
+	for key, count := range counts {
+		tags := map[string]string{
+			"namespace": key.namespace,
+			"kind":      key.kind,
+			"reason":    key.reason,
+			"type":      key.eventType,
+		}
+		meta.AttachSystemCommonTagsTo(tags)
+		metrics = append(metrics, &model.Metric{
+			Name:      "k8s_event_count",
+			Tags:      tags,
+			Timestamp: ts,
+			Value:     float64(count),
+		})
+	}
+	tags := make(map[string]string, 1)
+	meta.AttachSystemCommonTagsTo(tags)
+	metrics = append(metrics, &model.Metric{
+		Name:      "k8s_event_throttled",
+		Tags:      tags,
+		Timestamp: ts,
+		Value:     float64(throttled),
+	})
+	return metrics
+}
+
+// eventTime returns the time of last occurrence of an event
+func eventTime(e *v1.Event) time.Time {
+	if e.Series != nil && !e.Series.LastObservedTime.IsZero() {
+		return e.Series.LastObservedTime.Time
+	}
+	if !e.LastTimestamp.IsZero() {
+		return e.LastTimestamp.Time
+	}
+	if !e.EventTime.IsZero() {
+		return e.EventTime.Time
+	}
+	return e.CreationTimestamp.Time
+}
+
+func convertToReportEvent(e *v1.Event) *pb.ReportEventRequest_Event {
+	o := &e.InvolvedObject
+	tags := map[string]string{
+		"namespace": o.Namespace,
+		"kind":      o.Kind,
+		"name":      o.Name,
+		"reason":    e.Reason,
+		"type":      e.Type,
+		"component": e.Source.Component,
+		"host":      e.Source.Host,
+	}
+	// Uses the same tag as pod metrics, so events can be correlated with them
+	if o.Kind == "Pod" {
+		tags["pod"] = o.Name
+	}
+	meta.AttachSystemCommonTagsTo(tags)
+
+	message := e.Message
+	if len(message) > maxMessageLength {
+		message = message[:maxMessageLength]
+	}
+
+	return &pb.ReportEventRequest_Event{
+		EventTimestamp: eventTime(e).UnixMilli(),
+		EventType:      "DIGEST",
+		PayloadType:    "k8s_event",
+		Tags:           tags,
+		Numbers: map[string]int64{
+			"count": int64(eventCount(e)),
+		},
+		Strings: map[string]string{
+			"uid":     string(e.UID),
+			"message": message,
+		},
+	}
+}
@@ -19,7 +19,9 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"sync/atomic"
 	"time"
 )
 
@@ -32,6 +34,10 @@ type (
 	MetaSyncer interface {
 		Start()
 		Stop()
+		// PodLister returns a lister of pods watched by MetaSyncer, so that other components don't need to watch all pods again.
+		PodLister() corelisters.PodLister
+		// PodsSynced returns true after pods are listed for the first time
+		PodsSynced() bool
 	}
 	metaSyncer struct {
 		rs               *registry.Service
@@ -39,6 +45,9 @@ type (
 		namespaceChanged int32
 		nodeChanged      int32
 		podChanged       int32
+		// pods is a local cache of all pods, including Succeeded and Failed pods
+		pods       cache.Indexer
+		podsSynced int32
 	}
 	convertFunc      func(obj interface{}) *Resource
 	deleteStatusFunc func(obj interface{}) bool
@@ -55,6 +64,9 @@ type (
 		reportType string
 		funcs      resourceFuncs
 		verbose    bool
+		// store is optional, it is kept in sync with the list and watch events
+		store  cache.Store
+		synced *int32
 	}
 )
 
@@ -62,6 +74,7 @@ func NewMetaSyncer(rs *registry.Service, clientset *kubernetes.Clientset) MetaSy
 	return &metaSyncer{
 		rs:        rs,
 		clientset: clientset,
+		pods:      cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}),
 	}
 }
 
@@ -131,6 +144,7 @@ func (rs *resourceSyncer) start(stopCh <-chan struct{}) {
 	}, stopCh)
 	helper := listwatchext.NewListWatchHelper(lw, listwatchext.ListWatchCallback{
 		OnList: func(items []runtime.Object) {
+			rs.replaceStore(items)
 
 			req := &FullSyncRequest{
 				Apikey:    appconfig.StdAgentConfig.ApiKey,
@@ -171,6 +185,7 @@ func (rs *resourceSyncer) start(stopCh <-chan struct{}) {
 					logger.Metaz("onEvent", zap.String("type", string(e.Type)), zap.String("ns", ns), zap.String("name", name))
 				}
 			}
+			rs.updateStore(e)
 			em.Add(e)
 		},
 	})
@@ -178,6 +193,46 @@ func (rs *resourceSyncer) start(stopCh <-chan struct{}) {
 	go helper.Run(stopCh)
 }
 
+func (rs *resourceSyncer) replaceStore(items []runtime.Object) {
+	if rs.store == nil {
+		return
+	}
+	list := make([]interface{}, len(items))
+	for i, item := range items {
+		list[i] = item
+	}
+	if err := rs.store.Replace(list, ""); err != nil {
+		logger.Metaz("[k8s] replace store error", zap.String("type", rs.reportType), zap.Error(err))
+	}
+	if rs.synced != nil {
+		atomic.StoreInt32(rs.synced, 1)
+	}
+}
+
+func (rs *resourceSyncer) updateStore(e watch.Event) {
+	if rs.store == nil {
+		return
+	}
+	var err error
+	switch e.Type {
+	case watch.Added, watch.Modified:
+		err = rs.store.Update(e.Object)
+	case watch.Deleted:
+		err = rs.store.Delete(e.Object)
+	}
+	if err != nil {
+		logger.Metaz("[k8s] update store error", zap.String("type", rs.reportType), zap.Error(err))
+	}
+}
+
+func (s *metaSyncer) PodLister() corelisters.PodLister {
+	return corelisters.NewPodLister(s.pods)
+}
+
+func (s *metaSyncer) PodsSynced() bool {
+	return atomic.LoadInt32(&s.podsSynced) == 1
+}
+
 func (s *metaSyncer) Stop() {}
 
 func (s *metaSyncer) Start() {
@@ -235,6 +290,8 @@ func (s *metaSyncer) runOnce(stopCh <-chan struct{}) {
 			},
 		})
 		rs.verbose = true
+		rs.store = s.pods
+		rs.synced = &s.podsSynced
 		rs.start(stopCh)
 	}
 }
@@ -12,7 +12,6 @@ import (
 	cv1 "github.com/google/cadvisor/info/v1"
 	"github.com/traas-stack/holoinsight-agent/pkg/cri"
 	"github.com/traas-stack/holoinsight-agent/pkg/k8s/k8slabels"
-	"github.com/traas-stack/holoinsight-agent/pkg/k8s/k8smeta/extractor"
 	"github.com/traas-stack/holoinsight-agent/pkg/k8s/k8ssysmetrics/common"
 	"github.com/traas-stack/holoinsight-agent/pkg/logger"
 	"github.com/traas-stack/holoinsight-agent/pkg/meta"
@@ -581,20 +580,7 @@ func (c *cadvisorSysCollector) makePodCGroupInfo(ctrs []cv1.ContainerInfo) map[s
 }
 
 func (c *cadvisorSysCollector) extractNodeTags(cAdvisorPod *v1.Pod) map[string]string {
-	// Prepare node level tags
-	tags := map[string]string{
-		"ip": cAdvisorPod.Status.HostIP,
-	}
-	if node := c.cri.LocalAgentMeta().Node(); node != nil {
-		tags["name"] = node.Name
-		tags["hostname"] = extractor.PodMetaServiceInstance.NodeHostname(node)
-		tags["region"] = k8slabels.GetRegion(node.Labels)
-		tags["zone"] = k8slabels.GetZone(node.Labels)
-		tags["os"] = node.Labels[k8slabels.LabelK8sOs]
-		tags["arch"] = node.Labels[k8slabels.LabelK8sArch]
-		tags["instanceType"] = node.Labels[k8slabels.LabelK8sNodeInstanceType]
-	}
-	return tags
+	return common.ExtractNodeTags(cAdvisorPod.Status.HostIP, c.cri.LocalAgentMeta().Node())
 }
 
 // TODO refactor
@@ -0,0 +1,108 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package cgroup
+
+import (
+	"github.com/traas-stack/holoinsight-agent/pkg/model"
+	"math"
+	"time"
+)
+
+var (
+	// pressureMetrics maps keys of cgroupStats.Pressure to metric name suffixes
+	pressureMetrics = map[string]string{
+		"cpu_some":    "_cpu_pressure_some",
+		"memory_some": "_mem_pressure_some",
+		"memory_full": "_mem_pressure_full",
+		"io_some":     "_io_pressure_some",
+		"io_full":     "_io_pressure_full",
+	}
+)
+
+// calcMetrics calculates metrics of a cgroup from 2 snapshots, metric names and semantics are the same as cadvisor collector.
+// nodeCores and nodeMem are used as limits when the cgroup is unlimited.
+func calcMetrics(metricPrefix string, tags map[string]string, s1, s2 *cgroupStats, nodeCores float64, nodeMem uint64, metricTime int64) []*model.Metric {
+	var metrics []*model.Metric
+	add := func(name string, value float64) {
+		metrics = append(metrics, &model.Metric{
+			Name:      metricPrefix + name,
+			Tags:      tags,
+			Timestamp: metricTime,
+			Value:     value,
+		})
+	}
+
+	deltaTime := s2.Time.Sub(s1.Time)
+	if deltaTime <= 0 {
+		return nil
+	}
+
+	limitCpu := nodeCores
+	if s2.CpuLimit > 0 && (nodeCores <= 0 || s2.CpuLimit < nodeCores) {
+		limitCpu = s2.CpuLimit
+	}
+	limitMem := nodeMem
+	if s2.MemLimit > 0 && (nodeMem == 0 || s2.MemLimit < nodeMem) {
+		limitMem = s2.MemLimit
+	}
+
+	// cpu
+	if s2.CpuUsage >= s1.CpuUsage && s2.CpuUser >= s1.CpuUser && s2.CpuSys >= s1.CpuSys && limitCpu > 0 {
+		deltaTotal := float64(s2.CpuUsage - s1.CpuUsage)
+		deltaUser := float64(s2.CpuUser - s1.CpuUser)
+		deltaSys := float64(s2.CpuSys - s1.CpuSys)
+		if deltaUser+deltaSys > 0 {
+			add("_cpu_inuse_cores", deltaTotal/float64(deltaTime))
+			add("_cpu_total_cores", limitCpu)
+			add("_cpu_util", math.Min(deltaTotal/float64(deltaTime)/limitCpu*100, 100))
+			add("_cpu_user", math.Min(deltaUser/float64(deltaTime)/limitCpu*100, 100))
+			add("_cpu_sys", math.Min(deltaSys/float64(deltaTime)/limitCpu*100, 100))
+		}
+		if s2.CpuThrottledPeriods >= s1.CpuThrottledPeriods && s2.CpuThrottledTime >= s1.CpuThrottledTime {
+			add("_cpu_throttled_periods", float64(s2.CpuThrottledPeriods-s1.CpuThrottledPeriods))
+			// in ms
+			add("_cpu_throttled_time", float64(s2.CpuThrottledTime-s1.CpuThrottledTime)/float64(time.Millisecond))
+		}
+	}
+
+	// mem
+	if limitMem > 0 {
+		used := float64(s2.MemUsage) - float64(s2.MemCache)
+		if used < 0 {
+			used = 0
+		}
+		workingSet := float64(s2.MemUsage) - float64(s2.MemInactiveFile)
+		if workingSet < 0 {
+			workingSet = 0
+		}
+		add("_mem_cache", float64(s2.MemCache))
+		add("_mem_rss", float64(s2.MemRss))
+		add("_mem_used", used)
+		add("_mem_working_set", workingSet)
+		add("_mem_total", float64(limitMem))
+		add("_mem_util", math.Min(used/float64(limitMem)*100, 100))
+	}
+
+	// io, in bytes per second
+	if (s2.IoReadBytes > 0 || s2.IoWriteBytes > 0) && s2.IoReadBytes >= s1.IoReadBytes && s2.IoWriteBytes >= s1.IoWriteBytes {
+		add("_io_read_bytes", float64(s2.IoReadBytes-s1.IoReadBytes)*float64(time.Second)/float64(deltaTime))
+		add("_io_write_bytes", float64(s2.IoWriteBytes-s1.IoWriteBytes)*float64(time.Second)/float64(deltaTime))
+	}
+
+	if s2.PidsCurrent > 0 {
+		add("_pids_current", float64(s2.PidsCurrent))
+	}
+
+	// pressure, percentage of time in which tasks were stalled
+	for key, suffix := range pressureMetrics {
+		t2, ok2 := s2.Pressure[key]
+		t1, ok1 := s1.Pressure[key]
+		if ok1 && ok2 && t2 >= t1 {
+			add(suffix, math.Min(float64(t2-t1)*float64(time.Microsecond)/float64(deltaTime)*100, 100))
+		}
+	}
+
+	return metrics
+}
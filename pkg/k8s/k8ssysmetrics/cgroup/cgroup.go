@@ -0,0 +1,238 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package cgroup
+
+import (
+	"context"
+	"encoding/gob"
+	"github.com/traas-stack/holoinsight-agent/pkg/cri"
+	"github.com/traas-stack/holoinsight-agent/pkg/k8s/k8ssysmetrics/common"
+	"github.com/traas-stack/holoinsight-agent/pkg/logger"
+	"github.com/traas-stack/holoinsight-agent/pkg/meta"
+	"github.com/traas-stack/holoinsight-agent/pkg/model"
+	"github.com/traas-stack/holoinsight-agent/pkg/plugin/output/gateway"
+	"github.com/traas-stack/holoinsight-agent/pkg/util"
+	"github.com/traas-stack/holoinsight-agent/pkg/util/cgrouputil"
+	"go.uber.org/zap"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	nodeStatsKey = "node"
+)
+
+type (
+	// cgroupSysCollector reads pod/container resource usage directly from cgroup filesystem of node.
+	// Both cgroup v1 and v2 are supported. It emits the same metrics as cadvisor collector.
+	cgroupSysCollector struct {
+		cri cri.MetaStore
+		// hostfs is the dir where node '/sys/fs/cgroup' and '/proc' are mounted
+		hostfs   string
+		suffix   string
+		interval time.Duration
+		stopSig  *util.StopSignal
+		mutex    sync.Mutex
+		// stats of last collection, key is 'node', 'pod/<uid>' or 'container/<cid>'
+		stats map[string]*cgroupStats
+		timer *util.AlignedTimer
+	}
+	cgroupSysCollectorStateObj struct {
+		Stats      map[string]*cgroupStats
+		TimerBytes []byte
+	}
+)
+
+func init() {
+	gob.Register(&cgroupSysCollectorStateObj{})
+}
+
+func NewPodSystemResourceCollector(cri cri.MetaStore, hostfs string, suffix string, interval time.Duration) common.SysCollector {
+	timer, _ := util.NewAlignedTimer(interval, time.Second, false, false)
+
+	return &cgroupSysCollector{
+		cri:      cri,
+		hostfs:   hostfs,
+		suffix:   suffix,
+		interval: interval,
+		stopSig:  util.NewStopSignal(),
+		timer:    timer,
+	}
+}
+
+func (c *cgroupSysCollector) Name() string {
+	return "cgroup"
+}
+
+func (c *cgroupSysCollector) SaveState() (interface{}, error) {
+	timerBytes, err := c.timer.SaveState()
+	if err != nil {
+		return nil, err
+	}
+	return &cgroupSysCollectorStateObj{
+		Stats:      c.stats,
+		TimerBytes: timerBytes,
+	}, nil
+}
+
+func (c *cgroupSysCollector) LoadState(i interface{}) error {
+	if i == nil {
+		return nil
+	}
+	state := i.(*cgroupSysCollectorStateObj)
+	c.stats = state.Stats
+	return c.timer.LoadState(state.TimerBytes)
+}
+
+func (c *cgroupSysCollector) Start() {
+	go c.taskLoop()
+}
+
+func (c *cgroupSysCollector) Stop() {
+	c.stopSig.StopAndWait()
+}
+
+func (c *cgroupSysCollector) taskLoop() {
+	defer c.stopSig.StopDone()
+
+	timer := c.timer
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			// executes at {interval+1s, 2*interval+1s, ...}
+			func() {
+				c.mutex.Lock()
+				defer c.mutex.Unlock()
+				if c.stopSig.IsStopAsked() {
+					return
+				}
+				metricTime := timer.NextEmitTime().Truncate(c.interval).Add(-c.interval)
+				c.collectOnce(metricTime)
+				timer.Next()
+			}()
+		case <-c.stopSig.C:
+			return
+		}
+	}
+}
+
+func (c *cgroupSysCollector) collectOnce(metricTime time.Time) {
+	begin := time.Now()
+	metrics := c.calcMetrics(metricTime)
+	calcCost := time.Now().Sub(begin)
+
+	err := c.send(metrics)
+
+	logger.Infoz("[cgroup] collect once done",
+		zap.Int("metrics", len(metrics)),   //
+		zap.Duration("calcCost", calcCost), //
+		zap.Duration("cost", time.Now().Sub(begin)),
+		zap.Error(err))
+}
+
+// calcMetrics reads stats of node and all local pods/containers, and calculates metrics using stats of last collection.
+func (c *cgroupSysCollector) calcMetrics(metricTime time.Time) []*model.Metric {
+	alignTs := metricTime.UnixMilli()
+	cgroupRoot := filepath.Join(c.hostfs, "sys", "fs", "cgroup")
+	procDir := filepath.Join(c.hostfs, "proc")
+	v2 := cgrouputil.IsV2(cgroupRoot)
+
+	lastStats := c.stats
+	newStats := make(map[string]*cgroupStats)
+	defer func() {
+		c.stats = newStats
+	}()
+
+	var metrics []*model.Metric
+	calc := func(key, metricPrefix string, tags map[string]string, s *cgroupStats, nodeCores float64, nodeMem uint64) {
+		newStats[key] = s
+		if last, ok := lastStats[key]; ok {
+			metrics = append(metrics, calcMetrics(metricPrefix, tags, last, s, nodeCores, nodeMem, alignTs)...)
+		}
+	}
+
+	nodeCores := float64(0)
+	nodeMem := uint64(0)
+	if s, err := readNodeStats(procDir); err != nil {
+		logger.Errorz("[cgroup] read node stats error", zap.Error(err))
+	} else {
+		nodeCores = s.CpuLimit
+		nodeMem = s.MemLimit
+		calc(nodeStatsKey, "k8s_node", c.extractNodeTags(), s, nodeCores, nodeMem)
+	}
+
+	containers := 0
+	for _, pod := range c.cri.GetAllPods() {
+		podTags := meta.ExtractPodCommonTags(pod.Pod)
+		var podPaths cgrouputil.Paths
+		for _, ctr := range pod.All {
+			if ctr.State.Pid <= 0 {
+				continue
+			}
+			paths, err := cgrouputil.ReadProcCgroup(cgroupRoot, procDir, v2, ctr.State.Pid)
+			if err != nil {
+				logger.Debugz("[cgroup] read proc cgroup error", zap.String("cid", ctr.Id), zap.Error(err))
+				continue
+			}
+			// container cgroup is the direct child of pod cgroup
+			if podPaths == nil {
+				podPaths = paths.Parent()
+			}
+			// sandbox holds no resource usage
+			if ctr.IsSandbox() {
+				continue
+			}
+			s, err := readCgroupStats(cgroupRoot, v2, paths)
+			if err != nil {
+				continue
+			}
+			containers++
+			tags := make(map[string]string, len(podTags)+1)
+			for k, v := range podTags {
+				tags[k] = v
+			}
+			tags["container"] = ctr.K8sContainerName
+			calc("container/"+ctr.Id, "k8s_container", tags, s, nodeCores, nodeMem)
+		}
+
+		if podPaths == nil {
+			continue
+		}
+		if s, err := readCgroupStats(cgroupRoot, v2, podPaths); err == nil {
+			calc("pod/"+string(pod.UID), "k8s_pod", podTags, s, nodeCores, nodeMem)
+		}
+	}
+
+	metrics = append(metrics, &model.Metric{
+		Name:      "k8s_node_containers",
+		Tags:      map[string]string{"ip": c.cri.LocalAgentMeta().NodeIP()},
+		Timestamp: alignTs,
+		Value:     float64(containers),
+	})
+	return metrics
+}
+
+func (c *cgroupSysCollector) extractNodeTags() map[string]string {
+	agentMeta := c.cri.LocalAgentMeta()
+	return common.ExtractNodeTags(agentMeta.NodeIP(), agentMeta.Node())
+}
+
+func (c *cgroupSysCollector) send(metrics []*model.Metric) error {
+	if c.suffix != "" {
+		for _, metric := range metrics {
+			if !strings.HasSuffix(metric.Name, c.suffix) {
+				metric.Name += c.suffix
+			}
+		}
+	}
+
+	return gateway.GetWriteService().WriteV1(context.Background(), &gateway.WriteV1Request{
+		Batch: metrics,
+	})
+}
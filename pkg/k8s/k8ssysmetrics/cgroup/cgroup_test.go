@@ -0,0 +1,185 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package cgroup
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/traas-stack/holoinsight-agent/pkg/cri"
+	"github.com/traas-stack/holoinsight-agent/pkg/util/cgrouputil"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type (
+	fakeMetaStore struct {
+		cri.MetaStore
+		pods []*cri.Pod
+	}
+	fakeAgentMeta struct {
+		cri.LocalAgentMeta
+	}
+)
+
+func (f *fakeMetaStore) GetAllPods() []*cri.Pod {
+	return f.pods
+}
+
+func (f *fakeMetaStore) LocalAgentMeta() cri.LocalAgentMeta {
+	return &fakeAgentMeta{}
+}
+
+func (f *fakeAgentMeta) NodeIP() string {
+	return "192.168.0.1"
+}
+
+func (f *fakeAgentMeta) Node() *v1.Node {
+	return nil
+}
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+func newFakePod() *cri.Pod {
+	pod := &cri.Pod{Pod: &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo", UID: "uid1"},
+		Status:     v1.PodStatus{Phase: v1.PodRunning, PodIP: "10.0.0.2"},
+	}}
+	sandbox := &cri.Container{Id: "sandbox", Pod: pod, ContainerRole: cri.ContainerRoleSandbox, State: cri.ContainerState{Pid: 100}}
+	app := &cri.Container{Id: "app", Pod: pod, K8sContainerName: "app", ContainerRole: cri.ContainerRoleBiz, State: cri.ContainerState{Pid: 200}}
+	pod.All = []*cri.Container{sandbox, app}
+	pod.Sandbox = sandbox
+	pod.Biz = []*cri.Container{app}
+	return pod
+}
+
+func nodeFiles(userTicks int) map[string]string {
+	return map[string]string{
+		"proc/stat":    "cpu  " + strconv.Itoa(userTicks) + " 0 100 1000 0 0 0 0 0 0\ncpu0 1 0 1 1 0 0 0 0 0 0\ncpu1 1 0 1 1 0 0 0 0 0 0\nintr 1\n",
+		"proc/meminfo": "MemTotal:       4096 kB\nMemFree:        1024 kB\nBuffers:         512 kB\nCached:          512 kB\nAnonPages:      2048 kB\n",
+	}
+}
+
+func metricsByName(collector *cgroupSysCollector) map[string]float64 {
+	values := make(map[string]float64)
+	for _, m := range collector.calcMetrics(time.Now()) {
+		key := m.Name
+		if c, ok := m.Tags["container"]; ok {
+			key += "/" + c
+		}
+		values[key] = m.Value
+	}
+	return values
+}
+
+func TestCgroupV2(t *testing.T) {
+	root := t.TempDir()
+	podDir := "sys/fs/cgroup/kubepods.slice/kubepods-pod1.slice"
+	files := map[string]string{
+		"sys/fs/cgroup/cgroup.controllers": "cpu memory io pids",
+		// The agent runs in a private cgroup namespace rooted at /kubepods.slice/kubepods-podA.slice/cri-containerd-agent.scope
+		"proc/100/cgroup":                                 "0::/../../kubepods-pod1.slice/cri-containerd-sandbox.scope\n",
+		"proc/200/cgroup":                                 "0::/../../kubepods-pod1.slice/cri-containerd-app.scope\n",
+		podDir + "/cpu.stat":                              "usage_usec 1000000\nuser_usec 800000\nsystem_usec 200000\nnr_throttled 1\nthrottled_usec 1000\n",
+		podDir + "/memory.current":                        "1048576\n",
+		podDir + "/memory.max":                            "max\n",
+		podDir + "/memory.stat":                           "anon 524288\nfile 262144\ninactive_file 131072\n",
+		podDir + "/cpu.pressure":                          "some avg10=0.00 avg60=0.00 avg300=0.00 total=1000\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n",
+		podDir + "/memory.pressure":                       "some avg10=0.00 avg60=0.00 avg300=0.00 total=0\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n",
+		podDir + "/cri-containerd-app.scope/cpu.stat":     "usage_usec 1000000\nuser_usec 800000\nsystem_usec 200000\n",
+		podDir + "/cri-containerd-app.scope/cpu.max":      "50000 100000\n",
+		podDir + "/cri-containerd-app.scope/memory.max":   "2097152\n",
+		podDir + "/cri-containerd-app.scope/io.stat":      "8:0 rbytes=1000 wbytes=2000 rios=1 wios=2\n",
+		podDir + "/cri-containerd-app.scope/pids.current": "12\n",
+	}
+	writeFiles(t, root, files)
+	writeFiles(t, root, nodeFiles(1000))
+
+	collector := NewPodSystemResourceCollector(&fakeMetaStore{pods: []*cri.Pod{newFakePod()}}, root, "", time.Minute).(*cgroupSysCollector)
+	values := metricsByName(collector)
+	// No stats of last collection
+	assert.Equal(t, map[string]float64{"k8s_node_containers": 1}, values)
+
+	// Make a time gap of 1s
+	for _, s := range collector.stats {
+		s.Time = s.Time.Add(-time.Second)
+	}
+	writeFiles(t, root, map[string]string{
+		podDir + "/cpu.stat":                          "usage_usec 1500000\nuser_usec 1200000\nsystem_usec 300000\nnr_throttled 3\nthrottled_usec 5000\n",
+		podDir + "/cpu.pressure":                      "some avg10=0.00 avg60=0.00 avg300=0.00 total=101000\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n",
+		podDir + "/cri-containerd-app.scope/cpu.stat": "usage_usec 1250000\nuser_usec 1000000\nsystem_usec 250000\n",
+		podDir + "/cri-containerd-app.scope/io.stat":  "8:0 rbytes=3000 wbytes=2000 rios=1 wios=2\n",
+	})
+	writeFiles(t, root, nodeFiles(1100))
+	values = metricsByName(collector)
+
+	// pod: 0.5 core in 1s, unlimited so node cores is used
+	assert.InDelta(t, 0.5, values["k8s_pod_cpu_inuse_cores"], 0.01)
+	assert.Equal(t, float64(2), values["k8s_pod_cpu_total_cores"])
+	assert.Equal(t, float64(2), values["k8s_pod_cpu_throttled_periods"])
+	assert.Equal(t, float64(4), values["k8s_pod_cpu_throttled_time"])
+	assert.Equal(t, float64(1048576-262144), values["k8s_pod_mem_used"])
+	assert.Equal(t, float64(1048576-131072), values["k8s_pod_mem_working_set"])
+	assert.Equal(t, float64(4096*1024), values["k8s_pod_mem_total"])
+	assert.InDelta(t, 10, values["k8s_pod_cpu_pressure_some"], 0.5)
+	assert.Equal(t, float64(0), values["k8s_pod_mem_pressure_full"])
+
+	// container: limited to 0.5 core
+	assert.InDelta(t, 0.25, values["k8s_container_cpu_inuse_cores/app"], 0.01)
+	assert.Equal(t, 0.5, values["k8s_container_cpu_total_cores/app"])
+	assert.InDelta(t, 50, values["k8s_container_cpu_util/app"], 1)
+	assert.Equal(t, float64(2097152), values["k8s_container_mem_total/app"])
+	assert.InDelta(t, 2000, values["k8s_container_io_read_bytes/app"], 50)
+	assert.Equal(t, float64(12), values["k8s_container_pids_current/app"])
+
+	// node: 100 ticks(1s) in 1s
+	assert.InDelta(t, 1, values["k8s_node_cpu_inuse_cores"], 0.05)
+	assert.Equal(t, float64(2048*1024), values["k8s_node_mem_used"])
+}
+
+func TestCgroupV1(t *testing.T) {
+	root := t.TempDir()
+	cpuDir := "sys/fs/cgroup/cpuacct/kubepods/pod1/app"
+	files := map[string]string{
+		"proc/200/cgroup":                                                       "12:pids:/kubepods/pod1/app\n4:cpu,cpuacct:/kubepods/pod1/app\n3:memory:/kubepods/pod1/app\n2:blkio:/kubepods/pod1/app\n1:name=systemd:/kubepods/pod1/app\n",
+		cpuDir + "/cpuacct.usage":                                               "1000000000\n",
+		cpuDir + "/cpuacct.stat":                                                "user 80\nsystem 20\n",
+		"sys/fs/cgroup/cpu/kubepods/pod1/app/cpu.cfs_quota_us":                  "200000\n",
+		"sys/fs/cgroup/cpu/kubepods/pod1/app/cpu.cfs_period_us":                 "100000\n",
+		"sys/fs/cgroup/memory/kubepods/pod1/app/memory.usage_in_bytes":          "1000\n",
+		"sys/fs/cgroup/memory/kubepods/pod1/app/memory.limit_in_bytes":          "9223372036854771712\n",
+		"sys/fs/cgroup/memory/kubepods/pod1/app/memory.stat":                    "cache 1\nrss 2\ntotal_cache 100\ntotal_rss 800\ntotal_inactive_file 50\n",
+		"sys/fs/cgroup/blkio/kubepods/pod1/app/blkio.throttle.io_service_bytes": "8:0 Read 10\n8:0 Write 20\n8:16 Read 5\nTotal 35\n",
+		"sys/fs/cgroup/pids/kubepods/pod1/app/pids.current":                     "7\n",
+	}
+	writeFiles(t, root, files)
+
+	paths, err := cgrouputil.ReadProcCgroup(filepath.Join(root, "sys/fs/cgroup"), filepath.Join(root, "proc"), false, 200)
+	assert.NoError(t, err)
+	assert.Equal(t, "/kubepods/pod1/app", paths["cpuacct"])
+	assert.Equal(t, "/kubepods/pod1", paths.Parent()["memory"])
+
+	root2 := filepath.Join(root, "sys/fs/cgroup")
+	assert.False(t, cgrouputil.IsV2(root2))
+	s, err := readCgroupStats(root2, false, paths)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1e9), s.CpuUsage)
+	assert.Equal(t, uint64(8e8), s.CpuUser)
+	assert.Equal(t, float64(2), s.CpuLimit)
+	assert.Equal(t, uint64(100), s.MemCache)
+	assert.Equal(t, uint64(800), s.MemRss)
+	assert.Equal(t, uint64(15), s.IoReadBytes)
+	assert.Equal(t, uint64(20), s.IoWriteBytes)
+	assert.Equal(t, uint64(7), s.PidsCurrent)
+}
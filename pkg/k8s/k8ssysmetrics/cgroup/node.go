@@ -0,0 +1,77 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package cgroup
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// readNodeStats reads node level stats from /proc/stat and /proc/meminfo, and converts them into cgroupStats
+// so that node metrics are calculated in the same way as pod and container metrics.
+func readNodeStats(procDir string) (*cgroupStats, error) {
+	s := &cgroupStats{Time: time.Now()}
+
+	content, err := os.ReadFile(filepath.Join(procDir, "stat"))
+	if err != nil {
+		return nil, err
+	}
+	cores := 0
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || !strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+		if fields[0] != "cpu" {
+			cores++
+			continue
+		}
+		// cpu user nice system idle iowait irq softirq steal guest guest_nice
+		var ticks [8]uint64
+		for j := range ticks {
+			if j+1 < len(fields) {
+				ticks[j], _ = strconv.ParseUint(fields[j+1], 10, 64)
+			}
+		}
+		tick := uint64(time.Second / userHz)
+		s.CpuUser = (ticks[0] + ticks[1]) * tick
+		s.CpuSys = (ticks[2] + ticks[5] + ticks[6]) * tick
+		s.CpuUsage = s.CpuUser + s.CpuSys + ticks[7]*tick
+	}
+	s.CpuLimit = float64(cores)
+
+	content, err = os.ReadFile(filepath.Join(procDir, "meminfo"))
+	if err != nil {
+		return nil, err
+	}
+	// values are in kB
+	mi := make(map[string]uint64)
+	scanner = bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			continue
+		}
+		if u64, err := strconv.ParseUint(fields[0], 10, 64); err == nil {
+			mi[key] = u64 * 1024
+		}
+	}
+	s.MemLimit = mi["MemTotal"]
+	s.MemUsage = mi["MemTotal"] - mi["MemFree"]
+	s.MemCache = mi["Buffers"] + mi["Cached"]
+	s.MemRss = mi["AnonPages"]
+	s.MemInactiveFile = mi["Inactive(file)"]
+	return s, nil
+}
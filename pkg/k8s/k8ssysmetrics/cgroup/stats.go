@@ -0,0 +1,275 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package cgroup
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/traas-stack/holoinsight-agent/pkg/util/cgrouputil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// userHz is the unit of cpuacct.stat, it is 100 on almost all platforms
+	userHz = 100
+)
+
+var (
+	// pressureResources are PSI files of cgroup v2
+	pressureResources = []string{"cpu", "memory", "io"}
+)
+
+type (
+	// cgroupStats is a snapshot of a cgroup, all cumulative values are kept raw and are used to calculate deltas.
+	cgroupStats struct {
+		Time time.Time
+		// CpuUsage, CpuUser, CpuSys are cumulative cpu time in nanoseconds
+		CpuUsage uint64
+		CpuUser  uint64
+		CpuSys   uint64
+		// CpuLimit is in cores, 0 means unlimited
+		CpuLimit            float64
+		CpuThrottledPeriods uint64
+		// CpuThrottledTime is in nanoseconds
+		CpuThrottledTime uint64
+		MemUsage         uint64
+		MemCache         uint64
+		MemRss           uint64
+		MemInactiveFile  uint64
+		// MemLimit 0 means unlimited
+		MemLimit     uint64
+		IoReadBytes  uint64
+		IoWriteBytes uint64
+		PidsCurrent  uint64
+		// Pressure is cumulative stall time in microseconds of cgroup v2 PSI, the key is like 'cpu_some' and 'memory_full'
+		Pressure map[string]uint64
+	}
+)
+
+// readCgroupStats reads stats of a cgroup, missing files are ignored.
+func readCgroupStats(cgroupRoot string, v2 bool, paths cgrouputil.Paths) (*cgroupStats, error) {
+	s := &cgroupStats{Time: time.Now()}
+	if v2 {
+		path, ok := paths[""]
+		if !ok {
+			return nil, cgrouputil.ErrNoCgroupPath
+		}
+		readV2Stats(filepath.Join(cgroupRoot, path), s)
+		return s, nil
+	}
+
+	dir := func(controller string) string {
+		path, ok := paths[controller]
+		if !ok {
+			return ""
+		}
+		return filepath.Join(cgroupRoot, controller, path)
+	}
+	if _, ok := paths["memory"]; !ok {
+		return nil, cgrouputil.ErrNoCgroupPath
+	}
+	readV1Stats(dir, s)
+	return s, nil
+}
+
+func readV1Stats(dir func(controller string) string, s *cgroupStats) {
+	if d := dir("cpuacct"); d != "" {
+		s.CpuUsage, _ = readUint(filepath.Join(d, "cpuacct.usage"))
+		if kv, err := readKeyValues(filepath.Join(d, "cpuacct.stat")); err == nil {
+			s.CpuUser = kv["user"] * uint64(time.Second/userHz)
+			s.CpuSys = kv["system"] * uint64(time.Second/userHz)
+		}
+	}
+	if d := dir("cpu"); d != "" {
+		quota, err1 := readInt(filepath.Join(d, "cpu.cfs_quota_us"))
+		period, err2 := readInt(filepath.Join(d, "cpu.cfs_period_us"))
+		if err1 == nil && err2 == nil && quota > 0 && period > 0 {
+			s.CpuLimit = float64(quota) / float64(period)
+		}
+		if kv, err := readKeyValues(filepath.Join(d, "cpu.stat")); err == nil {
+			s.CpuThrottledPeriods = kv["nr_throttled"]
+			s.CpuThrottledTime = kv["throttled_time"]
+		}
+	}
+	if d := dir("memory"); d != "" {
+		s.MemUsage, _ = readUint(filepath.Join(d, "memory.usage_in_bytes"))
+		s.MemLimit, _ = readUint(filepath.Join(d, "memory.limit_in_bytes"))
+		if kv, err := readKeyValues(filepath.Join(d, "memory.stat")); err == nil {
+			s.MemCache = kv["total_cache"]
+			s.MemRss = kv["total_rss"]
+			s.MemInactiveFile = kv["total_inactive_file"]
+		}
+	}
+	if d := dir("blkio"); d != "" {
+		s.IoReadBytes, s.IoWriteBytes = readBlkioServiceBytes(filepath.Join(d, "blkio.throttle.io_service_bytes_recursive"))
+		if s.IoReadBytes == 0 && s.IoWriteBytes == 0 {
+			s.IoReadBytes, s.IoWriteBytes = readBlkioServiceBytes(filepath.Join(d, "blkio.throttle.io_service_bytes"))
+		}
+	}
+	if d := dir("pids"); d != "" {
+		s.PidsCurrent, _ = readUint(filepath.Join(d, "pids.current"))
+	}
+}
+
+func readV2Stats(dir string, s *cgroupStats) {
+	if kv, err := readKeyValues(filepath.Join(dir, "cpu.stat")); err == nil {
+		s.CpuUsage = kv["usage_usec"] * uint64(time.Microsecond)
+		s.CpuUser = kv["user_usec"] * uint64(time.Microsecond)
+		s.CpuSys = kv["system_usec"] * uint64(time.Microsecond)
+		s.CpuThrottledPeriods = kv["nr_throttled"]
+		s.CpuThrottledTime = kv["throttled_usec"] * uint64(time.Microsecond)
+	}
+	// cpu.max: "max 100000" or "200000 100000"
+	if content, err := os.ReadFile(filepath.Join(dir, "cpu.max")); err == nil {
+		fields := strings.Fields(string(content))
+		if len(fields) == 2 && fields[0] != "max" {
+			quota, err1 := strconv.ParseFloat(fields[0], 64)
+			period, err2 := strconv.ParseFloat(fields[1], 64)
+			if err1 == nil && err2 == nil && period > 0 {
+				s.CpuLimit = quota / period
+			}
+		}
+	}
+
+	s.MemUsage, _ = readUint(filepath.Join(dir, "memory.current"))
+	// memory.max is "max" when unlimited, which fails to parse and leaves MemLimit 0
+	s.MemLimit, _ = readUint(filepath.Join(dir, "memory.max"))
+	if kv, err := readKeyValues(filepath.Join(dir, "memory.stat")); err == nil {
+		s.MemCache = kv["file"]
+		s.MemRss = kv["anon"]
+		s.MemInactiveFile = kv["inactive_file"]
+	}
+
+	// io.stat: "8:0 rbytes=1459200 wbytes=314773504 rios=192 wios=353 dbytes=0 dios=0"
+	if content, err := os.ReadFile(filepath.Join(dir, "io.stat")); err == nil {
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		for scanner.Scan() {
+			for _, field := range strings.Fields(scanner.Text()) {
+				key, value, ok := strings.Cut(field, "=")
+				if !ok {
+					continue
+				}
+				u64, err := strconv.ParseUint(value, 10, 64)
+				if err != nil {
+					continue
+				}
+				switch key {
+				case "rbytes":
+					s.IoReadBytes += u64
+				case "wbytes":
+					s.IoWriteBytes += u64
+				}
+			}
+		}
+	}
+
+	s.PidsCurrent, _ = readUint(filepath.Join(dir, "pids.current"))
+
+	for _, resource := range pressureResources {
+		content, err := os.ReadFile(filepath.Join(dir, resource+".pressure"))
+		if err != nil {
+			continue
+		}
+		for kind, total := range parsePressure(content) {
+			if s.Pressure == nil {
+				s.Pressure = make(map[string]uint64)
+			}
+			s.Pressure[resource+"_"+kind] = total
+		}
+	}
+}
+
+// parsePressure parses PSI file and returns kind -> total:
+//
+//	some avg10=0.00 avg60=0.00 avg300=0.00 total=12345
+//	full avg10=0.00 avg60=0.00 avg300=0.00 total=2345
+func parsePressure(content []byte) map[string]uint64 {
+	ret := make(map[string]uint64, 2)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		for _, field := range fields[1:] {
+			if value, ok := strings.CutPrefix(field, "total="); ok {
+				if u64, err := strconv.ParseUint(value, 10, 64); err == nil {
+					ret[fields[0]] = u64
+				}
+			}
+		}
+	}
+	return ret
+}
+
+// readBlkioServiceBytes sums 'Read' and 'Write' bytes of all devices:
+//
+//	8:0 Read 1459200
+//	8:0 Write 314773504
+//	Total 316232704
+func readBlkioServiceBytes(path string) (read uint64, write uint64) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			continue
+		}
+		u64, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[1] {
+		case "Read":
+			read += u64
+		case "Write":
+			write += u64
+		}
+	}
+	return
+}
+
+// readKeyValues reads file like memory.stat or cpu.stat, each line is 'key value'
+func readKeyValues(path string) (map[string]uint64, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string]uint64)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if u64, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			ret[fields[0]] = u64
+		}
+	}
+	return ret, scanner.Err()
+}
+
+func readUint(path string) (uint64, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+}
+
+func readInt(path string) (int64, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64)
+}
@@ -0,0 +1,28 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package common
+
+import (
+	"github.com/traas-stack/holoinsight-agent/pkg/k8s/k8slabels"
+	"github.com/traas-stack/holoinsight-agent/pkg/k8s/k8smeta/extractor"
+	v1 "k8s.io/api/core/v1"
+)
+
+// ExtractNodeTags returns tags of node level metrics
+func ExtractNodeTags(ip string, node *v1.Node) map[string]string {
+	tags := map[string]string{
+		"ip": ip,
+	}
+	if node != nil {
+		tags["name"] = node.Name
+		tags["hostname"] = extractor.PodMetaServiceInstance.NodeHostname(node)
+		tags["region"] = k8slabels.GetRegion(node.Labels)
+		tags["zone"] = k8slabels.GetZone(node.Labels)
+		tags["os"] = node.Labels[k8slabels.LabelK8sOs]
+		tags["arch"] = node.Labels[k8slabels.LabelK8sArch]
+		tags["instanceType"] = node.Labels[k8slabels.LabelK8sNodeInstanceType]
+	}
+	return tags
+}
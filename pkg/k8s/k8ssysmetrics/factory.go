@@ -8,6 +8,7 @@ import (
 	"os"
 )
 
+// GetNewPodSystemResourceCollector returns the name of system resource collector, available values are "cadvisor", "cgroup" and "kubelet"
 func GetNewPodSystemResourceCollector() string {
 	if s := os.Getenv("HI_K8S_SYS_COLLECTOR"); s != "" {
 		return s
@@ -0,0 +1,466 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package kubelet
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"github.com/traas-stack/holoinsight-agent/pkg/appconfig"
+	"github.com/traas-stack/holoinsight-agent/pkg/cri"
+	"github.com/traas-stack/holoinsight-agent/pkg/k8s/k8ssysmetrics/common"
+	"github.com/traas-stack/holoinsight-agent/pkg/logger"
+	"github.com/traas-stack/holoinsight-agent/pkg/meta"
+	"github.com/traas-stack/holoinsight-agent/pkg/model"
+	"github.com/traas-stack/holoinsight-agent/pkg/plugin/output/gateway"
+	"github.com/traas-stack/holoinsight-agent/pkg/util"
+	"go.uber.org/zap"
+	"io"
+	v1 "k8s.io/api/core/v1"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultKubeletURL = "http://%s:10255"
+	summaryPath       = "/stats/summary"
+	resourcePath      = "/metrics/resource"
+	tokenFile         = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	defaultTimeout    = 10 * time.Second
+)
+
+type (
+	// kubeletSysCollector collects pod/container/volume stats from kubelet '/stats/summary'.
+	// If summary api is unavailable, '/metrics/resource' is used and only cpu and memory metrics are reported.
+	kubeletSysCollector struct {
+		cri      cri.Interface
+		baseURL  string
+		client   *http.Client
+		suffix   string
+		interval time.Duration
+		stopSig  *util.StopSignal
+		mutex    sync.Mutex
+		// cpu stats of last collection, used when usageNanoCores is absent, key is 'node', '<ns>/<pod>' or '<ns>/<pod>/<container>'
+		lastCpu map[string]*CPUStats
+		timer   *util.AlignedTimer
+	}
+	kubeletSysCollectorStateObj struct {
+		LastCpu    map[string]*CPUStats
+		TimerBytes []byte
+	}
+	// resourceLimits holds cpu (in cores) and memory (in bytes) limits, 0 means unlimited
+	resourceLimits struct {
+		cpu float64
+		mem float64
+	}
+)
+
+func init() {
+	gob.Register(&kubeletSysCollectorStateObj{})
+}
+
+// GetKubeletURL returns the base url of kubelet, '%s' in it is replaced with node ip.
+// It can be specified by env 'HI_KUBELET_URL', otherwise it is derived from 'getPodsFromKubeletAPI' config.
+func GetKubeletURL() string {
+	if s := os.Getenv("HI_KUBELET_URL"); s != "" {
+		return s
+	}
+	if s := appconfig.StdAgentConfig.K8s.Cri.GetPodsFromKubeletAPI; s != "" {
+		return strings.TrimSuffix(s, "/pods")
+	}
+	return defaultKubeletURL
+}
+
+func NewPodSystemResourceCollector(cri cri.Interface, baseURL string, suffix string, interval time.Duration) common.SysCollector {
+	timer, _ := util.NewAlignedTimer(interval, time.Second, false, false)
+
+	return &kubeletSysCollector{
+		cri:     cri,
+		baseURL: baseURL,
+		client: &http.Client{
+			Timeout: defaultTimeout,
+			Transport: &http.Transport{
+				// kubelet serving certificate is usually self-signed
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		},
+		suffix:   suffix,
+		interval: interval,
+		stopSig:  util.NewStopSignal(),
+		timer:    timer,
+	}
+}
+
+func (c *kubeletSysCollector) Name() string {
+	return "kubelet"
+}
+
+func (c *kubeletSysCollector) SaveState() (interface{}, error) {
+	timerBytes, err := c.timer.SaveState()
+	if err != nil {
+		return nil, err
+	}
+	return &kubeletSysCollectorStateObj{
+		LastCpu:    c.lastCpu,
+		TimerBytes: timerBytes,
+	}, nil
+}
+
+func (c *kubeletSysCollector) LoadState(i interface{}) error {
+	if i == nil {
+		return nil
+	}
+	state := i.(*kubeletSysCollectorStateObj)
+	c.lastCpu = state.LastCpu
+	return c.timer.LoadState(state.TimerBytes)
+}
+
+func (c *kubeletSysCollector) Start() {
+	go c.taskLoop()
+}
+
+func (c *kubeletSysCollector) Stop() {
+	c.stopSig.StopAndWait()
+}
+
+func (c *kubeletSysCollector) taskLoop() {
+	defer c.stopSig.StopDone()
+
+	timer := c.timer
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			// executes at {interval+1s, 2*interval+1s, ...}
+			func() {
+				c.mutex.Lock()
+				defer c.mutex.Unlock()
+				if c.stopSig.IsStopAsked() {
+					return
+				}
+				metricTime := timer.NextEmitTime().Truncate(c.interval).Add(-c.interval)
+				c.collectOnce(metricTime)
+				timer.Next()
+			}()
+		case <-c.stopSig.C:
+			return
+		}
+	}
+}
+
+func (c *kubeletSysCollector) collectOnce(metricTime time.Time) {
+	begin := time.Now()
+
+	var metrics []*model.Metric
+	source := summaryPath
+	summary, err := c.querySummary()
+	if err != nil {
+		logger.Warnz("[kubelet] query summary error, fallback to resource metrics", zap.Error(err))
+		source = resourcePath
+		summary, err = c.queryResourceMetrics()
+	}
+	queryCost := time.Now().Sub(begin)
+
+	if err == nil {
+		metrics = c.calcMetrics(metricTime, summary)
+		err = c.send(metrics)
+	}
+
+	logger.Infoz("[kubelet] collect once done",
+		zap.String("source", source),         //
+		zap.Int("metrics", len(metrics)),     //
+		zap.Duration("queryCost", queryCost), //
+		zap.Duration("cost", time.Now().Sub(begin)),
+		zap.Error(err))
+}
+
+func (c *kubeletSysCollector) url(path string) string {
+	base := c.baseURL
+	if strings.Contains(base, "%s") {
+		base = fmt.Sprintf(base, c.cri.LocalAgentMeta().NodeIP())
+	}
+	return strings.TrimSuffix(base, "/") + path
+}
+
+func (c *kubeletSysCollector) get(path string) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, c.url(path), nil)
+	if err != nil {
+		return nil, err
+	}
+	// Secure port requires authentication, use service account token of agent
+	if strings.HasPrefix(req.URL.Scheme, "https") {
+		if token, err := os.ReadFile(tokenFile); err == nil {
+			req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+		}
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("bad response code=[%d] url=[%s]", resp.StatusCode, req.URL)
+	}
+	return resp.Body, nil
+}
+
+func (c *kubeletSysCollector) querySummary() (*Summary, error) {
+	body, err := c.get(summaryPath)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	summary := &Summary{}
+	if err := json.NewDecoder(body).Decode(summary); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+func (c *kubeletSysCollector) queryResourceMetrics() (*Summary, error) {
+	body, err := c.get(resourcePath)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return parseResourceMetrics(body)
+}
+
+func (c *kubeletSysCollector) calcMetrics(metricTime time.Time, summary *Summary) []*model.Metric {
+	alignTs := metricTime.UnixMilli()
+	lastCpu := c.lastCpu
+	newCpu := make(map[string]*CPUStats)
+	defer func() {
+		c.lastCpu = newCpu
+	}()
+
+	var metrics []*model.Metric
+	add := func(name string, tags map[string]string, value float64) {
+		metrics = append(metrics, &model.Metric{
+			Name:      name,
+			Tags:      tags,
+			Timestamp: alignTs,
+			Value:     value,
+		})
+	}
+	// cpuCores returns cpu usage in cores
+	cpuCores := func(key string, s *CPUStats) (float64, bool) {
+		if s == nil {
+			return 0, false
+		}
+		if s.UsageNanoCores != nil {
+			return float64(*s.UsageNanoCores) / 1e9, true
+		}
+		if s.UsageCoreNanoSeconds == nil {
+			return 0, false
+		}
+		newCpu[key] = s
+		last, ok := lastCpu[key]
+		if !ok || last.UsageCoreNanoSeconds == nil || !s.Time.After(last.Time) || *s.UsageCoreNanoSeconds < *last.UsageCoreNanoSeconds {
+			return 0, false
+		}
+		return float64(*s.UsageCoreNanoSeconds-*last.UsageCoreNanoSeconds) / float64(s.Time.Sub(last.Time)), true
+	}
+	addCpuMem := func(prefix string, tags map[string]string, key string, cpu *CPUStats, mem *MemoryStats, limits resourceLimits) {
+		if cores, ok := cpuCores(key, cpu); ok {
+			add(prefix+"_cpu_inuse_cores", tags, cores)
+			if limits.cpu > 0 {
+				add(prefix+"_cpu_total_cores", tags, limits.cpu)
+				add(prefix+"_cpu_util", tags, math.Min(cores/limits.cpu*100, 100))
+			}
+		}
+		if mem == nil {
+			return
+		}
+		if limits.mem > 0 {
+			add(prefix+"_mem_total", tags, limits.mem)
+		}
+		if mem.RSSBytes != nil {
+			add(prefix+"_mem_rss", tags, float64(*mem.RSSBytes))
+		}
+		// Summary has no page cache, so mem_used is working set (usage minus inactive file), which is also what kubelet evicts by
+		if mem.WorkingSetBytes == nil {
+			return
+		}
+		used := float64(*mem.WorkingSetBytes)
+		add(prefix+"_mem_working_set", tags, used)
+		add(prefix+"_mem_used", tags, used)
+		if limits.mem > 0 {
+			add(prefix+"_mem_util", tags, math.Min(used/limits.mem*100, 100))
+		}
+	}
+
+	agentMeta := c.cri.LocalAgentMeta()
+	node := agentMeta.Node()
+	nodeLimits := getNodeLimits(node)
+
+	// node
+	{
+		tags := common.ExtractNodeTags(agentMeta.NodeIP(), node)
+		addCpuMem("k8s_node", tags, "node", summary.Node.CPU, summary.Node.Memory, nodeLimits)
+		addFsStats("k8s_node_fs", tags, summary.Node.Fs, add)
+	}
+
+	containers := 0
+	for i := range summary.Pods {
+		ps := &summary.Pods[i]
+		pod, err := c.cri.GetPod(ps.PodRef.Namespace, ps.PodRef.Name)
+		if err != nil {
+			continue
+		}
+		podTags := meta.ExtractPodCommonTags(pod.Pod)
+		podKey := ps.PodRef.Namespace + "/" + ps.PodRef.Name
+
+		addCpuMem("k8s_pod", podTags, podKey, ps.CPU, ps.Memory, getPodLimits(pod.Pod, nodeLimits))
+		if ps.EphemeralStorage != nil {
+			addFsStats("k8s_pod_ephemeral_storage", podTags, ps.EphemeralStorage, add)
+		}
+
+		for j := range ps.VolumeStats {
+			vs := &ps.VolumeStats[j]
+			// Only persistent volumes are reported, others such as configmap and secret are meaningless
+			if vs.PVCRef == nil {
+				continue
+			}
+			tags := copyTags(podTags)
+			tags["volume"] = vs.Name
+			tags["pvc"] = vs.PVCRef.Name
+			addFsStats("k8s_pod_volume", tags, &vs.FsStats, add)
+		}
+
+		restarts := make(map[string]int32, len(pod.Status.ContainerStatuses))
+		for _, status := range pod.Status.ContainerStatuses {
+			restarts[status.Name] = status.RestartCount
+		}
+
+		for j := range ps.Containers {
+			cs := &ps.Containers[j]
+			containers++
+			tags := copyTags(podTags)
+			tags["container"] = cs.Name
+			addCpuMem("k8s_container", tags, podKey+"/"+cs.Name, cs.CPU, cs.Memory, getContainerLimits(pod.Pod, cs.Name, nodeLimits))
+			if cs.Rootfs != nil && cs.Rootfs.UsedBytes != nil {
+				add("k8s_container_rootfs_used", tags, float64(*cs.Rootfs.UsedBytes))
+			}
+			if cs.Logs != nil && cs.Logs.UsedBytes != nil {
+				add("k8s_container_logs_used", tags, float64(*cs.Logs.UsedBytes))
+			}
+			if count, ok := restarts[cs.Name]; ok {
+				add("k8s_container_restarts", tags, float64(count))
+			}
+		}
+	}
+
+	add("k8s_node_containers", map[string]string{"ip": agentMeta.NodeIP()}, float64(containers))
+	return metrics
+}
+
+// addFsStats adds usage and inodes of a filesystem
+func addFsStats(prefix string, tags map[string]string, fs *FsStats, add func(string, map[string]string, float64)) {
+	if fs == nil {
+		return
+	}
+	if fs.UsedBytes != nil {
+		add(prefix+"_used", tags, float64(*fs.UsedBytes))
+	}
+	if fs.CapacityBytes != nil && *fs.CapacityBytes > 0 {
+		add(prefix+"_capacity", tags, float64(*fs.CapacityBytes))
+		if fs.UsedBytes != nil {
+			add(prefix+"_util", tags, float64(*fs.UsedBytes)/float64(*fs.CapacityBytes)*100)
+		}
+	}
+	if fs.AvailableBytes != nil {
+		add(prefix+"_available", tags, float64(*fs.AvailableBytes))
+	}
+	if fs.InodesUsed != nil {
+		add(prefix+"_inodes_used", tags, float64(*fs.InodesUsed))
+	}
+	if fs.Inodes != nil {
+		add(prefix+"_inodes", tags, float64(*fs.Inodes))
+	}
+}
+
+func getNodeLimits(node *v1.Node) resourceLimits {
+	if node == nil {
+		return resourceLimits{}
+	}
+	return resourceLimits{
+		cpu: node.Status.Capacity.Cpu().AsApproximateFloat64(),
+		mem: node.Status.Capacity.Memory().AsApproximateFloat64(),
+	}
+}
+
+// getPodLimits returns sum of container limits, node limits is returned if any container is unlimited
+func getPodLimits(pod *v1.Pod, nodeLimits resourceLimits) resourceLimits {
+	var cpu, mem float64
+	cpuUnlimited, memUnlimited := false, false
+	for i := range pod.Spec.Containers {
+		limits := pod.Spec.Containers[i].Resources.Limits
+		if q, ok := limits[v1.ResourceCPU]; ok && !q.IsZero() {
+			cpu += q.AsApproximateFloat64()
+		} else {
+			cpuUnlimited = true
+		}
+		if q, ok := limits[v1.ResourceMemory]; ok && !q.IsZero() {
+			mem += q.AsApproximateFloat64()
+		} else {
+			memUnlimited = true
+		}
+	}
+	ret := resourceLimits{cpu: cpu, mem: mem}
+	if cpuUnlimited || (nodeLimits.cpu > 0 && cpu > nodeLimits.cpu) {
+		ret.cpu = nodeLimits.cpu
+	}
+	if memUnlimited || (nodeLimits.mem > 0 && mem > nodeLimits.mem) {
+		ret.mem = nodeLimits.mem
+	}
+	return ret
+}
+
+func getContainerLimits(pod *v1.Pod, name string, nodeLimits resourceLimits) resourceLimits {
+	ret := nodeLimits
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name != name {
+			continue
+		}
+		limits := pod.Spec.Containers[i].Resources.Limits
+		if q, ok := limits[v1.ResourceCPU]; ok && !q.IsZero() {
+			ret.cpu = q.AsApproximateFloat64()
+		}
+		if q, ok := limits[v1.ResourceMemory]; ok && !q.IsZero() {
+			ret.mem = q.AsApproximateFloat64()
+		}
+	}
+	return ret
+}
+
+func copyTags(tags map[string]string) map[string]string {
+	ret := make(map[string]string, len(tags)+2)
+	for k, v := range tags {
+		ret[k] = v
+	}
+	return ret
+}
+
+func (c *kubeletSysCollector) send(metrics []*model.Metric) error {
+	if c.suffix != "" {
+		for _, metric := range metrics {
+			if !strings.HasSuffix(metric.Name, c.suffix) {
+				metric.Name += c.suffix
+			}
+		}
+	}
+
+	return gateway.GetWriteService().WriteV1(context.Background(), &gateway.WriteV1Request{
+		Batch: metrics,
+	})
+}
@@ -0,0 +1,175 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package kubelet
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/traas-stack/holoinsight-agent/pkg/cri"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testSummary = `{
+  "node": {"nodeName": "node1", "cpu": {"usageNanoCores": 2000000000}, "memory": {"usageBytes": 5368709120, "workingSetBytes": 4831838208, "rssBytes": 4294967296},
+    "fs": {"capacityBytes": 1000, "usedBytes": 250, "availableBytes": 750, "inodes": 100, "inodesUsed": 10}},
+  "pods": [{
+    "podRef": {"name": "demo", "namespace": "default", "uid": "uid1"},
+    "cpu": {"usageNanoCores": 500000000},
+    "memory": {"usageBytes": 805306368, "workingSetBytes": 536870912, "rssBytes": 268435456},
+    "containers": [{"name": "app", "cpu": {"usageNanoCores": 400000000}, "memory": {"usageBytes": 314572800, "workingSetBytes": 268435456, "rssBytes": 209715200},
+      "rootfs": {"usedBytes": 4096}, "logs": {"usedBytes": 1024}}],
+    "volume": [
+      {"name": "data", "usedBytes": 30, "capacityBytes": 100, "inodesUsed": 3, "inodes": 10, "pvcRef": {"name": "data-demo", "namespace": "default"}},
+      {"name": "kube-api-access", "usedBytes": 1}
+    ],
+    "ephemeral-storage": {"usedBytes": 5120, "inodesUsed": 5}
+  }, {
+    "podRef": {"name": "unknown", "namespace": "default", "uid": "uid2"}
+  }]
+}`
+	testResourceMetrics = `# TYPE container_cpu_usage_seconds_total counter
+container_cpu_usage_seconds_total{container="app",namespace="default",pod="demo"} %s 1690000000000
+# TYPE container_memory_working_set_bytes gauge
+container_memory_working_set_bytes{container="app",namespace="default",pod="demo"} 1024 1690000000000
+# TYPE node_cpu_usage_seconds_total counter
+node_cpu_usage_seconds_total 1000 1690000000000
+# TYPE pod_cpu_usage_seconds_total counter
+pod_cpu_usage_seconds_total{namespace="default",pod="demo"} 20 1690000000000
+`
+)
+
+type (
+	fakeCri struct {
+		cri.Interface
+		pod *cri.Pod
+	}
+	fakeAgentMeta struct {
+		cri.LocalAgentMeta
+	}
+)
+
+func (f *fakeCri) GetPod(namespace, podName string) (*cri.Pod, error) {
+	if f.pod.Namespace == namespace && f.pod.Name == podName {
+		return f.pod, nil
+	}
+	return nil, cri.NoPodError(namespace, podName)
+}
+
+func (f *fakeCri) LocalAgentMeta() cri.LocalAgentMeta {
+	return &fakeAgentMeta{}
+}
+
+func (f *fakeAgentMeta) NodeIP() string {
+	return "127.0.0.1"
+}
+
+func (f *fakeAgentMeta) Node() *v1.Node {
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+		Status: v1.NodeStatus{Capacity: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("4"),
+			v1.ResourceMemory: resource.MustParse("8Gi"),
+		}},
+	}
+}
+
+func newFakeCri() *fakeCri {
+	return &fakeCri{pod: &cri.Pod{Pod: &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo"},
+		Spec: v1.PodSpec{Containers: []v1.Container{{
+			Name: "app",
+			Resources: v1.ResourceRequirements{Limits: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("1"),
+				v1.ResourceMemory: resource.MustParse("1Gi"),
+			}},
+		}}},
+		Status: v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{{Name: "app", RestartCount: 2}}},
+	}}}
+}
+
+func metricValues(c *kubeletSysCollector, summary *Summary) map[string]float64 {
+	values := make(map[string]float64)
+	for _, m := range c.calcMetrics(time.Now(), summary) {
+		key := m.Name
+		for _, tag := range []string{"container", "pvc"} {
+			if v, ok := m.Tags[tag]; ok {
+				key += "/" + v
+			}
+		}
+		values[key] = m.Value
+	}
+	return values
+}
+
+func TestSummary(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != summaryPath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(testSummary))
+	}))
+	defer server.Close()
+
+	c := NewPodSystemResourceCollector(newFakeCri(), strings.Replace(server.URL, "127.0.0.1", "%s", 1), "", time.Minute).(*kubeletSysCollector)
+	summary, err := c.querySummary()
+	assert.NoError(t, err)
+	values := metricValues(c, summary)
+
+	assert.Equal(t, float64(2), values["k8s_node_cpu_inuse_cores"])
+	assert.Equal(t, float64(50), values["k8s_node_cpu_util"])
+	assert.Equal(t, 56.25, values["k8s_node_mem_util"])
+	assert.Equal(t, float64(25), values["k8s_node_fs_util"])
+
+	assert.Equal(t, 0.5, values["k8s_pod_cpu_inuse_cores"])
+	assert.Equal(t, float64(50), values["k8s_pod_cpu_util"])
+	// mem_used is working set, not rss
+	assert.Equal(t, float64(50), values["k8s_pod_mem_util"])
+	assert.Equal(t, float64(268435456), values["k8s_pod_mem_rss"])
+	assert.Equal(t, float64(536870912), values["k8s_pod_mem_used"])
+	assert.Equal(t, float64(536870912), values["k8s_pod_mem_working_set"])
+	assert.NotContains(t, values, "k8s_pod_mem_cache")
+	assert.Equal(t, float64(5120), values["k8s_pod_ephemeral_storage_used"])
+	assert.Equal(t, float64(5), values["k8s_pod_ephemeral_storage_inodes_used"])
+	assert.Equal(t, float64(30), values["k8s_pod_volume_used/data-demo"])
+	assert.Equal(t, float64(30), values["k8s_pod_volume_util/data-demo"])
+	assert.Equal(t, float64(3), values["k8s_pod_volume_inodes_used/data-demo"])
+
+	assert.Equal(t, float64(40), values["k8s_container_cpu_util/app"])
+	assert.Equal(t, float64(25), values["k8s_container_mem_util/app"])
+	assert.Equal(t, float64(4096), values["k8s_container_rootfs_used/app"])
+	assert.Equal(t, float64(1024), values["k8s_container_logs_used/app"])
+	assert.Equal(t, float64(2), values["k8s_container_restarts/app"])
+	assert.Equal(t, float64(1), values["k8s_node_containers"])
+}
+
+func TestResourceMetrics(t *testing.T) {
+	c := NewPodSystemResourceCollector(newFakeCri(), "", "", time.Minute).(*kubeletSysCollector)
+
+	summary, err := parseResourceMetrics(strings.NewReader(strings.Replace(testResourceMetrics, "%s", "10", 1)))
+	assert.NoError(t, err)
+	assert.Len(t, summary.Pods, 1)
+	values := metricValues(c, summary)
+	// cpu usage is cumulative, no value for the first time
+	assert.NotContains(t, values, "k8s_container_cpu_inuse_cores/app")
+	assert.Equal(t, float64(1024), values["k8s_container_mem_working_set/app"])
+	assert.Equal(t, float64(1024), values["k8s_container_mem_used/app"])
+
+	next := strings.Replace(testResourceMetrics, "%s", "40", 1)
+	next = strings.ReplaceAll(next, "1690000000000", "1690000060000")
+	summary, err = parseResourceMetrics(strings.NewReader(next))
+	assert.NoError(t, err)
+	values = metricValues(c, summary)
+	// 30 cpu seconds in 60 seconds
+	assert.Equal(t, 0.5, values["k8s_container_cpu_inuse_cores/app"])
+	assert.Equal(t, float64(0), values["k8s_pod_cpu_inuse_cores"])
+}
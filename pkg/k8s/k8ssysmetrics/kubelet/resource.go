@@ -0,0 +1,118 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package kubelet
+
+import (
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"io"
+	"time"
+)
+
+// parseResourceMetrics converts kubelet '/metrics/resource' into a Summary which only contains cpu and memory stats.
+// Cpu usage in '/metrics/resource' is cumulative, so only UsageCoreNanoSeconds is filled.
+func parseResourceMetrics(r io.Reader) (*Summary, error) {
+	families, err := (&expfmt.TextParser{}).TextToMetricFamilies(r)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Summary{}
+	pods := make(map[PodReference]*PodStats)
+	var orderedPods []*PodStats
+	getPod := func(m *dto.Metric) *PodStats {
+		ref := PodReference{Namespace: label(m, "namespace"), Name: label(m, "pod")}
+		if p, ok := pods[ref]; ok {
+			return p
+		}
+		p := &PodStats{PodRef: ref}
+		pods[ref] = p
+		orderedPods = append(orderedPods, p)
+		return p
+	}
+	getContainer := func(m *dto.Metric) *ContainerStats {
+		p := getPod(m)
+		name := label(m, "container")
+		for i := range p.Containers {
+			if p.Containers[i].Name == name {
+				return &p.Containers[i]
+			}
+		}
+		p.Containers = append(p.Containers, ContainerStats{Name: name})
+		return &p.Containers[len(p.Containers)-1]
+	}
+
+	for _, m := range families["node_cpu_usage_seconds_total"].GetMetric() {
+		s.Node.CPU = cpuStats(sampleOf(m))
+	}
+	for _, m := range families["node_memory_working_set_bytes"].GetMetric() {
+		s.Node.Memory = memoryStats(sampleOf(m))
+	}
+	for _, name := range []string{"pod_cpu_usage_seconds_total", "pod_memory_working_set_bytes"} {
+		for _, m := range families[name].GetMetric() {
+			value, t := sampleOf(m)
+			p := getPod(m)
+			if name == "pod_cpu_usage_seconds_total" {
+				p.CPU = cpuStats(value, t)
+			} else {
+				p.Memory = memoryStats(value, t)
+			}
+		}
+	}
+	for _, name := range []string{"container_cpu_usage_seconds_total", "container_memory_working_set_bytes"} {
+		for _, m := range families[name].GetMetric() {
+			if _, ok := pods[PodReference{Namespace: label(m, "namespace"), Name: label(m, "pod")}]; !ok {
+				// no pod level stats
+				continue
+			}
+			value, t := sampleOf(m)
+			c := getContainer(m)
+			if name == "container_cpu_usage_seconds_total" {
+				c.CPU = cpuStats(value, t)
+			} else {
+				c.Memory = memoryStats(value, t)
+			}
+		}
+	}
+
+	for _, p := range orderedPods {
+		s.Pods = append(s.Pods, *p)
+	}
+	return s, nil
+}
+
+func label(m *dto.Metric, name string) string {
+	for _, l := range m.GetLabel() {
+		if l.GetName() == name {
+			return l.GetValue()
+		}
+	}
+	return ""
+}
+
+func sampleOf(m *dto.Metric) (float64, time.Time) {
+	t := time.Now()
+	if m.TimestampMs != nil {
+		t = time.UnixMilli(m.GetTimestampMs())
+	}
+	switch {
+	case m.Counter != nil:
+		return m.Counter.GetValue(), t
+	case m.Gauge != nil:
+		return m.Gauge.GetValue(), t
+	default:
+		return m.GetUntyped().GetValue(), t
+	}
+}
+
+func cpuStats(seconds float64, t time.Time) *CPUStats {
+	ns := uint64(seconds * float64(time.Second))
+	return &CPUStats{Time: t, UsageCoreNanoSeconds: &ns}
+}
+
+func memoryStats(bytes float64, t time.Time) *MemoryStats {
+	u64 := uint64(bytes)
+	return &MemoryStats{Time: t, WorkingSetBytes: &u64}
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package kubelet
+
+import "time"
+
+// These types are a subset of k8s.io/kubelet/pkg/apis/stats/v1alpha1.
+// They are copied here to avoid depending on k8s.io/kubelet.
+type (
+	Summary struct {
+		Node NodeStats  `json:"node"`
+		Pods []PodStats `json:"pods"`
+	}
+	NodeStats struct {
+		NodeName string       `json:"nodeName"`
+		CPU      *CPUStats    `json:"cpu,omitempty"`
+		Memory   *MemoryStats `json:"memory,omitempty"`
+		Fs       *FsStats     `json:"fs,omitempty"`
+	}
+	PodReference struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+		UID       string `json:"uid"`
+	}
+	PodStats struct {
+		PodRef           PodReference     `json:"podRef"`
+		Containers       []ContainerStats `json:"containers"`
+		CPU              *CPUStats        `json:"cpu,omitempty"`
+		Memory           *MemoryStats     `json:"memory,omitempty"`
+		VolumeStats      []VolumeStats    `json:"volume,omitempty"`
+		EphemeralStorage *FsStats         `json:"ephemeral-storage,omitempty"`
+	}
+	ContainerStats struct {
+		Name   string       `json:"name"`
+		CPU    *CPUStats    `json:"cpu,omitempty"`
+		Memory *MemoryStats `json:"memory,omitempty"`
+		Rootfs *FsStats     `json:"rootfs,omitempty"`
+		Logs   *FsStats     `json:"logs,omitempty"`
+	}
+	CPUStats struct {
+		Time                 time.Time `json:"time"`
+		UsageNanoCores       *uint64   `json:"usageNanoCores,omitempty"`
+		UsageCoreNanoSeconds *uint64   `json:"usageCoreNanoSeconds,omitempty"`
+	}
+	MemoryStats struct {
+		Time            time.Time `json:"time"`
+		AvailableBytes  *uint64   `json:"availableBytes,omitempty"`
+		UsageBytes      *uint64   `json:"usageBytes,omitempty"`
+		WorkingSetBytes *uint64   `json:"workingSetBytes,omitempty"`
+		RSSBytes        *uint64   `json:"rssBytes,omitempty"`
+	}
+	FsStats struct {
+		AvailableBytes *uint64 `json:"availableBytes,omitempty"`
+		CapacityBytes  *uint64 `json:"capacityBytes,omitempty"`
+		UsedBytes      *uint64 `json:"usedBytes,omitempty"`
+		InodesFree     *uint64 `json:"inodesFree,omitempty"`
+		Inodes         *uint64 `json:"inodes,omitempty"`
+		InodesUsed     *uint64 `json:"inodesUsed,omitempty"`
+	}
+	PVCReference struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	}
+	VolumeStats struct {
+		FsStats
+		Name   string        `json:"name,omitempty"`
+		PVCRef *PVCReference `json:"pvcRef,omitempty"`
+	}
+)
@@ -24,6 +24,8 @@ type (
 		Count       int            `json:"count,omitempty"`
 		SourceWords []*SourceWord  `json:"sourceWords,omitempty"`
 		Sources     map[string]int `json:"-"`
+		// New is true if this pattern is seen for the first time
+		New bool `json:"new,omitempty"`
 	}
 	SourceWord struct {
 		Source string `json:"source"`
@@ -0,0 +1,310 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package loganalysis
+
+import (
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultDrainDepth        = 4
+	DefaultDrainSimThreshold = 0.4
+	DefaultDrainMaxChildren  = 100
+	DefaultDrainMaxClusters  = 512
+
+	// DrainWildcard is the placeholder of variable tokens in templates
+	DrainWildcard = "<*>"
+)
+
+type (
+	// DrainMiner mines log templates using Drain algorithm: https://jiemingzhu.github.io/pub/pjhe_icws2017.pdf
+	// Logs are routed in a fixed-depth parse tree by token count and their first few tokens, then merged into the most similar cluster of the leaf.
+	// Template of a cluster evolves when logs are merged into it, so clusters are identified in the miner by Key which never changes.
+	// Key depends on the first log of the cluster, use TemplateKey to identify a template across agents.
+	// This struct needs to be encoded by gob. So all fields are public.
+	DrainMiner struct {
+		// Depth is the depth of parse tree including root and leaf, at least 3
+		Depth int
+		// SimThreshold is the min similarity of a log and a cluster to be merged
+		SimThreshold float64
+		// MaxChildren is the max children count of an internal node
+		MaxChildren int
+		// MaxClusters is the max count of clusters, the least recently seen cluster is evicted when full
+		MaxClusters  int
+		MaxLogLength int
+		Root         *DrainNode
+		Clusters     map[int64]*DrainCluster
+		NextID       int64
+	}
+	DrainNode struct {
+		Children   map[string]*DrainNode
+		ClusterIDs []int64
+	}
+	DrainCluster struct {
+		ID     int64
+		Tokens []string
+		// Path is the route from root to the leaf node of this cluster
+		Path []string
+		// Key is a stable id of the cluster in this miner, it is a hash of Path and the first template.
+		// It never changes across windows, but agents seeing different first logs get different keys for the same template.
+		Key string
+		// LastSeen is the timestamp when the cluster was matched last time
+		LastSeen int64
+		// Size is the total count of matched logs
+		Size int64
+	}
+)
+
+func NewDrainMiner(depth int, simThreshold float64, maxChildren, maxClusters, maxLogLength int) *DrainMiner {
+	if depth < 3 {
+		depth = DefaultDrainDepth
+	}
+	if simThreshold <= 0 || simThreshold > 1 {
+		simThreshold = DefaultDrainSimThreshold
+	}
+	if maxChildren <= 0 {
+		maxChildren = DefaultDrainMaxChildren
+	}
+	if maxClusters <= 0 {
+		maxClusters = DefaultDrainMaxClusters
+	}
+	if maxLogLength <= 0 {
+		maxLogLength = DefaultMaxLoLength
+	}
+	return &DrainMiner{
+		Depth:        depth,
+		SimThreshold: simThreshold,
+		MaxChildren:  maxChildren,
+		MaxClusters:  maxClusters,
+		MaxLogLength: maxLogLength,
+		Root:         &DrainNode{},
+		Clusters:     make(map[int64]*DrainCluster),
+	}
+}
+
+// Template returns the template string of the cluster
+func (c *DrainCluster) Template() string {
+	return strings.Join(c.Tokens, " ")
+}
+
+// TemplateKey returns a hash of Path and the current template.
+// It only depends on the converged template, so it is the same across agents, but it changes while the template evolves.
+func (c *DrainCluster) TemplateKey() string {
+	return clusterKey(c.Path, c.Tokens)
+}
+
+// TemplateID returns a hash of a template string
+func TemplateID(template string) string {
+	h := fnv.New64a()
+	h.Write([]byte(template))
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// Get returns cluster by id, returns nil if the cluster does not exist or has been evicted
+func (m *DrainMiner) Get(id int64) *DrainCluster {
+	return m.Clusters[id]
+}
+
+// Add adds a log to the miner, and returns the cluster it belongs to.
+// It returns nil if the log is empty.
+func (m *DrainMiner) Add(log string, now int64) *DrainCluster {
+	if len(log) > m.MaxLogLength {
+		log = log[:m.MaxLogLength]
+	}
+	tokens := strings.Fields(log)
+	if len(tokens) == 0 {
+		return nil
+	}
+	m.ensureInit()
+
+	if c := m.match(tokens); c != nil {
+		for i, token := range tokens {
+			if c.Tokens[i] != token {
+				c.Tokens[i] = DrainWildcard
+			}
+		}
+		// Clusters decoded from state of old versions have no key
+		if c.Key == "" {
+			c.Key = clusterKey(c.Path, c.Tokens)
+		}
+		c.LastSeen = now
+		c.Size++
+		return c
+	}
+
+	if len(m.Clusters) >= m.MaxClusters {
+		m.evict()
+	}
+
+	m.NextID++
+	c := &DrainCluster{
+		ID:       m.NextID,
+		Tokens:   tokens,
+		LastSeen: now,
+		Size:     1,
+	}
+	leaf := m.Root
+	for _, key := range m.routeKeys(tokens) {
+		leaf, key = m.getOrCreateChild(leaf, key)
+		c.Path = append(c.Path, key)
+	}
+	leaf.ClusterIDs = append(leaf.ClusterIDs, c.ID)
+	c.Key = clusterKey(c.Path, tokens)
+	m.Clusters[c.ID] = c
+	return c
+}
+
+// clusterKey hashes route path and tokens, tokens with digits are replaced by wildcard like routeKeys
+func clusterKey(path, tokens []string) string {
+	sb := strings.Builder{}
+	for _, key := range path {
+		sb.WriteString(key)
+		sb.WriteByte('/')
+	}
+	for i, token := range tokens {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		if hasDigit(token) {
+			token = DrainWildcard
+		}
+		sb.WriteString(token)
+	}
+	return TemplateID(sb.String())
+}
+
+// ensureInit initializes nil fields, gob omits empty maps and nil pointers
+func (m *DrainMiner) ensureInit() {
+	if m.Root == nil {
+		m.Root = &DrainNode{}
+	}
+	if m.Clusters == nil {
+		m.Clusters = make(map[int64]*DrainCluster)
+	}
+}
+
+// routeKeys returns keys used to route the tokens: token count and first (Depth-2) tokens
+func (m *DrainMiner) routeKeys(tokens []string) []string {
+	n := m.Depth - 2
+	if n > len(tokens) {
+		n = len(tokens)
+	}
+	keys := make([]string, 0, n+1)
+	keys = append(keys, strconv.Itoa(len(tokens)))
+	for _, token := range tokens[:n] {
+		if hasDigit(token) {
+			token = DrainWildcard
+		}
+		keys = append(keys, token)
+	}
+	return keys
+}
+
+// match finds the most similar cluster of tokens
+func (m *DrainMiner) match(tokens []string) *DrainCluster {
+	node := m.Root
+	for i, key := range m.routeKeys(tokens) {
+		child := node.Children[key]
+		// The first key is token count, it never falls back to wildcard
+		if child == nil && i > 0 {
+			child = node.Children[DrainWildcard]
+		}
+		if child == nil {
+			return nil
+		}
+		node = child
+	}
+
+	var best *DrainCluster
+	bestSim := -1.0
+	bestParams := -1
+	for _, id := range node.ClusterIDs {
+		c := m.Clusters[id]
+		if c == nil || len(c.Tokens) != len(tokens) {
+			continue
+		}
+		sim, params := seqSimilarity(c.Tokens, tokens)
+		if sim > bestSim || (sim == bestSim && params > bestParams) {
+			best, bestSim, bestParams = c, sim, params
+		}
+	}
+	if best == nil || bestSim < m.SimThreshold {
+		return nil
+	}
+	return best
+}
+
+// getOrCreateChild returns child of node by key. Unknown keys fall back to wildcard when node has too many children.
+func (m *DrainMiner) getOrCreateChild(node *DrainNode, key string) (*DrainNode, string) {
+	if node.Children == nil {
+		node.Children = make(map[string]*DrainNode)
+	}
+	if child, ok := node.Children[key]; ok {
+		return child, key
+	}
+	if key != DrainWildcard && node != m.Root && len(node.Children) >= m.MaxChildren {
+		key = DrainWildcard
+		if child, ok := node.Children[key]; ok {
+			return child, key
+		}
+	}
+	child := &DrainNode{}
+	node.Children[key] = child
+	return child, key
+}
+
+// evict removes the least recently seen cluster
+func (m *DrainMiner) evict() {
+	var lru *DrainCluster
+	for _, c := range m.Clusters {
+		if lru == nil || c.LastSeen < lru.LastSeen || (c.LastSeen == lru.LastSeen && c.ID < lru.ID) {
+			lru = c
+		}
+	}
+	if lru == nil {
+		return
+	}
+	delete(m.Clusters, lru.ID)
+
+	node := m.Root
+	for _, key := range lru.Path {
+		if node = node.Children[key]; node == nil {
+			return
+		}
+	}
+	for i, id := range node.ClusterIDs {
+		if id == lru.ID {
+			node.ClusterIDs = append(node.ClusterIDs[:i], node.ClusterIDs[i+1:]...)
+			break
+		}
+	}
+}
+
+// seqSimilarity returns ratio of equal tokens and count of wildcards in template
+func seqSimilarity(template, tokens []string) (float64, int) {
+	equal := 0
+	params := 0
+	for i, t := range template {
+		if t == DrainWildcard {
+			params++
+			continue
+		}
+		if t == tokens[i] {
+			equal++
+		}
+	}
+	return float64(equal) / float64(len(template)), params
+}
+
+func hasDigit(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if isDigit(s[i]) {
+			return true
+		}
+	}
+	return false
+}
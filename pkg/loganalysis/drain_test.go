@@ -0,0 +1,101 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package loganalysis
+
+import (
+	"bytes"
+	"encoding/gob"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestDrainMiner_merge(t *testing.T) {
+	m := NewDrainMiner(0, 0, 0, 0, 0)
+
+	c1 := m.Add("connect to 10.0.0.1 failed after 3 retries", 1)
+	c2 := m.Add("connect to 10.0.0.2 failed after 5 retries", 2)
+	c3 := m.Add("user alice login success", 3)
+
+	assert.Equal(t, c1.ID, c2.ID)
+	assert.NotEqual(t, c1.ID, c3.ID)
+	assert.Equal(t, "connect to <*> failed after <*> retries", c1.Template())
+	assert.Equal(t, int64(2), c1.Size)
+	assert.Nil(t, m.Add("  ", 4))
+}
+
+func TestDrainMiner_deterministic(t *testing.T) {
+	logs := []string{
+		"GET /api/users 200 12ms",
+		"GET /api/users 500 30ms",
+		"order 1001 created by bob",
+		"order 1002 created by alice",
+	}
+	m1 := NewDrainMiner(0, 0, 0, 0, 0)
+	m2 := NewDrainMiner(0, 0, 0, 0, 0)
+	for i, log := range logs {
+		a := m1.Add(log, int64(i))
+		b := m2.Add(log, int64(i))
+		assert.Equal(t, a.Key, b.Key)
+	}
+	assert.Len(t, m1.Clusters, 2)
+}
+
+func TestDrainMiner_key(t *testing.T) {
+	m := NewDrainMiner(0, 0, 0, 0, 0)
+	c1 := m.Add("login user alice from web", 1)
+	key := c1.Key
+	assert.NotEmpty(t, key)
+
+	// key does not change when the template changes
+	c2 := m.Add("login user bob from app", 2)
+	assert.Equal(t, c1.ID, c2.ID)
+	assert.Equal(t, "login user <*> from <*>", c2.Template())
+	assert.Equal(t, key, c2.Key)
+
+	// another agent starting from another log has a different key, but the same template key after the template converges
+	m2 := NewDrainMiner(0, 0, 0, 0, 0)
+	c3 := m2.Add("login user carol from app", 3)
+	assert.NotEqual(t, key, c3.Key)
+	c3 = m2.Add("login user dave from web", 4)
+	assert.Equal(t, c2.Template(), c3.Template())
+	assert.Equal(t, c2.TemplateKey(), c3.TemplateKey())
+
+	// template key changes while the template evolves
+	templateKey := c2.TemplateKey()
+	m.Add("login user eve at web", 5)
+	assert.NotEqual(t, templateKey, c2.TemplateKey())
+}
+
+func TestDrainMiner_evict(t *testing.T) {
+	m := NewDrainMiner(0, 0, 0, 2, 0)
+	c1 := m.Add("a b c", 1)
+	c2 := m.Add("d e f g", 2)
+	m.Add("a b c", 3)
+	c3 := m.Add("h i j k l", 4)
+
+	assert.Len(t, m.Clusters, 2)
+	assert.NotNil(t, m.Get(c1.ID))
+	assert.Nil(t, m.Get(c2.ID))
+	assert.NotNil(t, m.Get(c3.ID))
+
+	// evicted cluster is removed from its leaf
+	c4 := m.Add("d e f g", 5)
+	assert.NotEqual(t, c2.ID, c4.ID)
+}
+
+func TestDrainMiner_gob(t *testing.T) {
+	m := NewDrainMiner(0, 0, 0, 0, 0)
+	m.Add("connect to 10.0.0.1 failed", 1)
+
+	var buf bytes.Buffer
+	require.NoError(t, gob.NewEncoder(&buf).Encode(m))
+	m2 := &DrainMiner{}
+	require.NoError(t, gob.NewDecoder(&buf).Decode(m2))
+
+	c := m2.Add("connect to 10.0.0.2 failed", 2)
+	assert.Equal(t, int64(1), c.ID)
+	assert.Equal(t, "connect to <*> failed", c.Template())
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package loganalysis
+
+import (
+	"bytes"
+	"time"
+)
+
+const (
+	DefaultNoveltyHorizon = 24 * time.Hour
+	DefaultNoveltyWarmup  = 10 * time.Minute
+	DefaultNoveltyMaxKeys = 10000
+)
+
+type (
+	// NoveltyTracker remembers pattern keys seen within a horizon, and detects first occurrences of patterns.
+	// Patterns observed during warmup are remembered but not reported as new, otherwise all patterns are new when agent starts.
+	// This struct needs to be encoded by gob. So all fields are public.
+	NoveltyTracker struct {
+		// Horizon in milliseconds, a pattern is new again if it has not been seen for Horizon
+		Horizon int64
+		// Warmup in milliseconds since the first observation
+		Warmup    int64
+		MaxKeys   int
+		Started   bool
+		StartTime int64
+		// key -> last seen timestamp
+		Seen map[string]int64
+	}
+)
+
+func NewNoveltyTracker(horizon, warmup time.Duration, maxKeys int) *NoveltyTracker {
+	if horizon <= 0 {
+		horizon = DefaultNoveltyHorizon
+	}
+	if warmup < 0 {
+		warmup = DefaultNoveltyWarmup
+	}
+	if maxKeys <= 0 {
+		maxKeys = DefaultNoveltyMaxKeys
+	}
+	return &NoveltyTracker{
+		Horizon: horizon.Milliseconds(),
+		Warmup:  warmup.Milliseconds(),
+		MaxKeys: maxKeys,
+		Seen:    make(map[string]int64),
+	}
+}
+
+// Observe records that key is seen at ts, and returns true if it is a new pattern
+func (t *NoveltyTracker) Observe(key string, ts int64) bool {
+	if t.Seen == nil {
+		t.Seen = make(map[string]int64)
+	}
+	if !t.Started {
+		t.Started = true
+		t.StartTime = ts
+	}
+
+	last, ok := t.Seen[key]
+	isNew := !ok || ts-last > t.Horizon
+	if !ok && len(t.Seen) >= t.MaxKeys {
+		t.Expire(ts)
+		if len(t.Seen) >= t.MaxKeys {
+			t.evictOldest()
+		}
+	}
+	if !ok || ts > last {
+		t.Seen[key] = ts
+	}
+	return isNew && ts >= t.StartTime+t.Warmup
+}
+
+// Expire removes keys not seen within horizon
+func (t *NoveltyTracker) Expire(now int64) {
+	for key, last := range t.Seen {
+		if now-last > t.Horizon {
+			delete(t.Seen, key)
+		}
+	}
+}
+
+func (t *NoveltyTracker) evictOldest() {
+	oldestKey := ""
+	oldest := int64(0)
+	for key, last := range t.Seen {
+		if oldestKey == "" || last < oldest || (last == oldest && key < oldestKey) {
+			oldestKey, oldest = key, last
+		}
+	}
+	delete(t.Seen, oldestKey)
+}
+
+// Key returns a stable key of the analyzed log, it is built from letters of its non-source parts
+func (el *AnalyzedLog) Key() string {
+	var sb bytes.Buffer
+	var reuse bytes.Buffer
+	for _, p := range el.Parts {
+		if p.Source {
+			continue
+		}
+		sb.WriteString(p.getLatterContent(&reuse))
+		sb.WriteByte('|')
+	}
+	return TemplateID(sb.String())
+}
@@ -0,0 +1,40 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package loganalysis
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNoveltyTracker(t *testing.T) {
+	minute := time.Minute.Milliseconds()
+	tracker := NewNoveltyTracker(time.Hour, 10*time.Minute, 0)
+
+	// warmup
+	assert.False(t, tracker.Observe("a", 0))
+	assert.False(t, tracker.Observe("b", 5*minute))
+
+	assert.True(t, tracker.Observe("c", 10*minute))
+	assert.False(t, tracker.Observe("c", 11*minute))
+	assert.False(t, tracker.Observe("a", 20*minute))
+
+	// not seen within horizon
+	assert.True(t, tracker.Observe("b", 70*minute))
+
+	tracker.Expire(90 * minute)
+	assert.Len(t, tracker.Seen, 1)
+}
+
+func TestNoveltyTracker_maxKeys(t *testing.T) {
+	tracker := NewNoveltyTracker(time.Hour, 0, 2)
+	assert.True(t, tracker.Observe("a", 1))
+	assert.True(t, tracker.Observe("b", 2))
+	assert.True(t, tracker.Observe("c", 3))
+	assert.Len(t, tracker.Seen, 2)
+	// 'a' is evicted
+	assert.True(t, tracker.Observe("a", 4))
+}
@@ -16,6 +16,8 @@ import (
 	"github.com/traas-stack/holoinsight-agent/pkg/collecttask"
 	"github.com/traas-stack/holoinsight-agent/pkg/core"
 	_ "github.com/traas-stack/holoinsight-agent/pkg/k8s/k8ssysmetrics/cadvisor"
+	_ "github.com/traas-stack/holoinsight-agent/pkg/k8s/k8ssysmetrics/cgroup"
+	_ "github.com/traas-stack/holoinsight-agent/pkg/k8s/k8ssysmetrics/kubelet"
 	"github.com/traas-stack/holoinsight-agent/pkg/logger"
 	"github.com/traas-stack/holoinsight-agent/pkg/pipeline/integration/alibabacloud"
 	"github.com/traas-stack/holoinsight-agent/pkg/pipeline/integration/base"
@@ -10,6 +10,8 @@ import (
 	_ "github.com/traas-stack/holoinsight-agent/pkg/plugin/input/jvm"
 	_ "github.com/traas-stack/holoinsight-agent/pkg/plugin/input/load"
 	_ "github.com/traas-stack/holoinsight-agent/pkg/plugin/input/mem"
+	_ "github.com/traas-stack/holoinsight-agent/pkg/plugin/input/netstack"
+	_ "github.com/traas-stack/holoinsight-agent/pkg/plugin/input/pressure"
 	_ "github.com/traas-stack/holoinsight-agent/pkg/plugin/input/process"
 	_ "github.com/traas-stack/holoinsight-agent/pkg/plugin/input/swap"
 	_ "github.com/traas-stack/holoinsight-agent/pkg/plugin/input/tcp"
@@ -7,12 +7,21 @@ package disk
 import (
 	"github.com/shirou/gopsutil/v3/disk"
 	"github.com/traas-stack/holoinsight-agent/pkg/logger"
+	"github.com/traas-stack/holoinsight-agent/pkg/model"
 	"github.com/traas-stack/holoinsight-agent/pkg/plugin/api"
 	"github.com/traas-stack/holoinsight-agent/pkg/plugin/input"
+	"github.com/traas-stack/holoinsight-agent/pkg/util"
+	"sort"
 )
 
 type (
 	diskInput struct {
+		filter *filter
+		state  *internalState
+	}
+	internalState struct {
+		Time int64
+		IO   map[string]disk.IOCountersStat
 	}
 )
 
@@ -50,5 +59,143 @@ func (i *diskInput) Collect(a api.Accumulator) error {
 	}
 
 	input.AddMetrics(a, values)
+
+	i.collectMounts(a)
+	i.collectIO(a)
 	return nil
 }
+
+// collectMounts adds usage and inode usage of every mountpoint
+func (i *diskInput) collectMounts(a api.Accumulator) {
+	partitions, err := disk.Partitions(true)
+	if err != nil {
+		logger.Errorf("get partitions error %+v", err)
+		return
+	}
+	visited := make(map[string]struct{})
+	for j := range partitions {
+		p := &partitions[j]
+		if !i.filter.acceptMount(p) {
+			continue
+		}
+		// The same filesystem may be mounted at the same place more than once
+		key := p.Device + "@" + p.Mountpoint
+		if _, ok := visited[key]; ok {
+			continue
+		}
+		visited[key] = struct{}{}
+
+		u, err := disk.Usage(p.Mountpoint)
+		if err != nil {
+			logger.Debugf("get usage of %s error %+v", p.Mountpoint, err)
+			continue
+		}
+		// pseudo filesystems
+		if u.Total == 0 {
+			continue
+		}
+		addMountMetrics(a, p, u)
+	}
+}
+
+func addMountMetrics(a api.Accumulator, p *disk.PartitionStat, u *disk.UsageStat) {
+	tags := map[string]string{
+		"mount":  p.Mountpoint,
+		"device": p.Device,
+		"fstype": p.Fstype,
+	}
+	values := map[string]float64{
+		"disk_mount_total": float64(u.Total),
+		"disk_mount_used":  float64(u.Used),
+		"disk_mount_free":  float64(u.Free),
+		"disk_mount_util":  u.UsedPercent,
+	}
+	// Some filesystems such as btrfs don't have inodes
+	if u.InodesTotal > 0 {
+		values["disk_mount_inodes_total"] = float64(u.InodesTotal)
+		values["disk_mount_inodes_used"] = float64(u.InodesUsed)
+		values["disk_mount_inodes_util"] = u.InodesUsedPercent
+	}
+	for name, value := range values {
+		a.AddMetric(&model.Metric{Name: name, Tags: tags, Value: value})
+	}
+}
+
+// collectIO adds io metrics of every block device, they are calculated as deltas between collections
+func (i *diskInput) collectIO(a api.Accumulator) {
+	now := util.CurrentMS()
+	counters, err := disk.IOCounters()
+	if err != nil {
+		if !util.IsNotImplemented(err) {
+			logger.Errorf("get disk IOCounters error %+v", err)
+		}
+		return
+	}
+
+	state := i.state
+	i.state = &internalState{Time: now, IO: counters}
+	if state == nil || now <= state.Time {
+		return
+	}
+
+	disks := wholeDisks(hostSys())
+	names := make([]string, 0, len(counters))
+	for name := range counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !i.filter.acceptIODevice(name, disks) {
+			continue
+		}
+		last, ok := state.IO[name]
+		if !ok {
+			continue
+		}
+		c := counters[name]
+		tags := map[string]string{"device": name}
+		for metricName, value := range calcIOMetrics(&last, &c, now-state.Time) {
+			a.AddMetric(&model.Metric{Name: metricName, Tags: tags, Value: value})
+		}
+	}
+}
+
+// calcIOMetrics calculates io metrics of a device from 2 snapshots, mills is the time between 2 snapshots.
+// ops and bytes are per second, await is in milliseconds, util is percentage of time the device is busy.
+func calcIOMetrics(s1, s2 *disk.IOCountersStat, mills int64) map[string]float64 {
+	// Counters are reset when device is re-attached
+	if s2.ReadCount < s1.ReadCount || s2.WriteCount < s1.WriteCount || s2.IoTime < s1.IoTime ||
+		s2.ReadBytes < s1.ReadBytes || s2.WriteBytes < s1.WriteBytes ||
+		s2.ReadTime < s1.ReadTime || s2.WriteTime < s1.WriteTime || mills <= 0 {
+		return nil
+	}
+	seconds := float64(mills) / 1000
+	reads := float64(s2.ReadCount - s1.ReadCount)
+	writes := float64(s2.WriteCount - s1.WriteCount)
+	readTime := float64(s2.ReadTime - s1.ReadTime)
+	writeTime := float64(s2.WriteTime - s1.WriteTime)
+
+	values := map[string]float64{
+		"disk_io_read_ops":    reads / seconds,
+		"disk_io_write_ops":   writes / seconds,
+		"disk_io_read_bytes":  float64(s2.ReadBytes-s1.ReadBytes) / seconds,
+		"disk_io_write_bytes": float64(s2.WriteBytes-s1.WriteBytes) / seconds,
+		"disk_io_read_await":  0,
+		"disk_io_write_await": 0,
+		"disk_io_await":       0,
+		"disk_io_util":        100 * float64(s2.IoTime-s1.IoTime) / float64(mills),
+	}
+	if reads > 0 {
+		values["disk_io_read_await"] = readTime / reads
+	}
+	if writes > 0 {
+		values["disk_io_write_await"] = writeTime / writes
+	}
+	if reads+writes > 0 {
+		values["disk_io_await"] = (readTime + writeTime) / (reads + writes)
+	}
+	if values["disk_io_util"] > 100 {
+		values["disk_io_util"] = 100
+	}
+	return values
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package disk
+
+import (
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/stretchr/testify/assert"
+	"github.com/traas-stack/holoinsight-agent/pkg/appconfig"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	f := newFilter(DefaultConfig)
+	assert.True(t, f.acceptMount(&disk.PartitionStat{Mountpoint: "/", Fstype: "ext4"}))
+	assert.True(t, f.acceptMount(&disk.PartitionStat{Mountpoint: "/data", Fstype: "xfs"}))
+	assert.False(t, f.acceptMount(&disk.PartitionStat{Mountpoint: "/dev/shm", Fstype: "tmpfs"}))
+	assert.False(t, f.acceptMount(&disk.PartitionStat{Mountpoint: "/var/lib/docker/overlay2/abc/merged", Fstype: "overlay"}))
+	assert.False(t, f.acceptMount(&disk.PartitionStat{Mountpoint: "/var/lib/kubelet/pods/abc/volumes/x", Fstype: "ext4"}))
+	assert.True(t, f.acceptDevice("sda"))
+	assert.True(t, f.acceptDevice("nvme0n1"))
+	assert.False(t, f.acceptDevice("loop0"))
+
+	f = newFilter(&Config{IncludeFsTypes: []string{"xfs"}, IncludeDevices: []string{"^sd"}})
+	assert.False(t, f.acceptMount(&disk.PartitionStat{Mountpoint: "/", Fstype: "ext4"}))
+	assert.True(t, f.acceptMount(&disk.PartitionStat{Mountpoint: "/data", Fstype: "xfs"}))
+	assert.False(t, f.acceptDevice("nvme0n1"))
+}
+
+func TestLoadConfig(t *testing.T) {
+	f := newFilter(loadConfig(appconfig.SysConfig_Disk{
+		ExcludeMounts:  []string{"^/data/tmp$"},
+		IncludeDevices: []string{"^nvme"},
+	}))
+	// default fs types are kept
+	assert.False(t, f.acceptMount(&disk.PartitionStat{Mountpoint: "/dev/shm", Fstype: "tmpfs"}))
+	assert.False(t, f.acceptMount(&disk.PartitionStat{Mountpoint: "/data/tmp", Fstype: "xfs"}))
+	assert.True(t, f.acceptMount(&disk.PartitionStat{Mountpoint: "/var/lib/docker", Fstype: "xfs"}))
+	assert.True(t, f.acceptDevice("nvme0n1"))
+	assert.False(t, f.acceptDevice("sda"))
+	assert.False(t, f.acceptDevice("dm-0"))
+}
+
+func TestAcceptIODevice(t *testing.T) {
+	sysDir := t.TempDir()
+	for _, name := range []string{"sda", "nvme0n1", "dm-0"} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(sysDir, "block", name), 0755))
+	}
+	disks := wholeDisks(sysDir)
+	assert.Len(t, disks, 3)
+	assert.Nil(t, wholeDisks(filepath.Join(sysDir, "none")))
+
+	f := newFilter(DefaultConfig)
+	assert.True(t, f.acceptIODevice("sda", disks))
+	assert.True(t, f.acceptIODevice("nvme0n1", disks))
+	assert.False(t, f.acceptIODevice("sda1", disks))
+	assert.False(t, f.acceptIODevice("nvme0n1p1", disks))
+	assert.False(t, f.acceptIODevice("dm-0", disks))
+	// partitions are not filtered when disks are unknown
+	assert.True(t, f.acceptIODevice("sda1", nil))
+
+	f = newFilter(loadConfig(appconfig.SysConfig_Disk{Partitions: true}))
+	assert.True(t, f.acceptIODevice("sda1", disks))
+	assert.False(t, f.acceptIODevice("dm-0", disks))
+}
+
+func TestCalcIOMetrics(t *testing.T) {
+	s1 := &disk.IOCountersStat{ReadCount: 100, WriteCount: 200, ReadBytes: 1000, WriteBytes: 2000, ReadTime: 50, WriteTime: 100, IoTime: 1000}
+	s2 := &disk.IOCountersStat{ReadCount: 120, WriteCount: 260, ReadBytes: 21000, WriteBytes: 2000, ReadTime: 90, WriteTime: 280, IoTime: 1500}
+	values := calcIOMetrics(s1, s2, 2000)
+	assert.Equal(t, float64(10), values["disk_io_read_ops"])
+	assert.Equal(t, float64(30), values["disk_io_write_ops"])
+	assert.Equal(t, float64(10000), values["disk_io_read_bytes"])
+	assert.Equal(t, float64(0), values["disk_io_write_bytes"])
+	assert.Equal(t, float64(2), values["disk_io_read_await"])
+	assert.Equal(t, float64(3), values["disk_io_write_await"])
+	assert.Equal(t, float64(2.75), values["disk_io_await"])
+	assert.Equal(t, float64(25), values["disk_io_util"])
+
+	// counters reset
+	assert.Nil(t, calcIOMetrics(s2, s1, 2000))
+}
@@ -0,0 +1,186 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package disk
+
+import (
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/traas-stack/holoinsight-agent/pkg/appconfig"
+	"github.com/traas-stack/holoinsight-agent/pkg/logger"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+type (
+	// Config controls which mountpoints and devices are reported.
+	// Empty include lists mean all. Exclusions take precedence over inclusions.
+	Config struct {
+		IncludeFsTypes []string `json:"includeFsTypes"`
+		ExcludeFsTypes []string `json:"excludeFsTypes"`
+		// IncludeMounts and ExcludeMounts are regexps of mountpoint
+		IncludeMounts []string `json:"includeMounts"`
+		ExcludeMounts []string `json:"excludeMounts"`
+		// IncludeDevices and ExcludeDevices are regexps of block device name such as 'sda' and 'nvme0n1'
+		IncludeDevices []string `json:"includeDevices"`
+		ExcludeDevices []string `json:"excludeDevices"`
+		// Partitions reports io of partitions. By default only whole disks are reported, because io of a partition is also counted by its disk.
+		Partitions bool `json:"partitions"`
+	}
+	filter struct {
+		includeFsTypes map[string]struct{}
+		excludeFsTypes map[string]struct{}
+		includeMounts  []*regexp.Regexp
+		excludeMounts  []*regexp.Regexp
+		includeDevices []*regexp.Regexp
+		excludeDevices []*regexp.Regexp
+		partitions     bool
+	}
+)
+
+var (
+	// DefaultConfig excludes pseudo and container filesystems, which are usually noise on a node.
+	// Device mapper devices are excluded because their io is also counted by the underlying disks.
+	DefaultConfig = &Config{
+		ExcludeFsTypes: []string{
+			"tmpfs", "devtmpfs", "overlay", "aufs", "squashfs", "proc", "sysfs", "cgroup", "cgroup2", "devpts", "mqueue",
+			"debugfs", "tracefs", "securityfs", "pstore", "bpf", "autofs", "configfs", "fusectl", "hugetlbfs", "nsfs",
+			"rpc_pipefs", "binfmt_misc", "ramfs", "shm", "nfsd",
+		},
+		ExcludeMounts: []string{
+			"^/(dev|proc|sys|run)($|/)",
+			"^/var/lib/(docker|containerd|kubelet)/",
+		},
+		ExcludeDevices: []string{
+			"^(loop|ram|fd|sr|zram)\\d*$",
+			"^dm-\\d+$",
+		},
+	}
+)
+
+// loadConfig overrides DefaultConfig with non-empty fields of agent config
+func loadConfig(c appconfig.SysConfig_Disk) *Config {
+	config := *DefaultConfig
+	if len(c.IncludeFsTypes) > 0 {
+		config.IncludeFsTypes = c.IncludeFsTypes
+	}
+	if len(c.ExcludeFsTypes) > 0 {
+		config.ExcludeFsTypes = c.ExcludeFsTypes
+	}
+	if len(c.IncludeMounts) > 0 {
+		config.IncludeMounts = c.IncludeMounts
+	}
+	if len(c.ExcludeMounts) > 0 {
+		config.ExcludeMounts = c.ExcludeMounts
+	}
+	if len(c.IncludeDevices) > 0 {
+		config.IncludeDevices = c.IncludeDevices
+	}
+	if len(c.ExcludeDevices) > 0 {
+		config.ExcludeDevices = c.ExcludeDevices
+	}
+	config.Partitions = c.Partitions
+	return &config
+}
+
+func newFilter(config *Config) *filter {
+	return &filter{
+		includeFsTypes: toSet(config.IncludeFsTypes),
+		excludeFsTypes: toSet(config.ExcludeFsTypes),
+		includeMounts:  compileAll(config.IncludeMounts),
+		excludeMounts:  compileAll(config.ExcludeMounts),
+		includeDevices: compileAll(config.IncludeDevices),
+		excludeDevices: compileAll(config.ExcludeDevices),
+		partitions:     config.Partitions,
+	}
+}
+
+func (f *filter) acceptMount(p *disk.PartitionStat) bool {
+	if _, ok := f.excludeFsTypes[p.Fstype]; ok {
+		return false
+	}
+	if len(f.includeFsTypes) > 0 {
+		if _, ok := f.includeFsTypes[p.Fstype]; !ok {
+			return false
+		}
+	}
+	return accept(p.Mountpoint, f.includeMounts, f.excludeMounts)
+}
+
+func (f *filter) acceptDevice(name string) bool {
+	return accept(name, f.includeDevices, f.excludeDevices)
+}
+
+// acceptIODevice checks whether io of the device should be reported.
+// disks are names of whole disks, nil means unknown and partitions are not filtered.
+func (f *filter) acceptIODevice(name string, disks map[string]struct{}) bool {
+	if !f.acceptDevice(name) {
+		return false
+	}
+	if f.partitions || disks == nil {
+		return true
+	}
+	_, ok := disks[name]
+	return ok
+}
+
+// wholeDisks returns names of whole disks, which are listed in /sys/block while partitions are not.
+// Returns nil if /sys/block is unavailable.
+func wholeDisks(sysDir string) map[string]struct{} {
+	entries, err := os.ReadDir(filepath.Join(sysDir, "block"))
+	if err != nil || len(entries) == 0 {
+		return nil
+	}
+	disks := make(map[string]struct{}, len(entries))
+	for _, e := range entries {
+		disks[e.Name()] = struct{}{}
+	}
+	return disks
+}
+
+// hostSys returns sysfs dir of host, HOST_SYS is respected like gopsutil
+func hostSys() string {
+	if s := os.Getenv("HOST_SYS"); s != "" {
+		return s
+	}
+	return "/sys"
+}
+
+func accept(s string, includes, excludes []*regexp.Regexp) bool {
+	for _, r := range excludes {
+		if r.MatchString(s) {
+			return false
+		}
+	}
+	if len(includes) == 0 {
+		return true
+	}
+	for _, r := range includes {
+		if r.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func toSet(ss []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(ss))
+	for _, s := range ss {
+		set[s] = struct{}{}
+	}
+	return set
+}
+
+func compileAll(exprs []string) []*regexp.Regexp {
+	var ret []*regexp.Regexp
+	for _, expr := range exprs {
+		r, err := regexp.Compile(expr)
+		if err != nil {
+			logger.Errorf("[disk] invalid regexp %s %+v", expr, err)
+			continue
+		}
+		ret = append(ret, r)
+	}
+	return ret
+}
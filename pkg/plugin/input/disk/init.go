@@ -5,12 +5,14 @@
 package disk
 
 import (
+	"github.com/traas-stack/holoinsight-agent/pkg/appconfig"
 	"github.com/traas-stack/holoinsight-agent/pkg/plugin/api"
 	"github.com/traas-stack/holoinsight-agent/pkg/plugin/input"
 )
 
 func init() {
 	input.Register("disk", func(config input.Config) (api.Input, error) {
-		return &diskInput{}, nil
+		// disk input is created without task config, filters are read from agent config
+		return &diskInput{filter: newFilter(loadConfig(appconfig.StdAgentConfig.Sys.Disk))}, nil
 	})
 }
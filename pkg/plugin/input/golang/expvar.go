@@ -0,0 +1,245 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package golang
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"github.com/spf13/cast"
+	"github.com/traas-stack/holoinsight-agent/pkg/model"
+	"github.com/traas-stack/holoinsight-agent/pkg/plugin/api"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// maxCustomVarSeries limits the number of series generated by custom expvars
+	maxCustomVarSeries = 200
+	// pauseBufferSize is the size of runtime.MemStats.PauseNs
+	pauseBufferSize = 256
+)
+
+type (
+	// memStats is a subset of runtime.MemStats
+	memStats struct {
+		Alloc         uint64
+		TotalAlloc    uint64
+		Sys           uint64
+		Mallocs       uint64
+		Frees         uint64
+		HeapAlloc     uint64
+		HeapSys       uint64
+		HeapIdle      uint64
+		HeapInuse     uint64
+		HeapReleased  uint64
+		HeapObjects   uint64
+		StackInuse    uint64
+		StackSys      uint64
+		NextGC        uint64
+		PauseTotalNs  uint64
+		PauseNs       [pauseBufferSize]uint64
+		NumGC         uint32
+		NumForcedGC   uint32
+		GCCPUFraction float64
+	}
+)
+
+var (
+	// builtinVars are published by expvar package itself
+	builtinVars = map[string]struct{}{
+		"memstats": {},
+		"cmdline":  {},
+	}
+	pauseQuantiles = []struct {
+		name     string
+		quantile float64
+	}{
+		{"gc_pause_p50", 0.5},
+		{"gc_pause_p90", 0.9},
+		{"gc_pause_p99", 0.99},
+		{"gc_pause_max", 1},
+	}
+	illegalMetricCharRegexp = regexp.MustCompile("[^a-zA-Z0-9_]+")
+)
+
+func (i *Input) collectVars(vars map[string]json.RawMessage, a api.Accumulator) error {
+	ms := &memStats{}
+	if err := json.Unmarshal(vars["memstats"], ms); err != nil {
+		return err
+	}
+	i.addMemStats(ms, a)
+
+	names := i.Config.Vars
+	if len(names) == 0 {
+		for name := range vars {
+			if _, ok := builtinVars[name]; !ok {
+				names = append(names, name)
+			}
+		}
+		// make the result stable when series are truncated
+		sort.Strings(names)
+	}
+
+	series := 0
+	for _, name := range names {
+		raw, ok := vars[name]
+		if !ok {
+			continue
+		}
+		series += addCustomVar(name, raw, maxCustomVarSeries-series, a)
+		if series >= maxCustomVarSeries {
+			break
+		}
+	}
+	return nil
+}
+
+func (i *Input) addMemStats(ms *memStats, a api.Accumulator) {
+	gauges := map[string]float64{
+		"memstats_alloc":           float64(ms.Alloc),
+		"memstats_sys":             float64(ms.Sys),
+		"memstats_heap_alloc":      float64(ms.HeapAlloc),
+		"memstats_heap_sys":        float64(ms.HeapSys),
+		"memstats_heap_idle":       float64(ms.HeapIdle),
+		"memstats_heap_inuse":      float64(ms.HeapInuse),
+		"memstats_heap_released":   float64(ms.HeapReleased),
+		"memstats_heap_objects":    float64(ms.HeapObjects),
+		"memstats_stack_inuse":     float64(ms.StackInuse),
+		"memstats_stack_sys":       float64(ms.StackSys),
+		"memstats_next_gc":         float64(ms.NextGC),
+		"memstats_gc_cpu_fraction": ms.GCCPUFraction,
+		// Cumulative counters, use 'delta' or 'rate' value manipulation to get per period values
+		"memstats_total_alloc":    float64(ms.TotalAlloc),
+		"memstats_mallocs":        float64(ms.Mallocs),
+		"memstats_frees":          float64(ms.Frees),
+		"memstats_pause_total_ns": float64(ms.PauseTotalNs),
+		"memstats_num_forced_gc":  float64(ms.NumForcedGC),
+		metricNumGC:               float64(ms.NumGC),
+	}
+	for name, value := range gauges {
+		a.AddMetric(&model.Metric{Name: name, Tags: map[string]string{}, Value: value})
+	}
+
+	if !i.Config.HasLastNumGC {
+		return
+	}
+	gcCount := gcCountSince(ms.NumGC, i.Config.LastNumGC)
+	a.AddMetric(&model.Metric{Name: "gc_count", Tags: map[string]string{}, Value: float64(gcCount)})
+
+	pauses := recentPauses(ms, i.Config.LastNumGC)
+	if len(pauses) == 0 {
+		return
+	}
+	for _, q := range pauseQuantiles {
+		a.AddMetric(&model.Metric{
+			Name: q.name,
+			Tags: map[string]string{},
+			// in ms
+			Value: quantile(pauses, q.quantile) / 1e6,
+		})
+	}
+}
+
+// gcCountSince returns the count of gc happened after lastNumGC
+func gcCountSince(numGC, lastNumGC uint32) uint32 {
+	if numGC < lastNumGC {
+		// target process restarted, all gc happened after the restart
+		return numGC
+	}
+	return numGC - lastNumGC
+}
+
+// recentPauses returns sorted gc pauses happened after lastNumGC.
+// PauseNs is a circular buffer, the most recent pause is at PauseNs[(NumGC+255)%256].
+func recentPauses(ms *memStats, lastNumGC uint32) []float64 {
+	n := gcCountSince(ms.NumGC, lastNumGC)
+	if n > pauseBufferSize {
+		n = pauseBufferSize
+	}
+	pauses := make([]float64, 0, n)
+	for j := uint32(0); j < n; j++ {
+		pauses = append(pauses, float64(ms.PauseNs[(ms.NumGC-1-j)%pauseBufferSize]))
+	}
+	sort.Float64s(pauses)
+	return pauses
+}
+
+// quantile returns the value at quantile q of sorted values using nearest rank method
+func quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	index := int(q*float64(len(sorted))+0.5) - 1
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(sorted) {
+		index = len(sorted) - 1
+	}
+	return sorted[index]
+}
+
+// addCustomVar adds numeric expvar or map of numeric expvars (such as expvar.Map).
+// Returns the number of series added.
+func addCustomVar(name string, raw json.RawMessage, limit int, a api.Accumulator) int {
+	if limit <= 0 {
+		return 0
+	}
+	metricName := "var_" + illegalMetricCharRegexp.ReplaceAllString(name, "_")
+
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return 0
+	}
+	switch x := v.(type) {
+	case float64:
+		a.AddMetric(&model.Metric{Name: metricName, Tags: map[string]string{}, Value: x})
+		return 1
+	case bool:
+		a.AddMetric(&model.Metric{Name: metricName, Tags: map[string]string{}, Value: api.BoolToFloat64(x)})
+		return 1
+	case map[string]interface{}:
+		keys := make([]string, 0, len(x))
+		for key := range x {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		count := 0
+		for _, key := range keys {
+			if count >= limit {
+				break
+			}
+			// Only numeric values are supported, nested maps are ignored
+			f64, err := cast.ToFloat64E(x[key])
+			if err != nil {
+				continue
+			}
+			if _, ok := x[key].(string); ok {
+				continue
+			}
+			a.AddMetric(&model.Metric{Name: metricName, Tags: map[string]string{"key": key}, Value: f64})
+			count++
+		}
+		return count
+	}
+	return 0
+}
+
+// parsePprofTotal parses total count from the first line of pprof debug page, such as "goroutine profile: total 12"
+func parsePprofTotal(content []byte) (float64, bool) {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	if !scanner.Scan() {
+		return 0, false
+	}
+	line := scanner.Text()
+	index := strings.LastIndex(line, "total ")
+	if index < 0 {
+		return 0, false
+	}
+	f64, err := cast.ToFloat64E(strings.TrimSpace(line[index+len("total "):]))
+	return f64, err == nil
+}
@@ -0,0 +1,261 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package golang
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/traas-stack/holoinsight-agent/pkg/model"
+	"github.com/traas-stack/holoinsight-agent/pkg/plugin/api"
+	"github.com/traas-stack/holoinsight-agent/pkg/plugin/input/inputproxy"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type (
+	// Config is serialized and passed to container helper when network mode is POD
+	Config struct {
+		// BaseURLs are candidate endpoints such as 'http://10.0.0.1:6060', the first available one is used
+		BaseURLs  []string      `json:"baseURLs"`
+		VarsPath  string        `json:"varsPath"`
+		PprofPath string        `json:"pprofPath"`
+		Timeout   time.Duration `json:"timeout"`
+		// Vars are names of custom expvars to report, empty means all numeric expvars
+		Vars        []string `json:"vars"`
+		NetworkMode string   `json:"networkMode"`
+		// LastNumGC is the NumGC of last collection, used to select gc pauses happened in this period
+		LastNumGC    uint32 `json:"lastNumGC"`
+		HasLastNumGC bool   `json:"hasLastNumGC"`
+	}
+	Input struct {
+		Config *Config
+		// discoveredURL is the base url which responds successfully last time
+		discoveredURL string
+	}
+)
+
+const (
+	HelperInputProxyConfigType = "golang"
+	defaultTimeout             = 3 * time.Second
+	defaultVarsPath            = "/debug/vars"
+	defaultPprofPath           = "/debug/pprof"
+	maxBodySize                = 4 * 1024 * 1024
+	metricNumGC                = "memstats_num_gc"
+)
+
+var notFoundErr = errors.New("not found")
+
+func init() {
+	inputproxy.Register(HelperInputProxyConfigType, func() api.InputExtNsEnter {
+		return &Input{}
+	})
+}
+
+func (i *Input) GetDefaultPrefix() string {
+	return "golang_"
+}
+
+func (i *Input) NetworkMode() string {
+	return i.Config.NetworkMode
+}
+
+func (i *Input) DebugInfo() map[string]interface{} {
+	return map[string]interface{}{
+		"baseURLs":      i.Config.BaseURLs,
+		"discoveredURL": i.discoveredURL,
+	}
+}
+
+func (i *Input) UpdateFrom(old interface{}) {
+	if old2, ok := old.(*Input); ok && old2 != i {
+		i.discoveredURL = old2.discoveredURL
+		i.Config.LastNumGC = old2.Config.LastNumGC
+		i.Config.HasLastNumGC = old2.Config.HasLastNumGC
+	}
+}
+
+func (i *Input) SerializeRequest() (interface{}, string, []byte, time.Duration, error) {
+	configBytes, err := json.Marshal(i.Config)
+	// The helper tries every candidate, so the timeout must cover all of them
+	timeout := i.getTimeout() * time.Duration(len(i.Config.BaseURLs)+1)
+	return nil, HelperInputProxyConfigType, configBytes, timeout, err
+}
+
+func (i *Input) ExecuteRequest(bytes []byte) ([]byte, error) {
+	config := &Config{}
+	if err := json.Unmarshal(bytes, config); err != nil {
+		return nil, err
+	}
+	i.Config = config
+
+	ma := api.NewMemoryAccumulator()
+	if err := i.Collect(ma); err != nil {
+		return nil, err
+	}
+	return json.Marshal(ma.Metrics)
+}
+
+func (i *Input) ProcessResponse(_ interface{}, respBytes []byte, err error, a api.Accumulator) error {
+	if err != nil {
+		return err
+	}
+	ma := api.NewMemoryAccumulator()
+	if err := api.NsEnterHelpProcesResponse(respBytes, ma); err != nil {
+		return err
+	}
+	for _, metric := range ma.Metrics {
+		i.afterCollect(metric)
+		a.AddMetric(metric)
+	}
+	return nil
+}
+
+func (i *Input) GenerateErrorMetrics(a api.Accumulator) {
+	a.AddMetric(&model.Metric{
+		Name:  "up",
+		Tags:  map[string]string{},
+		Value: 0,
+	})
+}
+
+func (i *Input) getTimeout() time.Duration {
+	if i.Config.Timeout > 0 {
+		return i.Config.Timeout
+	}
+	return defaultTimeout
+}
+
+// candidateURLs returns base urls to try, the discovered one comes first
+func (i *Input) candidateURLs() []string {
+	if i.discoveredURL == "" {
+		return i.Config.BaseURLs
+	}
+	urls := []string{i.discoveredURL}
+	for _, u := range i.Config.BaseURLs {
+		if u != i.discoveredURL {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+func (i *Input) Collect(a api.Accumulator) error {
+	var vars map[string]json.RawMessage
+	var baseURL string
+	var lastErr error
+	for _, u := range i.candidateURLs() {
+		if err := i.getJson(u+i.varsPath(), &vars); err != nil {
+			lastErr = err
+			continue
+		}
+		if _, ok := vars["memstats"]; !ok {
+			lastErr = fmt.Errorf("no memstats in expvar response of %s", u)
+			continue
+		}
+		baseURL = u
+		break
+	}
+	if baseURL == "" {
+		i.GenerateErrorMetrics(a)
+		if lastErr == nil {
+			lastErr = errors.New("no candidate url")
+		}
+		return lastErr
+	}
+	i.discoveredURL = baseURL
+
+	ma := api.NewMemoryAccumulator()
+	ma.AddMetric(&model.Metric{Name: "up", Value: 1})
+	if err := i.collectVars(vars, ma); err != nil {
+		return err
+	}
+	i.collectPprof(baseURL, ma)
+
+	for _, metric := range ma.Metrics {
+		if metric.Tags == nil {
+			metric.Tags = make(map[string]string)
+		}
+		i.afterCollect(metric)
+		a.AddMetric(metric)
+	}
+	return nil
+}
+
+// afterCollect updates internal state from metric
+func (i *Input) afterCollect(metric *model.Metric) {
+	if metric.Name == metricNumGC {
+		i.Config.LastNumGC = uint32(metric.Value)
+		i.Config.HasLastNumGC = true
+	}
+}
+
+func (i *Input) varsPath() string {
+	if i.Config.VarsPath != "" {
+		return i.Config.VarsPath
+	}
+	return defaultVarsPath
+}
+
+func (i *Input) pprofPath() string {
+	if i.Config.PprofPath != "" {
+		return i.Config.PprofPath
+	}
+	return defaultPprofPath
+}
+
+// collectPprof collects goroutine and thread count from pprof debug pages.
+// It is optional, errors are ignored.
+func (i *Input) collectPprof(baseURL string, a api.Accumulator) {
+	for _, profile := range []string{"goroutine", "threadcreate"} {
+		content, err := i.get(fmt.Sprintf("%s%s/%s?debug=1", baseURL, i.pprofPath(), profile))
+		if err != nil {
+			continue
+		}
+		if total, ok := parsePprofTotal(content); ok {
+			a.AddMetric(&model.Metric{Name: profile + "_total", Value: total})
+		}
+	}
+}
+
+func (i *Input) getJson(url string, v interface{}) error {
+	content, err := i.get(url)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, v)
+}
+
+func (i *Input) get(url string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), i.getTimeout())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := (&http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error { return http.ErrUseLastResponse },
+	}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, notFoundErr
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("bad response code=[%d] content=[%s]", resp.StatusCode, strings.TrimSpace(string(content)))
+	}
+	return content, nil
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package golang
+
+import (
+	"encoding/json"
+	"expvar"
+	"github.com/stretchr/testify/assert"
+	"github.com/traas-stack/holoinsight-agent/pkg/plugin/api"
+	"net/http"
+	"net/http/httptest"
+	"net/http/pprof"
+	"runtime"
+	"testing"
+)
+
+func TestGolang(t *testing.T) {
+	requests := expvar.NewMap("test_requests")
+	requests.Add("ok", 3)
+	requests.Add("fail", 1)
+	expvar.NewInt("test_queue_size").Set(7)
+	expvar.NewString("test_version").Set("v1")
+
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	input := &Input{Config: &Config{
+		// The first candidate is unavailable
+		BaseURLs: []string{"http://127.0.0.1:1", server.URL},
+	}}
+
+	ma := api.NewMemoryAccumulator()
+	assert.NoError(t, input.Collect(ma))
+	assert.Equal(t, server.URL, input.discoveredURL)
+	assert.True(t, input.Config.HasLastNumGC)
+
+	values := make(map[string]float64)
+	for _, m := range ma.Metrics {
+		key := m.Name
+		if k, ok := m.Tags["key"]; ok {
+			key += "/" + k
+		}
+		values[key] = m.Value
+	}
+	assert.Equal(t, float64(1), values["up"])
+	assert.Greater(t, values["memstats_heap_alloc"], float64(0))
+	assert.Greater(t, values["goroutine_total"], float64(0))
+	assert.Equal(t, float64(3), values["var_test_requests/ok"])
+	assert.Equal(t, float64(7), values["var_test_queue_size"])
+	assert.NotContains(t, values, "var_test_version")
+	// No gc pause metrics for the first collection
+	assert.NotContains(t, values, "gc_count")
+
+	runtime.GC()
+	ma = api.NewMemoryAccumulator()
+	assert.NoError(t, input.Collect(ma))
+	names := make(map[string]bool)
+	for _, m := range ma.Metrics {
+		names[m.Name] = true
+	}
+	assert.True(t, names["gc_count"])
+	assert.True(t, names["gc_pause_p99"])
+}
+
+func TestRecentPauses(t *testing.T) {
+	ms := &memStats{NumGC: 258}
+	for i := range ms.PauseNs {
+		ms.PauseNs[i] = uint64(i)
+	}
+	// pauses of gc #256 and #257 are stored at index 0 and 1
+	assert.Equal(t, []float64{0, 1}, recentPauses(ms, 256))
+	assert.Len(t, recentPauses(ms, 0), pauseBufferSize)
+
+	// target process restarted
+	assert.Equal(t, uint32(2), gcCountSince(2, 258))
+	assert.Equal(t, []float64{0, 1}, recentPauses(&memStats{NumGC: 2, PauseNs: ms.PauseNs}, 258))
+
+	assert.Equal(t, float64(1), quantile([]float64{0, 1}, 0.99))
+	assert.Equal(t, float64(0), quantile([]float64{0, 1}, 0.5))
+}
+
+func TestConfigRoundTrip(t *testing.T) {
+	input := &Input{Config: &Config{BaseURLs: []string{"http://10.0.0.1:6060"}, LastNumGC: 3, HasLastNumGC: true}}
+	_, actionType, bs, _, err := input.SerializeRequest()
+	assert.NoError(t, err)
+	assert.Equal(t, HelperInputProxyConfigType, actionType)
+
+	config := &Config{}
+	assert.NoError(t, json.Unmarshal(bs, config))
+	assert.Equal(t, input.Config, config)
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package golangw
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/traas-stack/holoinsight-agent/pkg/collecttask"
+	"github.com/traas-stack/holoinsight-agent/pkg/ioc"
+	"github.com/traas-stack/holoinsight-agent/pkg/plugin/api"
+	"github.com/traas-stack/holoinsight-agent/pkg/plugin/input/golang"
+	"github.com/traas-stack/holoinsight-agent/pkg/plugin/input/standard/providers"
+	v1 "k8s.io/api/core/v1"
+	"time"
+)
+
+const (
+	defaultPort    = 6060
+	defaultTimeout = 3 * time.Second
+	// maxCandidatePorts limits the number of ports to try when discovering endpoint
+	maxCandidatePorts = 5
+)
+
+type (
+	GolangConf struct {
+		// Port of expvar endpoint, if it is not specified, ports declared by pod containers are tried one by one
+		Port        int      `json:"port"`
+		VarsPath    string   `json:"varsPath"`
+		PprofPath   string   `json:"pprofPath"`
+		Timeout     int      `json:"timeout"`
+		Vars        []string `json:"vars"`
+		NetworkMode string   `json:"networkMode"`
+	}
+)
+
+func init() {
+	providers.RegisterInputProvider("golangtask", Parse)
+}
+
+func Parse(task *collecttask.CollectTask) (api.Input, error) {
+	conf := &GolangConf{}
+	if err := json.Unmarshal(task.Config.Content, conf); err != nil {
+		return nil, err
+	}
+
+	var host string
+	var ports []int
+	switch task.Target.Type {
+	case collecttask.TargetLocalhost:
+		host = "localhost"
+	case collecttask.TargetPod:
+		host = task.Target.GetIP()
+		if conf.Port <= 0 {
+			ports = discoverPodPorts(task.Target.GetNamespace(), task.Target.GetPodName())
+		}
+	default:
+		return nil, fmt.Errorf("unsupported target type %+v", task.Target)
+	}
+	if conf.Port > 0 {
+		ports = []int{conf.Port}
+	}
+	if len(ports) == 0 {
+		ports = []int{defaultPort}
+	}
+
+	var baseURLs []string
+	for _, port := range ports {
+		baseURLs = append(baseURLs, fmt.Sprintf("http://%s:%d", host, port))
+	}
+
+	timeout := defaultTimeout
+	if conf.Timeout > 0 {
+		timeout = time.Duration(conf.Timeout) * time.Millisecond
+	}
+
+	return &golang.Input{
+		Config: &golang.Config{
+			BaseURLs:    baseURLs,
+			VarsPath:    conf.VarsPath,
+			PprofPath:   conf.PprofPath,
+			Timeout:     timeout,
+			Vars:        conf.Vars,
+			NetworkMode: conf.NetworkMode,
+		},
+	}, nil
+}
+
+// discoverPodPorts returns TCP ports declared by containers of the pod, the default port comes first.
+func discoverPodPorts(namespace, podName string) []int {
+	ports := []int{defaultPort}
+	if ioc.Crii == nil {
+		return ports
+	}
+	pod, err := ioc.Crii.GetPod(namespace, podName)
+	if err != nil || pod.Pod == nil {
+		return ports
+	}
+	for _, container := range pod.Spec.Containers {
+		for _, p := range container.Ports {
+			if p.Protocol != "" && p.Protocol != v1.ProtocolTCP {
+				continue
+			}
+			port := int(p.ContainerPort)
+			if port <= 0 || port == defaultPort {
+				continue
+			}
+			ports = append(ports, port)
+			if len(ports) >= maxCandidatePorts {
+				return ports
+			}
+		}
+	}
+	return ports
+}
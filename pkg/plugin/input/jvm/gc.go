@@ -0,0 +1,106 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package jvm
+
+import (
+	"fmt"
+	"github.com/spf13/cast"
+	"regexp"
+	"strings"
+)
+
+const (
+	gcCollectorPrefix = "gc_"
+	// maxCollectors is the max number of 'sun.gc.collector.N' to scan, there are at most 3 collectors in JDK 8~17
+	maxCollectors = 8
+)
+
+// collectorNames maps collector names in hsperfdata to stable metric names.
+// The same collector has different names across JDK versions, so we normalize them here.
+// Note: hsperfdata doesn't distinguish G1 young pauses from mixed pauses, they are both counted as 'g1_young'.
+var collectorNames = map[string]string{
+	// Serial
+	"Copy": "serial_young",
+	"MSC":  "serial_old",
+	// Parallel
+	"PSScavenge":        "parallel_young",
+	"PSParallelCompact": "parallel_old",
+	"PSMarkSweep":       "parallel_old",
+	// CMS (JDK 8~13)
+	"PCopy":  "parnew",
+	"ParNew": "parnew",
+	"CMS":    "cms",
+	// G1 JDK 8~11
+	"G1 incremental collections":         "g1_young",
+	"G1 stop-the-world full collections": "g1_full",
+	// G1 JDK 11+, remark and cleanup pauses of concurrent cycles
+	"G1 stop-the-world phases": "g1_concurrent",
+	// G1 JDK 17+
+	"G1 young collection pauses":      "g1_young",
+	"G1 full collection pauses":       "g1_full",
+	"G1 concurrent collection pauses": "g1_concurrent",
+	// ZGC, invocations are counted per cycle
+	"Z concurrent cycle pauses": "zgc_cycles",
+	"Z minor cycle pauses":      "zgc_minor_cycles",
+	"Z major cycle pauses":      "zgc_major_cycles",
+	// Shenandoah
+	"Shenandoah Stop-the-World Pauses": "shenandoah_pauses",
+	"Shenandoah Concurrent Cycles":     "shenandoah_cycles",
+}
+
+var illegalMetricCharRegexp = regexp.MustCompile("[^a-z0-9]+")
+
+// normalizeCollectorName converts collector name to a stable metric name part
+func normalizeCollectorName(name string) string {
+	if x, ok := collectorNames[name]; ok {
+		return x
+	}
+	return strings.Trim(illegalMetricCharRegexp.ReplaceAllString(strings.ToLower(name), "_"), "_")
+}
+
+// addGcCollectorMetrics adds per collector metrics: gc_{collector}_count/time/last_pause
+func addGcCollectorMetrics(perfData map[string]interface{}, rawMetrics map[string]interface{}) {
+	for i := 0; i < maxCollectors; i++ {
+		prefix := fmt.Sprintf("sun.gc.collector.%d.", i)
+		name, ok := perfData[prefix+"name"].(string)
+		if !ok {
+			break
+		}
+		collector := normalizeCollectorName(name)
+		if collector == "" {
+			continue
+		}
+		metricPrefix := gcCollectorPrefix + collector
+
+		addFloat64(perfData, rawMetrics, prefix+"invocations", metricPrefix+"_count")
+		addFloat64(perfData, rawMetrics, prefix+"time", metricPrefix+"_time")
+
+		// Duration of the last gc pause in ms
+		entry, err1 := cast.ToFloat64E(perfData[prefix+"lastEntryTime"])
+		exit, err2 := cast.ToFloat64E(perfData[prefix+"lastExitTime"])
+		if err1 == nil && err2 == nil && exit >= entry && perfData[prefix+"lastExitTime"] != nil {
+			rawMetrics[metricPrefix+"_last_pause"] = (exit - entry) / 1e6
+		}
+	}
+}
+
+// jdkMajorVersion returns major version of JDK, such as 8/11/17. Returns 0 if unknown.
+func jdkMajorVersion(perfData map[string]interface{}) int {
+	for _, key := range []string{"java.property.java.vm.specification.version", "java.property.java.version"} {
+		if s, ok := perfData[key].(string); ok && s != "" {
+			return parseJdkMajorVersion(s)
+		}
+	}
+	return 0
+}
+
+// parseJdkMajorVersion parses "1.8"/"1.8.0_292" to 8, "11"/"11.0.12" to 11, "17" to 17
+func parseJdkMajorVersion(s string) int {
+	s = strings.TrimPrefix(s, "1.")
+	if index := strings.IndexAny(s, "._-+"); index >= 0 {
+		s = s[:index]
+	}
+	return cast.ToInt(s)
+}
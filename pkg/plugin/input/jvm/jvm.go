@@ -28,6 +28,8 @@ var timeMetrics = map[string]bool{
 	"safepoint_time":      true,
 	"safepoint_sync_time": true,
 	"application_time":    true,
+	"class_load_time":     true,
+	"compile_time":        true,
 }
 
 func init() {
@@ -47,11 +49,33 @@ func init() {
 	accuMetrics["futile_wakeups"] = true
 	accuMetrics["deflations"] = true
 	accuMetrics["contended_lock_attempts"] = true
+	accuMetrics["class_load_time"] = true
+	accuMetrics["compiles"] = true
+	accuMetrics["compile_bailouts"] = true
+	accuMetrics["compile_invalidates"] = true
+	accuMetrics["compile_time"] = true
+}
+
+// isAccuMetric returns true if the metric is a cumulative counter which should be reported as delta
+func isAccuMetric(key string) bool {
+	if accuMetrics[key] {
+		return true
+	}
+	return strings.HasPrefix(key, gcCollectorPrefix) && (strings.HasSuffix(key, "_count") || strings.HasSuffix(key, "_time"))
+}
+
+// isTimeMetric returns true if the metric is a time counter in nanoseconds
+func isTimeMetric(key string) bool {
+	if timeMetrics[key] {
+		return true
+	}
+	return strings.HasPrefix(key, gcCollectorPrefix) && strings.HasSuffix(key, "_time")
 }
 
 func addJvmMetricsFromProcess(javaProcess *criutils.ProcessInfo, rawMetrics map[string]interface{}, tags map[string]string) {
 	maxMetaspaceSize := int64(0)
 	reservedCodeCacheSize := int64(0)
+	maxDirectMemorySize := int64(0)
 
 	if javaProcess != nil {
 		for _, e := range javaProcess.CmdlineSlice {
@@ -65,6 +89,10 @@ func addJvmMetricsFromProcess(javaProcess *criutils.ProcessInfo, rawMetrics map[
 				valueStr := e[len("-XX:ReservedCodeCacheSize="):]
 				reservedCodeCacheSize = convertToBytes(valueStr)
 			}
+			if strings.HasPrefix(e, "-XX:MaxDirectMemorySize=") {
+				valueStr := e[len("-XX:MaxDirectMemorySize="):]
+				maxDirectMemorySize = convertToBytes(valueStr)
+			}
 		}
 		tags["user"] = javaProcess.User
 	}
@@ -72,6 +100,9 @@ func addJvmMetricsFromProcess(javaProcess *criutils.ProcessInfo, rawMetrics map[
 	// 2
 	rawMetrics["meta_max"] = float64(maxMetaspaceSize)
 	rawMetrics["reserved_code_cache_size"] = float64(reservedCodeCacheSize)
+	// Usage of direct/mapped buffer pools is only exposed by BufferPoolMXBean, hsperfdata doesn't contain it.
+	// So only the limit is reported here.
+	rawMetrics["direct_max"] = float64(maxDirectMemorySize)
 }
 
 func addJvmMetrics(perfData map[string]interface{}, rawMetrics map[string]interface{}) {
@@ -89,6 +120,7 @@ func addJvmMetrics(perfData map[string]interface{}, rawMetrics map[string]interf
 	addFloat64(perfData, rawMetrics, "sun.gc.metaspace.maxCapacity", "meta_max")
 	addFloat64(perfData, rawMetrics, "sun.gc.compressedclassspace.used", "compressedclass_used")
 	addFloat64(perfData, rawMetrics, "sun.gc.compressedclassspace.capacity", "compressedclass_capacity")
+	addFloat64(perfData, rawMetrics, "sun.gc.compressedclassspace.maxCapacity", "compressedclass_max")
 
 	// 3
 	addFloat64(perfData, rawMetrics, "sun.gc.generation.2.space.0.used", "perm_used")
@@ -133,11 +165,26 @@ func addJvmMetrics(perfData map[string]interface{}, rawMetrics map[string]interf
 	addFloat64(perfData, rawMetrics, "java.cls.unloadedClasses", "unloaded_classes")
 	addFloat64(perfData, rawMetrics, "java.cls.sharedLoadedClasses", "shared_loaded_classes")
 	addFloat64(perfData, rawMetrics, "java.cls.sharedUnloadedClasses", "shared_unloaded_classes")
+	addFloat64(perfData, rawMetrics, "sun.cls.time", "class_load_time")
 
 	// code cache相关
+	// Usage of code heaps is only exposed by MemoryPoolMXBean, nmethod sizes are the closest hsperfdata counters.
 	// 2
 	addFloat64(perfData, rawMetrics, "sun.ci.nmethodCodeSize", "nmethod_code_size")
 	addFloat64(perfData, rawMetrics, "sun.ci.nmethodSize", "nmethod_size")
+
+	// jit相关
+	// 4
+	addFloat64(perfData, rawMetrics, "sun.ci.totalCompiles", "compiles")
+	addFloat64(perfData, rawMetrics, "sun.ci.totalBailouts", "compile_bailouts")
+	addFloat64(perfData, rawMetrics, "sun.ci.totalInvalidates", "compile_invalidates")
+	addFloat64(perfData, rawMetrics, "sun.ci.totalTime", "compile_time")
+
+	addGcCollectorMetrics(perfData, rawMetrics)
+
+	if version := jdkMajorVersion(perfData); version > 0 {
+		rawMetrics["jdk_major_version"] = float64(version)
+	}
 }
 
 func calcFinalMetrics(rawMetrics map[string]interface{}, lastPidState *pidJvmState) map[string]interface{} {
@@ -146,24 +193,25 @@ func calcFinalMetrics(rawMetrics map[string]interface{}, lastPidState *pidJvmSta
 		finalMetrics[k] = v
 	}
 
-	if lastPidState != nil {
-		for key := range accuMetrics {
-			if f64, err := cast.ToFloat64E(lastPidState.RawMetrics[key]); err == nil {
-				v := math.Max(finalMetrics[key].(float64)-f64, 0)
-
-				if _, ok := timeMetrics[key]; ok {
-					v /= float64(time.Second.Nanoseconds())
-				}
+	for key, value := range rawMetrics {
+		if !isAccuMetric(key) {
+			continue
+		}
+		if lastPidState == nil {
+			// 如果没有老状态那么要清掉这些指标
+			delete(finalMetrics, key)
+			continue
+		}
+		if f64, err := cast.ToFloat64E(lastPidState.RawMetrics[key]); err == nil && lastPidState.RawMetrics[key] != nil {
+			v := math.Max(cast.ToFloat64(value)-f64, 0)
 
-				finalMetrics[key] = v
-			} else {
-				// 否则要清掉这些指标
-				delete(finalMetrics, key)
+			if isTimeMetric(key) {
+				v /= float64(time.Second.Nanoseconds())
 			}
-		}
-	} else {
-		// 如果没有老状态那么要清掉这些指标
-		for key := range accuMetrics {
+
+			finalMetrics[key] = v
+		} else {
+			// 否则要清掉这些指标
 			delete(finalMetrics, key)
 		}
 	}
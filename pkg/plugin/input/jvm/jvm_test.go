@@ -0,0 +1,59 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package jvm
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseJdkMajorVersion(t *testing.T) {
+	assert.Equal(t, 8, parseJdkMajorVersion("1.8"))
+	assert.Equal(t, 8, parseJdkMajorVersion("1.8.0_292"))
+	assert.Equal(t, 11, parseJdkMajorVersion("11"))
+	assert.Equal(t, 11, parseJdkMajorVersion("11.0.12"))
+	assert.Equal(t, 17, parseJdkMajorVersion("17"))
+	assert.Equal(t, 21, parseJdkMajorVersion("21-ea"))
+}
+
+func TestGcCollectorMetrics(t *testing.T) {
+	perfData := map[string]interface{}{
+		"java.property.java.vm.specification.version": "17",
+		"sun.gc.collector.0.name":                     "G1 young collection pauses",
+		"sun.gc.collector.0.invocations":              int64(10),
+		"sun.gc.collector.0.time":                     int64(2_000_000_000),
+		"sun.gc.collector.0.lastEntryTime":            int64(5_000_000),
+		"sun.gc.collector.0.lastExitTime":             int64(8_000_000),
+		"sun.gc.collector.1.name":                     "G1 stop-the-world full collections",
+		"sun.gc.collector.1.invocations":              int64(1),
+		"sun.gc.collector.1.time":                     int64(1_000_000_000),
+		"sun.gc.collector.2.name":                     "Some Future Collector",
+		"sun.gc.collector.2.invocations":              int64(3),
+	}
+	raw := make(map[string]interface{})
+	addJvmMetrics(perfData, raw)
+
+	assert.Equal(t, float64(10), raw["gc_g1_young_count"])
+	assert.Equal(t, float64(3), raw["gc_g1_young_last_pause"])
+	assert.Equal(t, float64(1), raw["gc_g1_full_count"])
+	assert.Equal(t, float64(3), raw["gc_some_future_collector_count"])
+	assert.Equal(t, float64(17), raw["jdk_major_version"])
+
+	// First collection has no delta
+	final := calcFinalMetrics(raw, nil)
+	assert.NotContains(t, final, "gc_g1_young_count")
+	assert.Contains(t, final, "gc_g1_young_last_pause")
+
+	next := map[string]interface{}{
+		"gc_g1_young_count": float64(15),
+		"gc_g1_young_time":  float64(3_000_000_000),
+		// A counter which is absent in last state
+		"compiles": float64(100),
+	}
+	final = calcFinalMetrics(next, &pidJvmState{RawMetrics: raw})
+	assert.Equal(t, float64(5), final["gc_g1_young_count"])
+	assert.Equal(t, float64(1), final["gc_g1_young_time"])
+	assert.NotContains(t, final, "compiles")
+}
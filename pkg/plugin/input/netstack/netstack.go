@@ -0,0 +1,252 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package netstack
+
+import (
+	"encoding/gob"
+	"github.com/traas-stack/holoinsight-agent/pkg/logger"
+	"github.com/traas-stack/holoinsight-agent/pkg/plugin/api"
+	"github.com/traas-stack/holoinsight-agent/pkg/plugin/input"
+	"github.com/traas-stack/holoinsight-agent/pkg/util"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type (
+	netstackInput struct {
+		// procDir returns the proc dir to read, files under '<procDir>/net' reflect the network namespace of the proc dir owner.
+		procDir func() (string, error)
+		// nodeLevel indicates whether to collect node wide statistics (softnet and conntrack sysctls)
+		nodeLevel bool
+		state     *internalState
+	}
+	internalState struct {
+		Time int64
+		// Dir is the proc dir of last collection, counters are not comparable when it changes
+		Dir string
+		// Counters are raw values of cumulative counters of last collection
+		Counters map[string]float64
+	}
+)
+
+var (
+	// snmpCounters are cumulative counters of /proc/net/snmp and /proc/net/netstat to report, proto -> counter -> metric name
+	snmpCounters = map[string]map[string]string{
+		"Tcp": {
+			"ActiveOpens":  "snmp_tcp_active_opens",
+			"PassiveOpens": "snmp_tcp_passive_opens",
+			"AttemptFails": "snmp_tcp_attempt_fails",
+			"EstabResets":  "snmp_tcp_estab_resets",
+			"InSegs":       "snmp_tcp_in_segs",
+			"OutSegs":      "snmp_tcp_out_segs",
+			"RetransSegs":  "snmp_tcp_retrans_segs",
+			"InErrs":       "snmp_tcp_in_errs",
+			"OutRsts":      "snmp_tcp_out_rsts",
+		},
+		"Udp": {
+			"InErrors":     "snmp_udp_in_errors",
+			"NoPorts":      "snmp_udp_no_ports",
+			"RcvbufErrors": "snmp_udp_rcvbuf_errors",
+			"SndbufErrors": "snmp_udp_sndbuf_errors",
+		},
+		"TcpExt": {
+			"ListenOverflows":   "tcpext_listen_overflows",
+			"ListenDrops":       "tcpext_listen_drops",
+			"TCPBacklogDrop":    "tcpext_backlog_drop",
+			"TCPTimeouts":       "tcpext_timeouts",
+			"TCPSynRetrans":     "tcpext_syn_retrans",
+			"TCPFastRetrans":    "tcpext_fast_retrans",
+			"TCPLostRetransmit": "tcpext_lost_retransmit",
+			"TCPAbortOnData":    "tcpext_abort_on_data",
+			"TCPAbortOnTimeout": "tcpext_abort_on_timeout",
+			"TCPAbortOnMemory":  "tcpext_abort_on_memory",
+			"TCPRcvQDrop":       "tcpext_rcvq_drop",
+			"PruneCalled":       "tcpext_prune_called",
+			"SyncookiesSent":    "tcpext_syncookies_sent",
+		},
+	}
+	// conntrackStatCounters are cumulative counters of /proc/net/stat/nf_conntrack to report
+	conntrackStatCounters = map[string]string{
+		"drop":           "conntrack_drop",
+		"early_drop":     "conntrack_early_drop",
+		"insert_failed":  "conntrack_insert_failed",
+		"invalid":        "conntrack_invalid",
+		"search_restart": "conntrack_search_restart",
+	}
+)
+
+func init() {
+	gob.Register(&internalState{})
+
+	input.Register("netstack", func(config input.Config) (api.Input, error) {
+		return &netstackInput{
+			procDir:   hostProcDir,
+			nodeLevel: true,
+		}, nil
+	})
+}
+
+// hostProcDir returns proc dir of node, HOST_PROC is respected as what gopsutil does
+func hostProcDir() (string, error) {
+	if s := os.Getenv("HOST_PROC"); s != "" {
+		return s, nil
+	}
+	return "/proc", nil
+}
+
+func (i *netstackInput) GetDefaultPrefix() string {
+	return ""
+}
+
+func (i *netstackInput) SaveState() (interface{}, error) {
+	return i.state, nil
+}
+
+func (i *netstackInput) LoadState(state interface{}) error {
+	i.state = state.(*internalState)
+	return nil
+}
+
+func (i *netstackInput) Collect(a api.Accumulator) error {
+	dir, err := i.procDir()
+	if err != nil {
+		return err
+	}
+
+	gauges := make(map[string]float64)
+	counters := make(map[string]float64)
+
+	// sockstat is always available, treat its failure as collection failure
+	content, err := os.ReadFile(filepath.Join(dir, "net", "sockstat"))
+	if err != nil {
+		return err
+	}
+	for key, value := range parseSockstat(content) {
+		gauges["sockstat_"+key] = value
+	}
+
+	i.collectSnmp(dir, gauges, counters)
+	i.collectConntrack(dir, gauges, counters)
+	if i.nodeLevel {
+		i.collectSoftnet(dir, counters)
+	}
+
+	now := util.CurrentMS()
+	last := i.state
+	i.state = &internalState{Time: now, Dir: dir, Counters: counters}
+
+	values := make(map[string]interface{}, len(gauges)+len(counters))
+	for name, value := range gauges {
+		values[name] = value
+	}
+	if last != nil && last.Dir == dir {
+		for name, value := range counters {
+			// Counters may be reset when network namespace is recreated
+			if lastValue, ok := last.Counters[name]; ok && value >= lastValue {
+				values[name] = value - lastValue
+			}
+		}
+	}
+	input.AddMetrics(a, values)
+	return nil
+}
+
+func (i *netstackInput) collectSnmp(dir string, gauges, counters map[string]float64) {
+	for _, name := range []string{"snmp", "netstat"} {
+		content, err := readOptionalFile(filepath.Join(dir, "net", name))
+		if err != nil || content == nil {
+			continue
+		}
+		pc, err := parseProtoCounters(content)
+		if err != nil {
+			logger.Warnz("[netstack] parse error", zap.String("file", name), zap.Error(err))
+			continue
+		}
+		for proto, mapping := range snmpCounters {
+			for counter, metricName := range mapping {
+				if value, ok := pc[proto][counter]; ok {
+					counters[metricName] = value
+				}
+			}
+		}
+		if value, ok := pc["Tcp"]["CurrEstab"]; ok {
+			gauges["snmp_tcp_curr_estab"] = value
+		}
+	}
+}
+
+func (i *netstackInput) collectConntrack(dir string, gauges, counters map[string]float64) {
+	// Conntrack module may be not loaded
+	if content, err := readOptionalFile(filepath.Join(dir, "net", "stat", "nf_conntrack")); err == nil && content != nil {
+		if stat, err := parseConntrackStat(content); err != nil {
+			logger.Warnz("[netstack] parse conntrack stat error", zap.Error(err))
+		} else {
+			if entries, ok := stat["entries"]; ok {
+				// Since kernel 4.x 'entries' is the count of current network namespace
+				gauges["conntrack_count"] = entries
+			}
+			for key, metricName := range conntrackStatCounters {
+				if value, ok := stat[key]; ok {
+					counters[metricName] = value
+				}
+			}
+		}
+	}
+
+	if !i.nodeLevel {
+		return
+	}
+	count, countOk := readSysctlValue(filepath.Join(dir, "sys", "net", "netfilter", "nf_conntrack_count"))
+	max, maxOk := readSysctlValue(filepath.Join(dir, "sys", "net", "netfilter", "nf_conntrack_max"))
+	if countOk {
+		gauges["conntrack_count"] = count
+	}
+	if maxOk {
+		gauges["conntrack_max"] = max
+		if max > 0 {
+			gauges["conntrack_usage"] = 100 * gauges["conntrack_count"] / max
+		}
+	}
+}
+
+func (i *netstackInput) collectSoftnet(dir string, counters map[string]float64) {
+	content, err := readOptionalFile(filepath.Join(dir, "net", "softnet_stat"))
+	if err != nil || content == nil {
+		return
+	}
+	processed, dropped, timeSqueeze, err := parseSoftnetStat(content)
+	if err != nil {
+		logger.Warnz("[netstack] parse softnet_stat error", zap.Error(err))
+		return
+	}
+	counters["softnet_processed"] = processed
+	counters["softnet_dropped"] = dropped
+	counters["softnet_time_squeeze"] = timeSqueeze
+}
+
+// readOptionalFile returns nil content and nil error if file does not exist
+func readOptionalFile(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		logger.Warnz("[netstack] read file error", zap.String("path", path), zap.Error(err))
+		return nil, err
+	}
+	return content, nil
+}
+
+func readSysctlValue(path string) (float64, bool) {
+	content, err := readOptionalFile(path)
+	if err != nil || content == nil {
+		return 0, false
+	}
+	f64, err := strconv.ParseFloat(strings.TrimSpace(string(content)), 64)
+	return f64, err == nil
+}
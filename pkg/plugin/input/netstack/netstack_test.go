@@ -0,0 +1,106 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package netstack
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/traas-stack/holoinsight-agent/pkg/plugin/api"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	testSnmp = `Ip: Forwarding DefaultTTL
+Ip: 1 64
+Tcp: RtoAlgorithm RtoMin RtoMax MaxConn ActiveOpens PassiveOpens AttemptFails EstabResets CurrEstab InSegs OutSegs RetransSegs InErrs OutRsts InCsumErrors
+Tcp: 1 200 120000 -1 %d 20 3 4 32 1000 2000 %d 0 5 0
+`
+	testNetstat = `TcpExt: SyncookiesSent ListenOverflows ListenDrops
+TcpExt: 0 %d 7
+`
+	testConntrackStat = `entries  searched found new invalid ignore delete delete_list insert insert_failed drop early_drop icmp_error  expect_new expect_create expect_delete search_restart
+00000064  00000000 00000000 00000000 00000002 00000000 00000000 00000000 00000000 00000001 00000000 00000000 00000000  00000000 00000000 00000000 00000000
+00000064  00000000 00000000 00000000 00000003 00000000 00000000 00000000 00000000 00000000 0000000a 00000000 00000000  00000000 00000000 00000000 00000000
+`
+)
+
+func writeProcFiles(t *testing.T, dir string, retrans, listenOverflows int) {
+	files := map[string]string{
+		"net/sockstat":                         "sockets: used 290\nTCP: inuse 12 orphan 1 tw 3 alloc 15 mem 2\nUDP: inuse 3 mem 1\n",
+		"net/snmp":                             fmt.Sprintf(testSnmp, 10+retrans, retrans),
+		"net/netstat":                          fmt.Sprintf(testNetstat, listenOverflows),
+		"net/softnet_stat":                     "0000000a 00000001 00000002 00000000\n00000014 00000000 00000003 00000000\n",
+		"net/stat/nf_conntrack":                testConntrackStat,
+		"sys/net/netfilter/nf_conntrack_count": "150\n",
+		"sys/net/netfilter/nf_conntrack_max":   "1000\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+func collect(t *testing.T, i *netstackInput) map[string]float64 {
+	ma := api.NewMemoryAccumulator()
+	assert.NoError(t, i.Collect(ma))
+	values := make(map[string]float64)
+	for _, m := range ma.Metrics {
+		values[m.Name] = m.Value
+	}
+	return values
+}
+
+func TestNetstack(t *testing.T) {
+	dir := t.TempDir()
+	writeProcFiles(t, dir, 100, 5)
+
+	i := &netstackInput{
+		procDir:   func() (string, error) { return dir, nil },
+		nodeLevel: true,
+	}
+	values := collect(t, i)
+	assert.Equal(t, float64(290), values["sockstat_sockets_used"])
+	assert.Equal(t, float64(3), values["sockstat_tcp_tw"])
+	assert.Equal(t, float64(32), values["snmp_tcp_curr_estab"])
+	assert.Equal(t, float64(150), values["conntrack_count"])
+	assert.Equal(t, float64(15), values["conntrack_usage"])
+	// No deltas for the first collection
+	assert.NotContains(t, values, "snmp_tcp_retrans_segs")
+
+	writeProcFiles(t, dir, 130, 8)
+	values = collect(t, i)
+	assert.Equal(t, float64(30), values["snmp_tcp_retrans_segs"])
+	assert.Equal(t, float64(30), values["snmp_tcp_active_opens"])
+	assert.Equal(t, float64(3), values["tcpext_listen_overflows"])
+	assert.Equal(t, float64(0), values["tcpext_listen_drops"])
+	assert.Equal(t, float64(0), values["softnet_dropped"])
+	assert.Equal(t, float64(0), values["conntrack_drop"])
+
+	// Pod level collection reads conntrack count from stat file and skips node wide statistics
+	pod := &netstackInput{procDir: func() (string, error) { return dir, nil }}
+	values = collect(t, pod)
+	assert.Equal(t, float64(100), values["conntrack_count"])
+	assert.NotContains(t, values, "conntrack_max")
+	assert.NotContains(t, values, "softnet_processed")
+}
+
+func TestParse(t *testing.T) {
+	processed, dropped, timeSqueeze, err := parseSoftnetStat([]byte("0000000a 00000001 00000002 00000000\n00000014 00000000 00000003 00000000\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{30, 1, 5}, []float64{processed, dropped, timeSqueeze})
+
+	stat, err := parseConntrackStat([]byte(testConntrackStat))
+	assert.NoError(t, err)
+	assert.Equal(t, float64(100), stat["entries"])
+	assert.Equal(t, float64(5), stat["invalid"])
+	assert.Equal(t, float64(10), stat["drop"])
+	assert.Equal(t, float64(1), stat["insert_failed"])
+
+	_, err = parseProtoCounters([]byte("Tcp: A B\nTcp: 1\n"))
+	assert.Error(t, err)
+}
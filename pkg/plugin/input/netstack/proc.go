@@ -0,0 +1,133 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package netstack
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type (
+	// protoCounters is parsed from /proc/net/snmp or /proc/net/netstat, proto -> counter name -> value
+	protoCounters map[string]map[string]float64
+)
+
+// parseSockstat parses /proc/net/sockstat, such as:
+//
+//	sockets: used 290
+//	TCP: inuse 12 orphan 0 tw 3 alloc 15 mem 2
+//	UDP: inuse 3 mem 1
+//
+// Keys of result are like 'sockets_used' and 'tcp_inuse'.
+func parseSockstat(content []byte) map[string]float64 {
+	ret := make(map[string]float64)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		proto, rest, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		proto = strings.ToLower(strings.TrimSpace(proto))
+		fields := strings.Fields(rest)
+		for j := 0; j+1 < len(fields); j += 2 {
+			f64, err := strconv.ParseFloat(fields[j+1], 64)
+			if err != nil {
+				continue
+			}
+			ret[proto+"_"+fields[j]] = f64
+		}
+	}
+	return ret
+}
+
+// parseProtoCounters parses /proc/net/snmp or /proc/net/netstat. Each protocol has a header line followed by a value line:
+//
+//	Tcp: RtoAlgorithm RtoMin RtoMax MaxConn ActiveOpens ...
+//	Tcp: 1 200 120000 -1 63673644 ...
+func parseProtoCounters(content []byte) (protoCounters, error) {
+	ret := make(protoCounters)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		header := scanner.Text()
+		if !scanner.Scan() {
+			return nil, fmt.Errorf("missing value line of [%s]", header)
+		}
+		proto, names, ok1 := strings.Cut(header, ":")
+		proto2, values, ok2 := strings.Cut(scanner.Text(), ":")
+		if !ok1 || !ok2 || proto != proto2 {
+			return nil, fmt.Errorf("malformed lines of [%s]", header)
+		}
+		nameFields := strings.Fields(names)
+		valueFields := strings.Fields(values)
+		if len(nameFields) != len(valueFields) {
+			return nil, fmt.Errorf("field count mismatch of [%s]", proto)
+		}
+		counters := make(map[string]float64, len(nameFields))
+		for j, name := range nameFields {
+			if f64, err := strconv.ParseFloat(valueFields[j], 64); err == nil {
+				counters[name] = f64
+			}
+		}
+		ret[proto] = counters
+	}
+	return ret, scanner.Err()
+}
+
+// parseSoftnetStat parses /proc/net/softnet_stat and returns sums of the first 3 columns across all cpus.
+// Each line is for a cpu, columns are hex: processed, dropped, time_squeeze, ...
+func parseSoftnetStat(content []byte) (processed, dropped, timeSqueeze float64, err error) {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		var values [3]uint64
+		for j := range values {
+			if values[j], err = strconv.ParseUint(fields[j], 16, 64); err != nil {
+				return
+			}
+		}
+		processed += float64(values[0])
+		dropped += float64(values[1])
+		timeSqueeze += float64(values[2])
+	}
+	err = scanner.Err()
+	return
+}
+
+// parseConntrackStat parses /proc/net/stat/nf_conntrack.
+// The first line is header, each following line is for a cpu and columns are hex.
+// 'entries' is the same for all cpus and is returned as is, other columns are summed.
+func parseConntrackStat(content []byte) (map[string]float64, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	if !scanner.Scan() {
+		return nil, fmt.Errorf("empty conntrack stat")
+	}
+	names := strings.Fields(scanner.Text())
+	ret := make(map[string]float64, len(names))
+	first := true
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != len(names) {
+			continue
+		}
+		for j, name := range names {
+			u64, err := strconv.ParseUint(fields[j], 16, 64)
+			if err != nil {
+				return nil, err
+			}
+			if name == "entries" && !first {
+				continue
+			}
+			ret[name] += float64(u64)
+		}
+		first = false
+	}
+	return ret, scanner.Err()
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package netstack
+
+import (
+	"fmt"
+	"github.com/spf13/cast"
+	"github.com/traas-stack/holoinsight-agent/pkg/collecttask"
+	"github.com/traas-stack/holoinsight-agent/pkg/core"
+	"github.com/traas-stack/holoinsight-agent/pkg/cri/criutils"
+	"github.com/traas-stack/holoinsight-agent/pkg/ioc"
+	"github.com/traas-stack/holoinsight-agent/pkg/plugin/api"
+	"github.com/traas-stack/holoinsight-agent/pkg/plugin/input/standard/providers"
+	"path/filepath"
+)
+
+func init() {
+	providers.RegisterInputProvider("netstacktask", Parse)
+}
+
+func Parse(task *collecttask.CollectTask) (api.Input, error) {
+	switch task.Target.Type {
+	case collecttask.TargetLocalhost:
+		return &netstackInput{procDir: hostProcDir, nodeLevel: true}, nil
+	case collecttask.TargetPod:
+		namespace := task.Target.GetNamespace()
+		podName := task.Target.GetPodName()
+		return &netstackInput{
+			procDir: func() (string, error) {
+				return podProcDir(namespace, podName)
+			},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported target type %+v", task.Target)
+	}
+}
+
+// podProcDir returns '/proc/<pid>' in hostfs of the main biz container of the pod.
+// '/proc/<pid>/net' reflects the network namespace of that process, which is shared by all containers of the pod,
+// so there is no need to enter the network namespace.
+// The pid may change when container restarts, so it is resolved every time.
+func podProcDir(namespace, podName string) (string, error) {
+	if ioc.Crii == nil {
+		return "", fmt.Errorf("cri is not available")
+	}
+	c, err := criutils.GetMainBizContainerE(ioc.Crii, namespace, podName)
+	if err != nil {
+		return "", err
+	}
+	if c.State.Pid <= 0 {
+		return "", fmt.Errorf("container %s is not running", c.Id)
+	}
+	return filepath.Join(core.GetHostfs(), "proc", cast.ToString(c.State.Pid)), nil
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package postgresql
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/traas-stack/holoinsight-agent/pkg/collecttask"
+	"github.com/traas-stack/holoinsight-agent/pkg/plugin/api"
+	"github.com/traas-stack/holoinsight-agent/pkg/plugin/input/standard/providers"
+)
+
+const (
+	defaultPort       = 5432
+	defaultDatabase   = "postgres"
+	defaultSslMode    = "disable"
+	defaultBloatTopN  = 10
+	maxBloatTopN      = 100
+	defaultServerHost = "localhost"
+)
+
+type (
+	Conf struct {
+		// Username/Password/Port/Database are used when target type is pod
+		Username string `json:"username,omitempty"`
+		Password string `json:"password,omitempty"`
+		Port     int    `json:"port,omitempty"`
+		Database string `json:"database,omitempty"`
+		SslMode  string `json:"sslmode,omitempty"`
+		// BloatTopN limits the number of tables reported by bloat metrics
+		BloatTopN int `json:"bloatTopN,omitempty"`
+		// Confs are used when target type is none, same as mysqlw
+		Confs []*Conf_Conf `json:"confs,omitempty"`
+	}
+	Conf_Conf struct {
+		Host     string
+		Port     int
+		User     string
+		Password string
+		Database string
+	}
+)
+
+func init() {
+	providers.RegisterInputProvider("telegraf_postgresql", Parse)
+}
+
+func Parse(task *collecttask.CollectTask) (api.Input, error) {
+	conf := &Conf{}
+	if err := json.Unmarshal(task.Config.Content, conf); err != nil {
+		return nil, err
+	}
+
+	sslMode := conf.SslMode
+	if sslMode == "" {
+		sslMode = defaultSslMode
+	}
+
+	var servers []*server
+	switch task.Target.Type {
+	case collecttask.TargetPod:
+		servers = append(servers, newServer(task.Target.GetIP(), conf.Port, conf.Username, conf.Password, conf.Database, sslMode))
+	case collecttask.TargetNone:
+		for _, c := range conf.Confs {
+			host := c.Host
+			if host == "" {
+				host = defaultServerHost
+			}
+			servers = append(servers, newServer(host, c.Port, c.User, c.Password, c.Database, sslMode))
+		}
+	default:
+		return nil, errors.New("unsupported target type: " + task.Target.Type)
+	}
+	if len(servers) == 0 {
+		return nil, errors.New("empty servers")
+	}
+
+	bloatTopN := conf.BloatTopN
+	if bloatTopN <= 0 {
+		bloatTopN = defaultBloatTopN
+	}
+	if bloatTopN > maxBloatTopN {
+		bloatTopN = maxBloatTopN
+	}
+
+	return &Input{
+		servers:   servers,
+		bloatTopN: bloatTopN,
+		state:     newInternalState(),
+	}, nil
+}
+
+func newServer(host string, port int, user, password, database, sslMode string) *server {
+	if port <= 0 {
+		port = defaultPort
+	}
+	if database == "" {
+		database = defaultDatabase
+	}
+	return &server{
+		host:     host,
+		port:     port,
+		user:     user,
+		password: password,
+		database: database,
+		sslMode:  sslMode,
+	}
+}
@@ -0,0 +1,367 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	_ "github.com/jackc/pgx/v4/stdlib"
+	"github.com/traas-stack/holoinsight-agent/pkg/logger"
+	"github.com/traas-stack/holoinsight-agent/pkg/model"
+	"github.com/traas-stack/holoinsight-agent/pkg/plugin/api"
+	"go.uber.org/zap"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	measurement    = "postgresql"
+	defaultTimeout = 5 * time.Second
+	connectTimeout = 2
+)
+
+type (
+	// Input collects connection, transaction, replication lag and bloat metrics from PostgreSQL servers.
+	// A new connection is created for each collection and closed immediately after, just like telegraf mysql plugin does.
+	Input struct {
+		servers   []*server
+		bloatTopN int
+		state     *internalState
+	}
+	server struct {
+		host     string
+		port     int
+		user     string
+		password string
+		database string
+		sslMode  string
+	}
+	internalState struct {
+		// Counters stores last values of cumulative counters, used to calculate deltas
+		Counters map[string]float64
+	}
+	// collectContext holds per-collection data
+	collectContext struct {
+		ctx      context.Context
+		db       *sql.DB
+		a        api.Accumulator
+		tags     map[string]string
+		counters map[string]float64
+	}
+)
+
+// counterFields are cumulative counters in pg_stat_database, they are reported as deltas between collections.
+var counterFields = []string{
+	"xact_commit",
+	"xact_rollback",
+	"blks_read",
+	"blks_hit",
+	"tup_returned",
+	"tup_fetched",
+	"tup_inserted",
+	"tup_updated",
+	"tup_deleted",
+	"conflicts",
+	"temp_files",
+	"temp_bytes",
+	"deadlocks",
+}
+
+func newInternalState() *internalState {
+	return &internalState{Counters: make(map[string]float64)}
+}
+
+func (s *server) address() string {
+	return net.JoinHostPort(s.host, strconv.Itoa(s.port))
+}
+
+// dsn builds connection string in url format, credentials are escaped properly.
+func (s *server) dsn() string {
+	u := &url.URL{
+		Scheme: "postgres",
+		Host:   s.address(),
+		Path:   "/" + s.database,
+	}
+	if s.password != "" {
+		u.User = url.UserPassword(s.user, s.password)
+	} else if s.user != "" {
+		u.User = url.User(s.user)
+	}
+	q := url.Values{}
+	q.Set("sslmode", s.sslMode)
+	q.Set("connect_timeout", strconv.Itoa(connectTimeout))
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func (i *Input) GetDefaultPrefix() string {
+	return "telegraf_"
+}
+
+func (i *Input) DebugInfo() map[string]interface{} {
+	var addresses []string
+	for _, s := range i.servers {
+		addresses = append(addresses, s.address())
+	}
+	return map[string]interface{}{
+		"servers": addresses,
+	}
+}
+
+func (i *Input) UpdateFrom(old interface{}) {
+	if i == old {
+		return
+	}
+	if old2, ok := old.(*Input); ok && old2.state != nil {
+		i.state = old2.state
+	}
+}
+
+func (i *Input) Collect(a api.Accumulator) error {
+	if i.state == nil {
+		i.state = newInternalState()
+	}
+
+	counters := make(map[string]float64, len(i.state.Counters))
+	var firstErr error
+	for _, s := range i.servers {
+		err := i.collectServer(s, a, counters)
+		if err != nil {
+			logger.Warnz("[postgresql] collect error", zap.String("server", s.address()), zap.Error(err))
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	i.state.Counters = counters
+	return firstErr
+}
+
+func (i *Input) collectServer(s *server, a api.Accumulator, counters map[string]float64) error {
+	tags := map[string]string{
+		"server": s.address(),
+	}
+
+	db, err := sql.Open("pgx", s.dsn())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	cc := &collectContext{
+		ctx:      ctx,
+		db:       db,
+		a:        a,
+		tags:     tags,
+		counters: counters,
+	}
+
+	var version int
+	if err := db.QueryRowContext(ctx, queryVersion).Scan(&version); err != nil {
+		cc.add("up", 0, nil)
+		return err
+	}
+	cc.add("up", 1, nil)
+
+	// Each group is collected independently, so that a permission error in one group won't affect others.
+	var firstErr error
+	for _, f := range []func(*collectContext) error{
+		i.collectConnections,
+		i.collectDatabases,
+		func(cc *collectContext) error { return i.collectReplication(cc, version) },
+		i.collectBloat,
+	} {
+		if err := f(cc); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (i *Input) collectConnections(cc *collectContext) error {
+	var maxConnections float64
+	if err := cc.db.QueryRowContext(cc.ctx, queryMaxConnections).Scan(&maxConnections); err != nil {
+		return err
+	}
+
+	rows, err := cc.db.QueryContext(cc.ctx, queryConnections)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	total := float64(0)
+	for rows.Next() {
+		var datname, state string
+		var count float64
+		if err := rows.Scan(&datname, &state, &count); err != nil {
+			return err
+		}
+		total += count
+		cc.add("connections", count, map[string]string{"db": datname, "state": state})
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	cc.add("connections_total", total, nil)
+	cc.add("connections_max", maxConnections, nil)
+	if maxConnections > 0 {
+		cc.add("connections_usage", total*100/maxConnections, nil)
+	}
+	return nil
+}
+
+func (i *Input) collectDatabases(cc *collectContext) error {
+	rows, err := cc.db.QueryContext(cc.ctx, queryDatabases)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var datname string
+		var numbackends, size float64
+		values := make([]float64, len(counterFields))
+		dest := make([]interface{}, 0, len(values)+3)
+		dest = append(dest, &datname, &numbackends)
+		for idx := range values {
+			dest = append(dest, &values[idx])
+		}
+		dest = append(dest, &size)
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+
+		extraTags := map[string]string{"db": datname}
+		cc.add("db_numbackends", numbackends, extraTags)
+		cc.add("db_size_bytes", size, extraTags)
+
+		deltas := make(map[string]float64, len(counterFields))
+		for idx, field := range counterFields {
+			if delta, ok := i.delta(cc, fmt.Sprintf("%s/%s/%s", cc.tags["server"], datname, field), values[idx]); ok {
+				deltas[field] = delta
+				cc.add("db_"+field, delta, extraTags)
+			}
+		}
+
+		// Hit ratio in this period
+		if hit, ok := deltas["blks_hit"]; ok {
+			if read := deltas["blks_read"]; hit+read > 0 {
+				cc.add("db_cache_hit_ratio", hit*100/(hit+read), extraTags)
+			}
+		}
+	}
+	return rows.Err()
+}
+
+func (i *Input) collectReplication(cc *collectContext, version int) error {
+	var inRecovery bool
+	var replayDelay float64
+	if err := cc.db.QueryRowContext(cc.ctx, queryIsInRecovery).Scan(&inRecovery, &replayDelay); err != nil {
+		return err
+	}
+	cc.add("replication_is_standby", api.BoolToFloat64(inRecovery), nil)
+
+	if inRecovery {
+		// On standby servers, replay delay is the time since last replayed transaction
+		cc.add("replication_replay_delay_seconds", replayDelay, nil)
+		return nil
+	}
+
+	query := queryReplication
+	if version < versionPg10 {
+		query = queryReplication96
+	}
+
+	rows, err := cc.db.QueryContext(cc.ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	replicas := 0
+	for rows.Next() {
+		var application, clientAddr, state string
+		var lagBytes, lagSeconds float64
+		if err := rows.Scan(&application, &clientAddr, &state, &lagBytes, &lagSeconds); err != nil {
+			return err
+		}
+		replicas++
+		extraTags := map[string]string{
+			"application": application,
+			"client_addr": clientAddr,
+			"state":       state,
+		}
+		cc.add("replication_lag_bytes", lagBytes, extraTags)
+		cc.add("replication_lag_seconds", lagSeconds, extraTags)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	cc.add("replication_replicas", float64(replicas), nil)
+	return nil
+}
+
+func (i *Input) collectBloat(cc *collectContext) error {
+	rows, err := cc.db.QueryContext(cc.ctx, queryBloat, i.bloatTopN)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var datname, schema, table string
+		var live, dead, size float64
+		if err := rows.Scan(&datname, &schema, &table, &live, &dead, &size); err != nil {
+			return err
+		}
+		extraTags := map[string]string{
+			"db":     datname,
+			"schema": schema,
+			"table":  table,
+		}
+		cc.add("table_live_tuples", live, extraTags)
+		cc.add("table_dead_tuples", dead, extraTags)
+		cc.add("table_size_bytes", size, extraTags)
+		if live+dead > 0 {
+			cc.add("table_dead_ratio", dead*100/(live+dead), extraTags)
+		}
+	}
+	return rows.Err()
+}
+
+// delta records current value of a counter and returns its delta against last collection.
+// The second return value is false if there is no last value or the counter is reset.
+func (i *Input) delta(cc *collectContext, key string, value float64) (float64, bool) {
+	cc.counters[key] = value
+	last, ok := i.state.Counters[key]
+	if !ok || value < last {
+		return 0, false
+	}
+	return value - last, true
+}
+
+func (cc *collectContext) add(name string, value float64, extraTags map[string]string) {
+	tags := make(map[string]string, len(cc.tags)+len(extraTags))
+	for k, v := range cc.tags {
+		tags[k] = v
+	}
+	for k, v := range extraTags {
+		tags[k] = v
+	}
+	cc.a.AddMetric(&model.Metric{
+		Name:  measurement + "_" + name,
+		Tags:  tags,
+		Value: value,
+	})
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package postgresql
+
+const (
+	queryVersion = `SELECT current_setting('server_version_num')::integer`
+
+	queryMaxConnections = `SELECT setting::float8 FROM pg_settings WHERE name = 'max_connections'`
+
+	queryConnections = `
+SELECT coalesce(datname, ''), coalesce(state, 'unknown'), count(*)::float8
+FROM pg_stat_activity
+GROUP BY 1, 2`
+
+	queryDatabases = `
+SELECT datname,
+       numbackends::float8,
+       xact_commit::float8,
+       xact_rollback::float8,
+       blks_read::float8,
+       blks_hit::float8,
+       tup_returned::float8,
+       tup_fetched::float8,
+       tup_inserted::float8,
+       tup_updated::float8,
+       tup_deleted::float8,
+       conflicts::float8,
+       temp_files::float8,
+       temp_bytes::float8,
+       deadlocks::float8,
+       pg_database_size(datname)::float8
+FROM pg_stat_database
+WHERE datname IS NOT NULL AND datname NOT IN ('template0', 'template1')`
+
+	queryIsInRecovery = `
+SELECT pg_is_in_recovery(),
+       CASE WHEN pg_is_in_recovery()
+            THEN coalesce(extract(epoch FROM now() - pg_last_xact_replay_timestamp()), 0)
+            ELSE 0 END::float8`
+
+	// queryReplication is for PostgreSQL 10+ where 'xlog' is renamed to 'wal'
+	queryReplication = `
+SELECT coalesce(application_name, ''),
+       coalesce(client_addr::text, ''),
+       coalesce(state, ''),
+       coalesce(pg_wal_lsn_diff(pg_current_wal_lsn(), replay_lsn), 0)::float8,
+       coalesce(extract(epoch FROM replay_lag), 0)::float8
+FROM pg_stat_replication`
+
+	// queryReplication96 is for PostgreSQL 9.x which has no replay_lag column
+	queryReplication96 = `
+SELECT coalesce(application_name, ''),
+       coalesce(client_addr::text, ''),
+       coalesce(state, ''),
+       coalesce(pg_xlog_location_diff(pg_current_xlog_location(), replay_location), 0)::float8,
+       0::float8
+FROM pg_stat_replication`
+
+	// queryBloat estimates bloat using dead tuples, it is much cheaper than page level estimation.
+	queryBloat = `
+SELECT current_database(),
+       schemaname,
+       relname,
+       n_live_tup::float8,
+       n_dead_tup::float8,
+       pg_total_relation_size(relid)::float8
+FROM pg_stat_user_tables
+ORDER BY n_dead_tup DESC
+LIMIT $1`
+
+	versionPg10 = 100000
+)
@@ -0,0 +1,224 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package pressure
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"github.com/traas-stack/holoinsight-agent/pkg/logger"
+	"github.com/traas-stack/holoinsight-agent/pkg/plugin/api"
+	"github.com/traas-stack/holoinsight-agent/pkg/plugin/input"
+	"github.com/traas-stack/holoinsight-agent/pkg/util"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type (
+	pressureInput struct {
+		// resolve returns files to read, it is called every collection since pod cgroup may change
+		resolve func() (*files, error)
+		state   *internalState
+	}
+	files struct {
+		// psi is resource -> PSI file path, resource is one of cpu/memory/io
+		psi map[string]string
+		// vmstat is the path of /proc/vmstat, empty means not collecting vmstat
+		vmstat string
+	}
+	internalState struct {
+		Time int64
+		// Key identifies the files of last collection, counters are not comparable when it changes
+		Key string
+		// Counters are raw values of cumulative counters of last collection
+		Counters map[string]float64
+	}
+	// psiLine is a line of PSI file: 'some avg10=0.00 avg60=0.00 avg300=0.00 total=0'
+	psiLine struct {
+		avg10  float64
+		avg60  float64
+		avg300 float64
+		// total is cumulative stall time in microseconds
+		total float64
+	}
+)
+
+var (
+	psiResources = []string{"cpu", "memory", "io"}
+	// vmstatCounters are cumulative counters of /proc/vmstat to report.
+	// Counters with the same metric name are summed, such as allocstall_dma and allocstall_normal.
+	vmstatCounters = map[string]string{
+		"pgmajfault":         "vmstat_pgmajfault",
+		"oom_kill":           "vmstat_oom_kill",
+		"pswpin":             "vmstat_pswpin",
+		"pswpout":            "vmstat_pswpout",
+		"allocstall":         "vmstat_allocstall",
+		"allocstall_dma":     "vmstat_allocstall",
+		"allocstall_dma32":   "vmstat_allocstall",
+		"allocstall_normal":  "vmstat_allocstall",
+		"allocstall_movable": "vmstat_allocstall",
+		"allocstall_device":  "vmstat_allocstall",
+		"compact_stall":      "vmstat_compact_stall",
+		"pgscan_direct":      "vmstat_pgscan_direct",
+		"pgsteal_direct":     "vmstat_pgsteal_direct",
+	}
+)
+
+func init() {
+	gob.Register(&internalState{})
+
+	input.Register("pressure", func(config input.Config) (api.Input, error) {
+		return &pressureInput{resolve: nodeFiles}, nil
+	})
+}
+
+// nodeFiles returns PSI and vmstat files of node, HOST_PROC is respected as what gopsutil does
+func nodeFiles() (*files, error) {
+	procDir := "/proc"
+	if s := os.Getenv("HOST_PROC"); s != "" {
+		procDir = s
+	}
+	f := &files{
+		psi:    make(map[string]string, len(psiResources)),
+		vmstat: filepath.Join(procDir, "vmstat"),
+	}
+	for _, resource := range psiResources {
+		f.psi[resource] = filepath.Join(procDir, "pressure", resource)
+	}
+	return f, nil
+}
+
+func (i *pressureInput) GetDefaultPrefix() string {
+	return ""
+}
+
+func (i *pressureInput) SaveState() (interface{}, error) {
+	return i.state, nil
+}
+
+func (i *pressureInput) LoadState(state interface{}) error {
+	i.state = state.(*internalState)
+	return nil
+}
+
+func (i *pressureInput) Collect(a api.Accumulator) error {
+	f, err := i.resolve()
+	if err != nil {
+		return err
+	}
+
+	gauges := make(map[string]float64)
+	counters := make(map[string]float64)
+
+	for _, resource := range psiResources {
+		content, err := os.ReadFile(f.psi[resource])
+		if err != nil {
+			// PSI is available since kernel 4.20 and may be disabled by 'psi=0'
+			if !os.IsNotExist(err) {
+				logger.Warnz("[pressure] read psi error", zap.String("path", f.psi[resource]), zap.Error(err))
+			}
+			continue
+		}
+		for kind, line := range parsePsi(content) {
+			prefix := "psi_" + resource + "_" + kind
+			gauges[prefix+"_avg10"] = line.avg10
+			gauges[prefix+"_avg60"] = line.avg60
+			gauges[prefix+"_avg300"] = line.avg300
+			counters[prefix+"_total"] = line.total
+		}
+	}
+
+	if f.vmstat != "" {
+		if content, err := os.ReadFile(f.vmstat); err != nil {
+			logger.Warnz("[pressure] read vmstat error", zap.String("path", f.vmstat), zap.Error(err))
+		} else {
+			for key, value := range parseVmstat(content) {
+				if metricName, ok := vmstatCounters[key]; ok {
+					counters[metricName] += value
+				}
+			}
+		}
+	}
+
+	now := util.CurrentMS()
+	last := i.state
+	key := f.psi["cpu"]
+	i.state = &internalState{Time: now, Key: key, Counters: counters}
+
+	values := make(map[string]interface{}, len(gauges)+len(counters))
+	for name, value := range gauges {
+		values[name] = value
+	}
+	if last != nil && last.Key == key {
+		for name, value := range counters {
+			lastValue, ok := last.Counters[name]
+			if !ok || value < lastValue {
+				continue
+			}
+			delta := value - lastValue
+			if strings.HasPrefix(name, "psi_") {
+				// stall time in milliseconds
+				delta /= 1000
+			}
+			values[name] = delta
+		}
+	}
+	input.AddMetrics(a, values)
+	return nil
+}
+
+// parsePsi parses PSI file, returns kind(some/full) -> line
+func parsePsi(content []byte) map[string]*psiLine {
+	ret := make(map[string]*psiLine, 2)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		line := &psiLine{}
+		for _, field := range fields[1:] {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			f64, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			switch key {
+			case "avg10":
+				line.avg10 = f64
+			case "avg60":
+				line.avg60 = f64
+			case "avg300":
+				line.avg300 = f64
+			case "total":
+				line.total = f64
+			}
+		}
+		ret[fields[0]] = line
+	}
+	return ret
+}
+
+// parseVmstat parses /proc/vmstat, each line is 'key value'
+func parseVmstat(content []byte) map[string]float64 {
+	ret := make(map[string]float64)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), " ")
+		if !ok {
+			continue
+		}
+		if f64, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+			ret[key] = f64
+		}
+	}
+	return ret
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package pressure
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/traas-stack/holoinsight-agent/pkg/plugin/api"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPressure(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	write("cpu", "some avg10=1.78 avg60=1.80 avg300=2.00 total=80000000\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n")
+	write("vmstat", "pswpin 0\npswpout 0\nallocstall_dma 0\nallocstall_normal 3\npgmajfault 423\noom_kill 1\n")
+
+	i := &pressureInput{resolve: func() (*files, error) {
+		return &files{
+			// memory and io are absent
+			psi:    map[string]string{"cpu": filepath.Join(dir, "cpu"), "memory": filepath.Join(dir, "memory")},
+			vmstat: filepath.Join(dir, "vmstat"),
+		}, nil
+	}}
+	collect := func() map[string]float64 {
+		ma := api.NewMemoryAccumulator()
+		assert.NoError(t, i.Collect(ma))
+		values := make(map[string]float64)
+		for _, m := range ma.Metrics {
+			values[m.Name] = m.Value
+		}
+		return values
+	}
+
+	values := collect()
+	assert.Equal(t, 1.78, values["psi_cpu_some_avg10"])
+	assert.Equal(t, 1.8, values["psi_cpu_some_avg60"])
+	assert.Equal(t, float64(0), values["psi_cpu_full_avg10"])
+	assert.NotContains(t, values, "psi_memory_some_avg10")
+	assert.NotContains(t, values, "psi_cpu_some_total")
+	assert.NotContains(t, values, "vmstat_pgmajfault")
+
+	write("cpu", "some avg10=5.00 avg60=2.00 avg300=2.00 total=83000000\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n")
+	write("vmstat", "pswpin 0\npswpout 0\nallocstall_dma 1\nallocstall_normal 5\npgmajfault 500\noom_kill 1\n")
+	values = collect()
+	assert.Equal(t, float64(3000), values["psi_cpu_some_total"])
+	assert.Equal(t, float64(77), values["vmstat_pgmajfault"])
+	assert.Equal(t, float64(3), values["vmstat_allocstall"])
+	assert.Equal(t, float64(0), values["vmstat_oom_kill"])
+}
+
+func TestPodPsiFiles(t *testing.T) {
+	hostfs := t.TempDir()
+	podDir := filepath.Join(hostfs, "sys/fs/cgroup/kubepods.slice/kubepods-pod1.slice")
+	assert.NoError(t, os.MkdirAll(filepath.Join(podDir, "cri-containerd-abc.scope"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(hostfs, "sys/fs/cgroup/cgroup.controllers"), []byte("cpu memory io"), 0644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(hostfs, "proc/100"), 0755))
+	// The agent runs in a private cgroup namespace rooted at /kubepods.slice/kubepods-podA.slice/cri-containerd-agent.scope
+	assert.NoError(t, os.WriteFile(filepath.Join(hostfs, "proc/100/cgroup"), []byte("0::/../../kubepods-pod1.slice/cri-containerd-abc.scope\n"), 0644))
+
+	f, err := podPsiFiles(hostfs, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(podDir, "cpu.pressure"), f.psi["cpu"])
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(hostfs, "proc/200"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(hostfs, "proc/200/cgroup"), []byte("0::/\n"), 0644))
+	_, err = podPsiFiles(hostfs, 200)
+	assert.Equal(t, errNoCgroupV2, err)
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package pressure
+
+import (
+	"errors"
+	"fmt"
+	"github.com/traas-stack/holoinsight-agent/pkg/collecttask"
+	"github.com/traas-stack/holoinsight-agent/pkg/core"
+	"github.com/traas-stack/holoinsight-agent/pkg/cri/criutils"
+	"github.com/traas-stack/holoinsight-agent/pkg/ioc"
+	"github.com/traas-stack/holoinsight-agent/pkg/plugin/api"
+	"github.com/traas-stack/holoinsight-agent/pkg/plugin/input/standard/providers"
+	"github.com/traas-stack/holoinsight-agent/pkg/util/cgrouputil"
+	"path/filepath"
+)
+
+var errNoCgroupV2 = errors.New("pod cgroup does not expose PSI, cgroup v2 is required")
+
+func init() {
+	providers.RegisterInputProvider("pressuretask", Parse)
+}
+
+func Parse(task *collecttask.CollectTask) (api.Input, error) {
+	switch task.Target.Type {
+	case collecttask.TargetLocalhost:
+		return &pressureInput{resolve: nodeFiles}, nil
+	case collecttask.TargetPod:
+		namespace := task.Target.GetNamespace()
+		podName := task.Target.GetPodName()
+		return &pressureInput{
+			resolve: func() (*files, error) {
+				return podFiles(namespace, podName)
+			},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported target type %+v", task.Target)
+	}
+}
+
+// podFiles returns PSI files of pod level cgroup. vmstat is node wide, so it is not collected for pods.
+func podFiles(namespace, podName string) (*files, error) {
+	if ioc.Crii == nil {
+		return nil, errors.New("cri is not available")
+	}
+	c, err := criutils.GetMainBizContainerE(ioc.Crii, namespace, podName)
+	if err != nil {
+		return nil, err
+	}
+	if c.State.Pid <= 0 {
+		return nil, fmt.Errorf("container %s is not running", c.Id)
+	}
+	return podPsiFiles(core.GetHostfs(), c.State.Pid)
+}
+
+// podPsiFiles returns PSI files of the parent cgroup of the process, the container cgroup is the direct child of pod cgroup
+func podPsiFiles(hostfs string, pid int) (*files, error) {
+	cgroupRoot := filepath.Join(hostfs, "sys", "fs", "cgroup")
+	paths, err := cgrouputil.ReadProcCgroup(cgroupRoot, filepath.Join(hostfs, "proc"), cgrouputil.IsV2(cgroupRoot), pid)
+	if err != nil {
+		return nil, err
+	}
+	path, ok := paths[""]
+	if !ok || path == "/" {
+		return nil, errNoCgroupV2
+	}
+	dir := filepath.Join(cgroupRoot, filepath.Dir(path))
+	f := &files{psi: make(map[string]string, len(psiResources))}
+	for _, resource := range psiResources {
+		f.psi[resource] = filepath.Join(dir, resource+".pressure")
+	}
+	return f, nil
+}
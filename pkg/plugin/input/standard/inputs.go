@@ -8,10 +8,15 @@ import (
 	"errors"
 	"github.com/traas-stack/holoinsight-agent/pkg/collecttask"
 	_ "github.com/traas-stack/holoinsight-agent/pkg/plugin/input/dialcheckw"
+	_ "github.com/traas-stack/holoinsight-agent/pkg/plugin/input/golangw"
 	_ "github.com/traas-stack/holoinsight-agent/pkg/plugin/input/httpcheckw"
 	_ "github.com/traas-stack/holoinsight-agent/pkg/plugin/input/jvm"
+	_ "github.com/traas-stack/holoinsight-agent/pkg/plugin/input/netstack"
 	_ "github.com/traas-stack/holoinsight-agent/pkg/plugin/input/nvidia_smi"
+	_ "github.com/traas-stack/holoinsight-agent/pkg/plugin/input/postgresql"
+	_ "github.com/traas-stack/holoinsight-agent/pkg/plugin/input/pressure"
 	_ "github.com/traas-stack/holoinsight-agent/pkg/plugin/input/springboot"
+	_ "github.com/traas-stack/holoinsight-agent/pkg/plugin/input/standard/kafka"
 	_ "github.com/traas-stack/holoinsight-agent/pkg/plugin/input/standard/mysqlw"
 	"github.com/traas-stack/holoinsight-agent/pkg/plugin/input/standard/providers"
 	_ "github.com/traas-stack/holoinsight-agent/pkg/plugin/input/standard/telegraf_adapters/mongodb"
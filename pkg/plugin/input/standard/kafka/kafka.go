@@ -0,0 +1,304 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package kafka
+
+import (
+	"github.com/Shopify/sarama"
+	"github.com/traas-stack/holoinsight-agent/pkg/logger"
+	"github.com/traas-stack/holoinsight-agent/pkg/model"
+	"github.com/traas-stack/holoinsight-agent/pkg/plugin/api"
+	"go.uber.org/zap"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	measurement    = "kafka"
+	defaultTimeout = 3 * time.Second
+)
+
+type (
+	// Input collects broker metadata, partition offsets and consumer group lag from a kafka cluster.
+	// A new client is created for each collection and closed immediately after.
+	Input struct {
+		brokers       []string
+		conf          *sarama.Config
+		groups        []*regexp.Regexp
+		excludeGroups []*regexp.Regexp
+		topics        []*regexp.Regexp
+		excludeTopics []*regexp.Regexp
+	}
+	// partitionOffsets is a map from topic to partition to offset
+	partitionOffsets map[string]map[int32]int64
+)
+
+func (i *Input) GetDefaultPrefix() string {
+	return "telegraf_"
+}
+
+func (i *Input) DebugInfo() map[string]interface{} {
+	return map[string]interface{}{
+		"brokers": i.brokers,
+	}
+}
+
+func (i *Input) UpdateFrom(interface{}) {
+}
+
+func (i *Input) Collect(a api.Accumulator) error {
+	client, err := sarama.NewClient(i.brokers, i.conf)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	i.collectBrokers(client, a)
+
+	topicPartitions, err := i.collectTopics(client, a)
+	if err != nil {
+		return err
+	}
+
+	newest, err := i.getOffsets(client, topicPartitions, sarama.OffsetNewest)
+	if err != nil {
+		return err
+	}
+	oldest, err := i.getOffsets(client, topicPartitions, sarama.OffsetOldest)
+	if err != nil {
+		return err
+	}
+	for topic, partitions := range newest {
+		for partition, offset := range partitions {
+			tags := partitionTags(topic, partition)
+			add(a, "partition_newest_offset", float64(offset), tags)
+			if oldestOffset, ok := oldest[topic][partition]; ok {
+				add(a, "partition_oldest_offset", float64(oldestOffset), tags)
+				add(a, "partition_messages", float64(offset-oldestOffset), tags)
+			}
+		}
+	}
+
+	return i.collectGroups(client, topicPartitions, newest, a)
+}
+
+func (i *Input) collectBrokers(client sarama.Client, a api.Accumulator) {
+	brokers := client.Brokers()
+	add(a, "brokers", float64(len(brokers)), nil)
+
+	controllerID := int32(-1)
+	if controller, err := client.Controller(); err == nil {
+		controllerID = controller.ID()
+	}
+	for _, broker := range brokers {
+		add(a, "broker_info", 1, map[string]string{
+			"broker_id":     strconv.Itoa(int(broker.ID())),
+			"address":       broker.Addr(),
+			"is_controller": strconv.FormatBool(broker.ID() == controllerID),
+		})
+	}
+}
+
+// collectTopics emits partition metadata of matched topics and returns partitions of them
+func (i *Input) collectTopics(client sarama.Client, a api.Accumulator) (map[string][]int32, error) {
+	topics, err := client.Topics()
+	if err != nil {
+		return nil, err
+	}
+
+	topicPartitions := make(map[string][]int32)
+	for _, topic := range topics {
+		if !i.matchTopic(topic) {
+			continue
+		}
+		partitions, err := client.Partitions(topic)
+		if err != nil {
+			logger.Warnz("[kafka] get partitions error", zap.String("topic", topic), zap.Error(err))
+			continue
+		}
+		topicPartitions[topic] = partitions
+		add(a, "topic_partitions", float64(len(partitions)), map[string]string{"topic": topic})
+
+		for _, partition := range partitions {
+			tags := partitionTags(topic, partition)
+			replicas, _ := client.Replicas(topic, partition)
+			isr, _ := client.InSyncReplicas(topic, partition)
+			add(a, "partition_replicas", float64(len(replicas)), tags)
+			add(a, "partition_in_sync_replicas", float64(len(isr)), tags)
+			add(a, "partition_under_replicated", api.BoolToFloat64(len(isr) < len(replicas)), tags)
+			if leader, err := client.Leader(topic, partition); err == nil {
+				add(a, "partition_leader", float64(leader.ID()), tags)
+			}
+		}
+	}
+	return topicPartitions, nil
+}
+
+// getOffsets queries offsets of all partitions, requests are grouped by leader broker.
+func (i *Input) getOffsets(client sarama.Client, topicPartitions map[string][]int32, time int64) (partitionOffsets, error) {
+	requests := make(map[*sarama.Broker]*sarama.OffsetRequest)
+	for topic, partitions := range topicPartitions {
+		for _, partition := range partitions {
+			leader, err := client.Leader(topic, partition)
+			if err != nil {
+				continue
+			}
+			request, ok := requests[leader]
+			if !ok {
+				request = &sarama.OffsetRequest{}
+				if i.conf.Version.IsAtLeast(sarama.V0_10_1_0) {
+					request.Version = 1
+				}
+				requests[leader] = request
+			}
+			request.AddBlock(topic, partition, time, 1)
+		}
+	}
+
+	offsets := make(partitionOffsets)
+	for broker, request := range requests {
+		resp, err := broker.GetAvailableOffsets(request)
+		if err != nil {
+			logger.Warnz("[kafka] get offsets error", zap.String("broker", broker.Addr()), zap.Error(err))
+			continue
+		}
+		for topic, blocks := range resp.Blocks {
+			for partition, block := range blocks {
+				if block.Err != sarama.ErrNoError || len(block.Offsets) == 0 {
+					continue
+				}
+				offsets.put(topic, partition, block.Offsets[0])
+			}
+		}
+	}
+	return offsets, nil
+}
+
+func (i *Input) collectGroups(client sarama.Client, topicPartitions map[string][]int32, newest partitionOffsets, a api.Accumulator) error {
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		return err
+	}
+	// Don't close admin, it will close the underlying client which is closed by caller.
+
+	allGroups, err := admin.ListConsumerGroups()
+	if err != nil {
+		return err
+	}
+	var groups []string
+	for group := range allGroups {
+		if i.matchGroup(group) {
+			groups = append(groups, group)
+		}
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+
+	if descriptions, err := admin.DescribeConsumerGroups(groups); err == nil {
+		for _, desc := range descriptions {
+			add(a, "consumergroup_members", float64(len(desc.Members)), map[string]string{
+				"group": desc.GroupId,
+				"state": desc.State,
+			})
+		}
+	} else {
+		logger.Warnz("[kafka] describe consumer groups error", zap.Error(err))
+	}
+
+	for _, group := range groups {
+		resp, err := admin.ListConsumerGroupOffsets(group, topicPartitions)
+		if err != nil {
+			logger.Warnz("[kafka] list consumer group offsets error", zap.String("group", group), zap.Error(err))
+			continue
+		}
+		for topic, blocks := range resp.Blocks {
+			lagSum := int64(0)
+			committed := false
+			for partition, block := range blocks {
+				// -1 means there is no committed offset
+				if block.Err != sarama.ErrNoError || block.Offset < 0 {
+					continue
+				}
+				committed = true
+				tags := partitionTags(topic, partition)
+				tags["group"] = group
+				add(a, "consumergroup_offset", float64(block.Offset), tags)
+				if newestOffset, ok := newest[topic][partition]; ok {
+					lag := newestOffset - block.Offset
+					if lag < 0 {
+						lag = 0
+					}
+					lagSum += lag
+					add(a, "consumergroup_lag", float64(lag), tags)
+				}
+			}
+			if committed {
+				add(a, "consumergroup_lag_sum", float64(lagSum), map[string]string{
+					"group": group,
+					"topic": topic,
+				})
+			}
+		}
+	}
+	return nil
+}
+
+func (i *Input) matchTopic(topic string) bool {
+	// internal topics are ignored unless they are included explicitly
+	if len(i.topics) == 0 && strings.HasPrefix(topic, "__") {
+		return false
+	}
+	return matchRegexps(topic, i.topics, i.excludeTopics)
+}
+
+func (i *Input) matchGroup(group string) bool {
+	return matchRegexps(group, i.groups, i.excludeGroups)
+}
+
+func matchRegexps(s string, includes, excludes []*regexp.Regexp) bool {
+	for _, r := range excludes {
+		if r.MatchString(s) {
+			return false
+		}
+	}
+	if len(includes) == 0 {
+		return true
+	}
+	for _, r := range includes {
+		if r.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o partitionOffsets) put(topic string, partition int32, offset int64) {
+	partitions, ok := o[topic]
+	if !ok {
+		partitions = make(map[int32]int64)
+		o[topic] = partitions
+	}
+	partitions[partition] = offset
+}
+
+func partitionTags(topic string, partition int32) map[string]string {
+	return map[string]string{
+		"topic":     topic,
+		"partition": strconv.Itoa(int(partition)),
+	}
+}
+
+func add(a api.Accumulator, name string, value float64, tags map[string]string) {
+	if tags == nil {
+		tags = make(map[string]string)
+	}
+	a.AddMetric(&model.Metric{
+		Name:  measurement + "_" + name,
+		Tags:  tags,
+		Value: value,
+	})
+}
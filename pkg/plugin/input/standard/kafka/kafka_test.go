@@ -0,0 +1,100 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package kafka
+
+import (
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/traas-stack/holoinsight-agent/pkg/plugin/api"
+	"testing"
+)
+
+func TestKafka(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetController(broker.BrokerID()).
+			SetLeader("orders", 0, broker.BrokerID()).
+			SetLeader("orders", 1, broker.BrokerID()).
+			SetLeader("__consumer_offsets", 0, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetVersion(1).
+			SetOffset("orders", 0, sarama.OffsetNewest, 100).
+			SetOffset("orders", 0, sarama.OffsetOldest, 10).
+			SetOffset("orders", 1, sarama.OffsetNewest, 50).
+			SetOffset("orders", 1, sarama.OffsetOldest, 0),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, "billing", broker).
+			SetCoordinator(sarama.CoordinatorGroup, "audit", broker),
+		"ListGroupsRequest": sarama.NewMockListGroupsResponse(t).
+			AddGroup("billing", "consumer").
+			AddGroup("audit", "consumer"),
+		"DescribeGroupsRequest": sarama.NewMockDescribeGroupsResponse(t).
+			AddGroupDescription("billing", &sarama.GroupDescription{
+				GroupId: "billing",
+				State:   "Stable",
+				Members: map[string]*sarama.GroupMemberDescription{"m1": {}, "m2": {}},
+			}),
+		"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+			SetOffset("billing", "orders", 0, 90, "", sarama.ErrNoError).
+			SetOffset("billing", "orders", 1, -1, "", sarama.ErrNoError),
+	})
+
+	input, err := newInput([]string{broker.Addr()}, &Conf{
+		Version:       "2.0.0",
+		ExcludeGroups: []string{"^audit$"},
+	})
+	assert.NoError(t, err)
+
+	ma := api.NewMemoryAccumulator()
+	assert.NoError(t, input.Collect(ma))
+
+	find := func(name string, tags map[string]string) (float64, bool) {
+	outer:
+		for _, m := range ma.Metrics {
+			if m.Name != name {
+				continue
+			}
+			for k, v := range tags {
+				if m.Tags[k] != v {
+					continue outer
+				}
+			}
+			return m.Value, true
+		}
+		return 0, false
+	}
+
+	v, _ := find("kafka_brokers", nil)
+	assert.Equal(t, float64(1), v)
+
+	v, _ = find("kafka_topic_partitions", map[string]string{"topic": "orders"})
+	assert.Equal(t, float64(2), v)
+
+	_, ok := find("kafka_topic_partitions", map[string]string{"topic": "__consumer_offsets"})
+	assert.False(t, ok)
+
+	v, _ = find("kafka_partition_messages", map[string]string{"topic": "orders", "partition": "0"})
+	assert.Equal(t, float64(90), v)
+
+	v, _ = find("kafka_consumergroup_members", map[string]string{"group": "billing"})
+	assert.Equal(t, float64(2), v)
+
+	v, _ = find("kafka_consumergroup_lag", map[string]string{"group": "billing", "topic": "orders", "partition": "0"})
+	assert.Equal(t, float64(10), v)
+
+	// partition 1 has no committed offset
+	_, ok = find("kafka_consumergroup_lag", map[string]string{"group": "billing", "topic": "orders", "partition": "1"})
+	assert.False(t, ok)
+
+	v, _ = find("kafka_consumergroup_lag_sum", map[string]string{"group": "billing", "topic": "orders"})
+	assert.Equal(t, float64(10), v)
+
+	_, ok = find("kafka_consumergroup_offset", map[string]string{"group": "audit"})
+	assert.False(t, ok)
+}
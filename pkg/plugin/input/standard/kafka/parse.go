@@ -0,0 +1,128 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package kafka
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Shopify/sarama"
+	"github.com/traas-stack/holoinsight-agent/pkg/collecttask"
+	"github.com/traas-stack/holoinsight-agent/pkg/plugin/api"
+	"github.com/traas-stack/holoinsight-agent/pkg/plugin/input/standard/providers"
+	"net"
+	"regexp"
+	"strconv"
+)
+
+const (
+	defaultPort = 9092
+)
+
+type (
+	Conf struct {
+		// Brokers are used when target type is none
+		Brokers []string `json:"brokers,omitempty"`
+		// Port is used when target type is pod
+		Port int `json:"port,omitempty"`
+		// Version is the kafka protocol version, such as "2.8.0"
+		Version  string `json:"version,omitempty"`
+		Username string `json:"username,omitempty"`
+		Password string `json:"password,omitempty"`
+		// Groups are regexps of consumer groups to collect, empty means all groups
+		Groups        []string `json:"groups,omitempty"`
+		ExcludeGroups []string `json:"excludeGroups,omitempty"`
+		// Topics are regexps of topics to collect, empty means all non-internal topics
+		Topics        []string `json:"topics,omitempty"`
+		ExcludeTopics []string `json:"excludeTopics,omitempty"`
+	}
+)
+
+func init() {
+	providers.RegisterInputProvider("telegraf_kafka", Parse)
+}
+
+func Parse(task *collecttask.CollectTask) (api.Input, error) {
+	conf := &Conf{}
+	if err := json.Unmarshal(task.Config.Content, conf); err != nil {
+		return nil, err
+	}
+
+	var brokers []string
+	switch task.Target.Type {
+	case collecttask.TargetPod:
+		port := conf.Port
+		if port <= 0 {
+			port = defaultPort
+		}
+		brokers = []string{net.JoinHostPort(task.Target.GetIP(), strconv.Itoa(port))}
+	case collecttask.TargetNone:
+		brokers = conf.Brokers
+	default:
+		return nil, errors.New("unsupported target type: " + task.Target.Type)
+	}
+	if len(brokers) == 0 {
+		return nil, errors.New("empty brokers")
+	}
+
+	return newInput(brokers, conf)
+}
+
+func newInput(brokers []string, conf *Conf) (*Input, error) {
+	saramaConf := sarama.NewConfig()
+	saramaConf.ClientID = "holoinsight-agent"
+	saramaConf.Net.DialTimeout = defaultTimeout
+	saramaConf.Net.ReadTimeout = defaultTimeout
+	saramaConf.Net.WriteTimeout = defaultTimeout
+	saramaConf.Metadata.Retry.Max = 1
+	saramaConf.Metadata.Full = true
+
+	if conf.Version != "" {
+		version, err := sarama.ParseKafkaVersion(conf.Version)
+		if err != nil {
+			return nil, err
+		}
+		saramaConf.Version = version
+	}
+	if conf.Username != "" {
+		saramaConf.Net.SASL.Enable = true
+		saramaConf.Net.SASL.User = conf.Username
+		saramaConf.Net.SASL.Password = conf.Password
+	}
+	if err := saramaConf.Validate(); err != nil {
+		return nil, err
+	}
+
+	i := &Input{
+		brokers: brokers,
+		conf:    saramaConf,
+	}
+	var err error
+	if i.groups, err = compileRegexps(conf.Groups); err != nil {
+		return nil, err
+	}
+	if i.excludeGroups, err = compileRegexps(conf.ExcludeGroups); err != nil {
+		return nil, err
+	}
+	if i.topics, err = compileRegexps(conf.Topics); err != nil {
+		return nil, err
+	}
+	if i.excludeTopics, err = compileRegexps(conf.ExcludeTopics); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+func compileRegexps(exprs []string) ([]*regexp.Regexp, error) {
+	var ret []*regexp.Regexp
+	for _, expr := range exprs {
+		r, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regexp %s: %v", expr, err)
+		}
+		ret = append(ret, r)
+	}
+	return ret, nil
+}
@@ -0,0 +1,142 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+// Package cgrouputil resolves cgroup paths of processes. It only reads files, so it can be shared by collectors.
+package cgrouputil
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"github.com/spf13/cast"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrNoCgroupPath = errors.New("no cgroup path")
+	// kubepodsRoots are known parents of pod cgroups of systemd and cgroupfs cgroup drivers
+	kubepodsRoots = []string{
+		"/",
+		"/kubepods.slice",
+		"/kubepods.slice/kubepods-burstable.slice",
+		"/kubepods.slice/kubepods-besteffort.slice",
+		"/kubepods",
+		"/kubepods/burstable",
+		"/kubepods/besteffort",
+	}
+)
+
+type (
+	// Paths is the cgroup paths of a process.
+	// For cgroup v1 it is controller -> path, for cgroup v2 the only key is "".
+	Paths map[string]string
+)
+
+// IsV2 checks whether the cgroup root is mounted as cgroup v2 unified hierarchy
+func IsV2(cgroupRoot string) bool {
+	_, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers"))
+	return err == nil
+}
+
+// ReadProcCgroup returns cgroup paths of a process relative to cgroupRoot
+func ReadProcCgroup(cgroupRoot, procDir string, v2 bool, pid int) (Paths, error) {
+	content, err := os.ReadFile(filepath.Join(procDir, cast.ToString(pid), "cgroup"))
+	if err != nil {
+		return nil, err
+	}
+	paths := resolveCgroupPaths(cgroupRoot, v2, parseProcCgroup(content))
+	if len(paths) == 0 {
+		return nil, ErrNoCgroupPath
+	}
+	return paths, nil
+}
+
+// Parent returns the cgroup paths of parent cgroup
+func (p Paths) Parent() Paths {
+	ret := make(Paths, len(p))
+	for controller, path := range p {
+		ret[controller] = filepath.Dir(path)
+	}
+	return ret
+}
+
+// parseProcCgroup parses /proc/<pid>/cgroup:
+//
+//	cgroup v1: 4:cpu,cpuacct:/kubepods/pod1/abc
+//	cgroup v2: 0::/kubepods.slice/kubepods-pod1.slice/cri-containerd-abc.scope
+//
+// When agent runs in a private cgroup namespace, paths are relative to the cgroup namespace root of agent,
+// see resolveCgroupPaths.
+func parseProcCgroup(content []byte) Paths {
+	paths := make(Paths)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		ss := strings.SplitN(scanner.Text(), ":", 3)
+		if len(ss) != 3 {
+			continue
+		}
+		path := ss[2]
+		if ss[1] == "" {
+			paths[""] = path
+			continue
+		}
+		for _, controller := range strings.Split(ss[1], ",") {
+			paths[controller] = path
+		}
+	}
+	return paths
+}
+
+// resolveCgroupPaths converts paths relative to the cgroup namespace root of agent into paths relative to cgroupRoot.
+// Paths that can't be resolved are removed.
+//
+// When agent runs in a private cgroup namespace, the kernel reports a path like '/../../kubepods-pod1.slice/cri-containerd-abc.scope',
+// which means going up from the namespace root of agent. The namespace root itself is not visible to agent,
+// so the rest of the path is looked up under known parents of pod cgroups.
+func resolveCgroupPaths(cgroupRoot string, v2 bool, paths Paths) Paths {
+	ret := make(Paths, len(paths))
+	for controller, path := range paths {
+		if !strings.HasPrefix(path, "/..") {
+			ret[controller] = path
+			continue
+		}
+		base := cgroupRoot
+		if !v2 {
+			base = filepath.Join(cgroupRoot, controller)
+		}
+		if resolved, ok := resolveRelativeCgroupPath(base, path); ok {
+			ret[controller] = resolved
+		}
+	}
+	return ret
+}
+
+func resolveRelativeCgroupPath(base string, path string) (string, bool) {
+	rest := path
+	for strings.HasPrefix(rest, "/..") {
+		rest = rest[len("/.."):]
+	}
+	if rest == "" || rest == "/" {
+		// an ancestor of the namespace root of agent
+		return "", false
+	}
+	for _, root := range kubepodsRoots {
+		p := filepath.Join(root, rest)
+		if _, err := os.Stat(filepath.Join(base, p)); err == nil {
+			return p, true
+		}
+	}
+	// agent and the process are in the same pod, the rest is relative to the pod cgroup
+	for _, root := range kubepodsRoots[1:] {
+		matches, _ := filepath.Glob(filepath.Join(base, root, "*", rest))
+		if len(matches) == 1 {
+			if p, err := filepath.Rel(base, matches[0]); err == nil {
+				return "/" + p, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,43 @@
+/*
+ * Copyright 2022 Holoinsight Project Authors. Licensed under Apache-2.0.
+ */
+
+package cgrouputil
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveCgroupPaths(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{
+		"kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod1.slice/cri-containerd-app.scope",
+		"kubepods.slice/kubepods-pod2.slice/cri-containerd-app.scope",
+		"memory/kubepods/besteffort/pod3/app",
+	} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(root, dir), 0755))
+	}
+
+	// agent runs at /kubepods.slice/kubepods-podA.slice/cri-containerd-agent.scope
+	paths := resolveCgroupPaths(root, true, parseProcCgroup([]byte("0::/../../kubepods-burstable.slice/kubepods-burstable-pod1.slice/cri-containerd-app.scope\n")))
+	assert.Equal(t, "/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod1.slice/cri-containerd-app.scope", paths[""])
+
+	// agent runs at /system.slice/agent.service
+	paths = resolveCgroupPaths(root, true, parseProcCgroup([]byte("0::/../../kubepods.slice/kubepods-pod2.slice/cri-containerd-app.scope\n")))
+	assert.Equal(t, "/kubepods.slice/kubepods-pod2.slice/cri-containerd-app.scope", paths[""])
+
+	// agent runs in the same pod
+	paths = resolveCgroupPaths(root, true, parseProcCgroup([]byte("0::/../cri-containerd-app.scope\n")))
+	assert.Equal(t, "/kubepods.slice/kubepods-pod2.slice/cri-containerd-app.scope", paths[""])
+
+	// cgroup v1, agent runs at /kubepods/burstable/podA/agent
+	paths = resolveCgroupPaths(root, false, parseProcCgroup([]byte("3:memory:/../../../besteffort/pod3/app\n1:name=systemd:/../../../besteffort/pod3/app\n")))
+	assert.Equal(t, Paths{"memory": "/kubepods/besteffort/pod3/app"}, paths)
+
+	// unknown cgroups are removed
+	paths = resolveCgroupPaths(root, true, parseProcCgroup([]byte("0::/../../unknown.slice/app.scope\n")))
+	assert.Empty(t, paths)
+}